@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// enableANSI is a no-op off Windows, where terminals understand ANSI
+// escapes natively.
+func enableANSI() bool {
+	return true
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"code.cloudfoundry.org/cli/plugin"
 	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
@@ -25,7 +30,8 @@ var commands = make(map[string]cf.Command)
 
 func (c *LogCacheCLI) Run(conn plugin.CliConnection, args []string) {
 	if len(args) == 1 && args[0] == "CLI-MESSAGE-UNINSTALL" {
-		// someone's uninstalling the plugin, but we don't need to clean up
+		// someone's uninstalling the plugin, remove the state it wrote
+		cf.Uninstall()
 		return
 	}
 
@@ -34,6 +40,7 @@ func (c *LogCacheCLI) Run(conn plugin.CliConnection, args []string) {
 	}
 
 	isTerminal := terminal.IsTerminal(int(os.Stdout.Fd()))
+	ansiCapable := !isTerminal || enableANSI()
 
 	commands["query"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
 		var opts []cf.QueryOption
@@ -44,14 +51,96 @@ func (c *LogCacheCLI) Run(conn plugin.CliConnection, args []string) {
 		var opts []cf.TailOption
 		if !isTerminal {
 			opts = append(opts, cf.WithTailNoHeaders())
+			opts = append(opts, cf.WithTailNoColor())
+		} else {
+			if !ansiCapable {
+				// the console can't render escape codes, degrade gracefully
+				opts = append(opts, cf.WithTailNoColor())
+			}
+			if width, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil {
+				opts = append(opts, cf.WithTailTerminalWidth(width))
+			}
 		}
 		cf.Tail(ctx, cli, args, c, log, tableWriter, opts...)
 	}
 
+	commands["export"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.ExportOption
+		if !isTerminal {
+			opts = append(opts, cf.WithExportNoHeaders())
+		}
+		cf.Export(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-top"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.TopOption
+		if isTerminal && ansiCapable {
+			opts = append(opts, cf.WithTopClearScreen())
+		}
+		cf.Top(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-stats"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.StatsOption
+		cf.Stats(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-latency"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.LatencyOption
+		cf.Latency(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-bench"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.BenchOption
+		cf.Bench(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-blackbox"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.BlackboxOption
+		cf.Blackbox(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-emit"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.EmitOption
+		cf.Emit(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-version"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		cf.ShowVersion(ctx, cli, args, c, log, tableWriter)
+	}
+
+	commands["log-cache-update"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		cf.Update(ctx, cli, args, c, log, tableWriter)
+	}
+
+	commands["meta-daemon"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.MetaDaemonOption
+		cf.MetaDaemon(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["log-cache-meta-diff"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		var opts []cf.MetaDiffOption
+		cf.MetaDiff(ctx, cli, args, c, log, tableWriter, opts...)
+	}
+
+	commands["usage-report"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		cf.UsageReport(ctx, cli, args, c, log, tableWriter)
+	}
+
+	commands["completion"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		cf.Completion(ctx, cli, args, c, log, tableWriter)
+	}
+
+	commands["log-cache-save"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
+		cf.SavePreset(ctx, cli, args, c, log, tableWriter)
+	}
+
 	commands["log-meta"] = func(ctx context.Context, cli plugin.CliConnection, args []string, c cf.HTTPClient, log cf.Logger, tableWriter io.Writer) {
 		var opts []cf.MetaOption
 		if !isTerminal {
 			opts = append(opts, cf.WithMetaNoHeaders())
+		} else if width, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil {
+			opts = append(opts, cf.WithMetaTerminalWidth(width))
 		}
 		cf.Meta(
 			ctx,
@@ -76,7 +165,93 @@ func (c *LogCacheCLI) Run(conn plugin.CliConnection, args []string) {
 	if !ok {
 		log.Fatalf("Unknown Log Cache command: %s", args[0])
 	}
-	op(context.Background(), conn, args[1:], http.DefaultClient, log.New(os.Stderr, "", 0), os.Stdout)
+
+	// --trace and --timeout apply to every command and are handled here
+	// rather than in each command's flag struct
+	var (
+		trace   bool
+		follow  bool
+		timeout time.Duration
+		headers [][2]string
+	)
+	commandArgs := []string{}
+	rawArgs := args[1:]
+	for i := 0; i < len(rawArgs); i++ {
+		arg := rawArgs[i]
+		switch {
+		case arg == "--trace":
+			trace = true
+		case arg == "--timeout" && i+1 < len(rawArgs):
+			timeout = parseTimeout(rawArgs[i+1])
+			i++
+		case strings.HasPrefix(arg, "--timeout="):
+			timeout = parseTimeout(strings.TrimPrefix(arg, "--timeout="))
+		case arg == "--header" && i+1 < len(rawArgs):
+			headers = append(headers, parseHeader(rawArgs[i+1]))
+			i++
+		case strings.HasPrefix(arg, "--header="):
+			headers = append(headers, parseHeader(strings.TrimPrefix(arg, "--header=")))
+		default:
+			if arg == "--follow" || arg == "-f" {
+				follow = true
+			}
+			commandArgs = append(commandArgs, arg)
+		}
+	}
+
+	pooled := cf.NewPooledClient()
+	var httpClient cf.HTTPClient = cf.NewRetryAfterClient(cf.NewGzipClient(pooled))
+	if timeout > 0 {
+		httpClient = cf.NewTimeoutClient(httpClient, timeout)
+	}
+	if len(headers) > 0 {
+		httpClient = cf.NewHeaderClient(httpClient, headers)
+	}
+	if trace {
+		httpClient = cf.NewTraceClient(httpClient, os.Stderr)
+	} else {
+		httpClient = cf.NewTraceClientFromEnv(httpClient)
+	}
+	if tracer := cf.NewCommandTracer(args[0], pooled); tracer != nil {
+		httpClient = tracer.Client(httpClient)
+		defer tracer.Finish()
+	}
+	if recorder := cf.NewTelemetryRecorder(args[0]); recorder != nil {
+		httpClient = recorder.Client(httpClient)
+		defer recorder.Finish()
+	}
+
+	// an interrupt cancels the context so in-flight requests stop cleanly
+	// and deferred flushes still run; a second interrupt force-exits
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 && !follow {
+		// --timeout also bounds the whole run unless it is a live stream
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		<-sigs
+		cancel()
+		<-sigs
+		os.Exit(130)
+	}()
+
+	op(ctx, conn, commandArgs, httpClient, exitCodeLogger{log.New(os.Stderr, "", 0)}, os.Stdout)
+}
+
+// exitCodeLogger terminates with distinct exit codes for classified
+// failures (usage, auth, not-found, network) so scripts can branch on $?
+// instead of scraping stderr.
+type exitCodeLogger struct {
+	*log.Logger
+}
+
+func (l exitCodeLogger) FatalfWithCode(code int, format string, args ...interface{}) {
+	l.Printf(format, args...)
+	os.Exit(code)
 }
 
 func (c *LogCacheCLI) GetMetadata() plugin.PluginMetadata {
@@ -84,6 +259,10 @@ func (c *LogCacheCLI) GetMetadata() plugin.PluginMetadata {
 	// Ignore the error. If this doesn't unmarshal, then we want the default
 	// VersionType.
 	_ = json.Unmarshal([]byte(version), &v)
+	if v == (plugin.VersionType{}) {
+		// fall back to the version stamped into the cf package
+		fmt.Sscanf(cf.Version, "%d.%d.%d", &v.Major, &v.Minor, &v.Build)
+	}
 
 	return plugin.PluginMetadata{
 		Name:    "log-cache",
@@ -93,21 +272,52 @@ func (c *LogCacheCLI) GetMetadata() plugin.PluginMetadata {
 				Name:     "tail",
 				HelpText: "Output logs for a source-id/app",
 				UsageDetails: plugin.Usage{
-					Usage: `tail [options] <source-id/app>
+					Usage: `tail [options] <source-id/app>...
 
 ENVIRONMENT VARIABLES:
    LOG_CACHE_ADDR       Overrides the default location of log-cache.
    LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
 					Options: map[string]string{
-						"-start-time":         "Start of query range in UNIX nanoseconds.",
-						"-end-time":           "End of query range in UNIX nanoseconds.",
+						"-start-time":         "Start of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
+						"-end-time":           "End of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
 						"-envelope-type, -t":  "Envelope type filter. Available filters: 'log', 'counter', 'gauge', 'timer', 'event', and 'any'.",
 						"-envelope-class, -c": "Envelope class filter. Available filters: 'logs', 'metrics', and 'any'.",
 						"-follow, -f":         "Output appended to stdout as logs are egressed.",
 						"-json":               "Output envelopes in JSON format.",
+						"-pretty-json":        "Re-indent log payloads that are JSON objects for readability.",
+						"-field":              "Print only the given JSON field from structured log payloads. Dot paths reach nested fields. Repeatable.",
+						"-tag":                "Only show envelopes whose tags match the given key=value pair. Repeatable, all pairs must match.",
+						"-show-tags":          "Append each envelope's tags as sorted key=value pairs to the rendered line.",
+						"-promql-names":       "Render metric names the way the PromQL layer exposes them, e.g. dots become underscores.",
+						"-dry-run":            "Print the computed read URL and resolved source ID instead of performing the request.",
+						"-raw-source-names":   "Show platform source IDs as-is instead of recognizable component names.",
+						"-trace-id":           "Only print log lines containing the given request/trace ID. With no source argument, every source from meta is searched.",
+						"-output":             "Alternate output mode. 'json-lines' emits one compact JSON document per envelope as it arrives.",
 						"-lines, -n":          "Number of envelopes to return. Default is 10.",
 						"-new-line":           "Character used for new line substition, must be single unicode character. Default is '\\n'.",
 						"-name-filter":        "Filters metrics by name.",
+						"-verbose, -v":        "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+						"-quiet, -q":          "Suppress the retrieving preamble and other decorative output, even on a terminal.",
+						"-forward":            "Forward the tailed stream as NDJSON to a local socket, e.g. 'tcp://localhost:5140'.",
+						"-filter":             "Only show output lines containing the given substring, highlighting matches on a TTY.",
+						"-filter-regex":       "Only show output lines matching the given regex, highlighting matches on a TTY.",
+						"-audit":              "Record per-source ordering violations, duplicate timestamps, and gaps, and print an audit report at the end of the session.",
+						"-interactive":        "Control the stream with key presses: space pauses/resumes, 'b' replays scrollback, '/text' filters, 'q' quits. Implies --follow.",
+						"-source-colors":      "Color each line by a deterministic hash of its source GUID so interleaved streams stay distinguishable.",
+						"-counter-rates":      "Show the delta and per-second rate for each counter relative to its previous observation.",
+						"-endpoints":          "Comma separated ordered list of Log Cache endpoints, e.g. gateway, reverse proxy, direct node. Reads fall back to the next endpoint after repeated failures.",
+						"-dedup":              "Collapse consecutive identical log messages into a single line with an '(xN)' suffix.",
+						"-dedupe-window":      "Number of recent lines a message is deduplicated against. Defaults to 1. Implies --dedup when larger. A per-source removal count is reported at exit.",
+						"-quiet-errors":       "Append non-fatal warnings and diagnostics to the given file instead of stderr, keeping piped output clean.",
+						"-target":             "Tail the named profile from ~/.cf/log-cache-cli/profiles.json instead of the targeted cf API. Profiles can configure 'auth' and 'ca_cert'.",
+						"-space":              "Tail every app in the targeted space together, or in the named space when a space name is given instead of a source.",
+						"-preset":             "Insert the flags and arguments saved under the given name with log-cache-save.",
+						"-wrap":               "Wrap lines longer than the terminal width instead of printing them raw. Cannot be used with --no-wrap.",
+						"-no-wrap":            "Truncate lines longer than the terminal width. Cannot be used with --wrap.",
+						"-wrap-indent":        "Number of spaces continuation lines are indented by with --wrap. Default is 4.",
+						"-version-check":      "Verify the Log Cache version is within the tested range before running.",
+						"-strict-compat":      "Fail instead of warn when --version-check finds an incompatible Log Cache version.",
+						"-insecure-http":      "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
 					},
 				},
 			},
@@ -121,27 +331,278 @@ ENVIRONMENT VARIABLES:
    LOG_CACHE_ADDR       Overrides the default location of log-cache.
    LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
 					Options: map[string]string{
-						"-source-type": "Source type of information to show. Available: 'all', 'application', 'service', 'platform', and 'unknown'. Excludes unknown sources unless 'all' or 'unknown' is selected, or `--guid` is used.",
-						"-sort-by":     "Sort by specified column. Available: 'source-id', 'source', 'source-type', 'count', 'expired', 'cache-duration', and 'rate'.",
-						"-noise":       "Fetch and display the rate of envelopes per minute for the last minute. WARNING: This is slow...",
-						"-guid":        "Display raw source GUIDs with no source Names. Incompatible with 'source' and 'source-type' for --sort-by. Only allows 'platform' for --source-type",
+						"-source-type":        "Source type of information to show. Available: 'all', 'application', 'service', 'platform', and 'unknown'. Excludes unknown sources unless 'all' or 'unknown' is selected, or `--guid` is used.",
+						"-sort-by":            "Sort by specified column. Available: 'source-id', 'source', 'source-type', 'count', 'expired', 'cache-duration', and 'rate'.",
+						"-noise":              "Fetch and display the rate of envelopes per minute for the last minute. WARNING: This is slow...",
+						"-guid":               "Display raw source GUIDs with no source Names. Incompatible with 'source' and 'source-type' for --sort-by. Only allows 'platform' for --source-type",
+						"-by-org":             "Roll counts, expired totals, and rates up per organization. Sources without an org are grouped under '(platform)'.",
+						"-by-type":            "Break the count down by envelope type, sampled from each source's most recent envelopes. WARNING: This is slow...",
+						"-parallelism":        "Number of sources to sample concurrently for --by-type. Default is 10.",
+						"-show-crashes":       "Add a Crashes column counting app process crash audit events for the listed sources.",
+						"-columns":            "Comma-separated list of columns to show, in order, e.g. source,count,rate.",
+						"-sample":             "Append a timestamped meta sample per source to --sample-file at this interval. Requires --duration.",
+						"-duration":           "How long to keep sampling with --sample.",
+						"-sample-file":        "File the sample rows are appended to. A .ndjson extension writes NDJSON, anything else CSV.",
+						"-raw-source-names":   "Show platform source IDs as-is instead of recognizable component names.",
+						"-bosh-mapping":       "YAML file mapping platform source IDs to BOSH job/instance names, e.g. exported from 'bosh instances'.",
+						"-capi-batch-size":    "Number of GUIDs per CAPI name-resolution request, 1 to 200. Default is 50. Also settable via LOG_CACHE_CAPI_BATCH_SIZE.",
+						"-wide":               "Show every optional column: timestamps, per-type counts, and crashes.",
+						"-output":             "Output format. Available: 'table' and 'csv'. Default is 'table'.",
+						"-show-timestamps":    "Display the oldest and newest cached timestamp for each source as RFC3339.",
+						"-no-name-cache":      "Skip the on-disk GUID to name cache and always resolve names via CAPI.",
+						"-from-snapshot":      "Read meta from a snapshot file written by meta-daemon instead of querying Log Cache. Incompatible with --noise.",
+						"-verbose, -v":        "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+						"-quiet, -q":          "Suppress the retrieving preamble and other decorative output, even on a terminal.",
+						"-threshold":          "Mark sources whose --noise rate exceeds the given envelopes/minute and exit non-zero when any do.",
+						"-only-above":         "Only show sources exceeding --threshold.",
+						"-require-rate-above": "Only show sources whose --noise rate exceeds the given envelopes/minute.",
+						"-require-rate-below": "Only show sources whose --noise rate is under the given envelopes/minute.",
+						"-target-retention":   "Render Cache Duration as a percentage of the given target, e.g. '15m', with color thresholds on a terminal.",
+						"-version-check":      "Verify the Log Cache version is within the tested range before running.",
+						"-strict-compat":      "Fail instead of warn when --version-check finds an incompatible Log Cache version.",
+						"-insecure-http":      "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+					},
+				},
+			},
+			{
+				Name:     "log-cache-stats",
+				HelpText: "Show a bucketed histogram of envelope counts for a source",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-stats [options] <source-id/app>
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-start-time":    "Start of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
+						"-end-time":      "End of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
+						"-bucket":        "Histogram bucket width as a duration, e.g. '30s'. Default is 1m.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+					},
+				},
+			},
+			{
+				Name:     "log-cache-version",
+				HelpText: "Show the plugin version and build information",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-version`,
+				},
+			},
+			{
+				Name:     "log-cache-meta-diff",
+				HelpText: "Show per-source meta changes between two samples",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-meta-diff [options] [<snapshot-before> <snapshot-after>]
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-interval":      "Take two live meta samples separated by the given duration instead of reading snapshots.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
 					},
 				},
 			},
+			{
+				Name:     "log-cache-update",
+				HelpText: "Update the plugin to the latest GitHub release",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-update`,
+				},
+			},
+			{
+				Name:     "log-cache-latency",
+				HelpText: "Show latency percentiles from timer envelopes for a source",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-latency [options] <source-id/app>
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-start-time":    "Start of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
+						"-end-time":      "End of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+					},
+				},
+			},
+			{
+				Name:     "log-cache-bench",
+				HelpText: "Benchmark the log-cache read path for a source",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-bench [options] <source-id/app>
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-requests":      "Number of reads to issue. Default is 100.",
+						"-limit":         "Number of envelopes to request per read. Default is 1000.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+					},
+				},
+			},
+			{
+				Name:     "log-cache-blackbox",
+				HelpText: "Emit tagged test envelopes and read them back to measure loss and latency",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-blackbox --emit-url <url> [options] <source-id/app>
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-emit-url":      "Endpoint that accepts POSTed test payloads and emits them as logs for the source. Required.",
+						"-count":         "Number of test envelopes to emit. Default is 100.",
+						"-wait":          "How long to wait for emitted envelopes to appear, e.g. '30s'. Default is 30s.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+					},
+				},
+			},
+			{
+				Name:     "log-cache-emit",
+				HelpText: "Send synthetic envelopes to a log-cache ingress",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-emit --ingress-addr <addr> [options] <source-id>`,
+					Options: map[string]string{
+						"-ingress-addr": "HTTP address of the log-cache ingress to emit to. Required.",
+						"-type":         "Envelope type to emit: 'log', 'counter', or 'gauge'. Default is 'log'.",
+						"-count":        "Number of envelopes to emit. Default is 10.",
+						"-interval":     "Duration to wait between envelopes, e.g. '1s'. Default is 0s.",
+						"-message":      "Payload for log envelopes. The envelope index is appended.",
+						"-name":         "Name for counter and gauge envelopes. Default is 'log_cache_emit'.",
+						"-value":        "Delta for counter envelopes or value for gauge envelopes. Default is 1.",
+						"-unit":         "Unit for gauge envelopes.",
+					},
+				},
+			},
+			{
+				Name:     "meta-daemon",
+				HelpText: "Keep a rolling on-disk snapshot of meta information",
+				UsageDetails: plugin.Usage{
+					Usage: `meta-daemon --snapshot <path> [options]
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-snapshot":      "File to write the rolling meta snapshot to. Required.",
+						"-interval":      "Duration between refreshes, e.g. '1m'. Default is 1m.",
+						"-iterations":    "Exit after the given number of refreshes. Default is to run until interrupted.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+					},
+				},
+			},
+			{
+				Name:     "log-cache-top",
+				HelpText: "Continuously show the sources producing the most envelopes",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-top [options]
+
+KEY BINDINGS:
+   s  Cycle the sort column: rate, count, source.
+   a  Cycle the source type scope: all, application, service, platform.
+   q  Quit.
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-interval":      "Seconds between refreshes. Default is 5.",
+						"-lines, -n":     "Number of sources to show. Default is 25.",
+						"-sort-by":       "Sort by specified column. Available: 'rate', 'count', and 'source'. Default is 'rate'.",
+						"-iterations":    "Exit after the given number of refreshes. Default is to run until interrupted.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+					},
+				},
+			},
+			{
+				Name:     "export",
+				HelpText: "Export envelopes for a source-id/app to newline delimited JSON files",
+				UsageDetails: plugin.Usage{
+					Usage: `export [options] <source-id/app>
+
+ENVIRONMENT VARIABLES:
+   LOG_CACHE_ADDR       Overrides the default location of log-cache.
+   LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
+					Options: map[string]string{
+						"-start-time":    "Start of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
+						"-end-time":      "End of query range as UNIX nanoseconds, RFC3339, 'YYYY-MM-DD HH:MM', or a relative duration, e.g. '-15m'.",
+						"-split-by":      "Split output files along time boundaries. Available: 'hour' and 'day'.",
+						"-output-dir":    "Directory to write export files into. Default is the working directory.",
+						"-insecure-http": "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
+						"-verbose, -v":   "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+						"-quiet, -q":     "Suppress the exporting preamble and other decorative output, even on a terminal.",
+					},
+				},
+			},
+			{
+				Name:     "usage-report",
+				HelpText: "Print locally recorded Log Cache CLI usage counters",
+				UsageDetails: plugin.Usage{
+					Usage: `usage-report
+
+Counters are only recorded after opting in with "telemetry": true in
+~/.cf/log-cache-cli/config.json. They never leave the local machine.`,
+				},
+			},
+			{
+				Name:     "log-cache-save",
+				HelpText: "Save flags and arguments as a named preset for tail and query",
+				UsageDetails: plugin.Usage{
+					Usage: `log-cache-save <name> <flags and arguments>...
+
+Presets are stored in ~/.cf/log-cache-cli/presets.json and recalled with
+--preset <name>, e.g. 'cf log-cache-save crashes --filter Exited' then
+'cf tail my-app --preset crashes'.`,
+				},
+			},
+			{
+				Name:     "completion",
+				HelpText: "Print a shell completion script for the Log Cache commands",
+				UsageDetails: plugin.Usage{
+					Usage: `completion <bash|zsh>
+
+Completes command names, flags, and app names from the targeted space.
+Load it with e.g. 'eval "$(cf completion bash)"' in your shell profile.`,
+				},
+			},
 			{
 				Name:     "query",
 				HelpText: "Issues a PromQL query against Log Cache",
 				UsageDetails: plugin.Usage{
-					Usage: `query <promql-query> [options]
+					Usage: `query [<promql-query>] [options]
 
 ENVIRONMENT VARIABLES:
    LOG_CACHE_ADDR       Overrides the default location of log-cache.
    LOG_CACHE_SKIP_AUTH  Set to 'true' to disable CF authentication.`,
 					Options: map[string]string{
-						"-time":  "Effective time for query execution of an instant query. Cannont be used with --start, --end, or --step. Can be a unix timestamp or RFC3339.",
-						"-start": "Start time for a range query. Cannont be used with --time. Can be a unix timestamp or RFC3339.",
-						"-end":   "End time for a range query. Cannont be used with --time. Can be a unix timestamp or RFC3339.",
-						"-step":  "Step interval for a range query. Cannot be used with --time.",
+						"-time":           "Effective time for query execution of an instant query. Cannot be used with --start, --end, or --step. Can be a unix timestamp or RFC3339.",
+						"-start":          "Start time for a range query. Cannot be used with --time. Can be a unix timestamp or RFC3339.",
+						"-end":            "End time for a range query. Cannot be used with --time. Can be a unix timestamp or RFC3339.",
+						"-step":           "Step interval for a range query. Cannot be used with --time.",
+						"-query, -q":      "A PromQL query to evaluate. May be given multiple times; results are printed in labeled sections.",
+						"-query-file":     "File of PromQL queries, one per line. Blank lines and '#' comments are skipped.",
+						"-relative-times": "Render range query timestamps as offsets from --end, e.g. '-14m'.",
+						"-assert":         "Fail with a non-zero exit code when any sample violates the condition, e.g. '> 0.95'. Offending samples are printed.",
+						"-force":          "Skip the confirmation prompt shown before range queries with a large estimated result size.",
+						"-preset":         "Insert the flags and arguments saved under the given name with log-cache-save.",
+						"-record":         "Append a timestamped CSV row per sample to the given file on each run, building a simple longitudinal dataset.",
+						"-target":         "Run against the named profile from ~/.cf/log-cache-cli/profiles.json instead of the targeted cf API. Profiles can configure 'auth' and 'ca_cert'.",
+						"-profiles":       "Comma separated profile names from ~/.cf/log-cache-cli/profiles.json. Runs the instant query against each foundation and prints a side-by-side table.",
+						"-output":         "Output format. Available: 'table' for an aligned label table (instant vector results only), 'prom' for the Prometheus text exposition format, 'grafana' for Grafana dataframe JSON (range queries only), and 'sqlite:<path>' to load samples into a SQLite file via the sqlite3 CLI.",
+						"-verbose, -v":    "Increase output verbosity. -v adds progress and timing detail, -vv adds request logging.",
+						"-validate":       "Lint the queries locally for common syntax errors and warn about sources and metric names Log Cache has never seen, without evaluating them. Not a full PromQL parse; passing does not guarantee the server accepts the query.",
+						"-version-check":  "Verify the Log Cache version is within the tested range before running.",
+						"-strict-compat":  "Fail instead of warn when --version-check finds an incompatible Log Cache version.",
+						"-insecure-http":  "Allow a plain HTTP Log Cache address, e.g. a local development gateway. Unencrypted traffic is loudly called out.",
 					},
 				},
 			},
@@ -149,7 +610,36 @@ ENVIRONMENT VARIABLES:
 	}
 }
 
+// parseHeader parses a global --header value of the form "Key: Value",
+// exiting with a usage error if it is malformed.
+func parseHeader(value string) [2]string {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		fmt.Fprintf(os.Stderr, "--header must be of the form 'Key: Value'.\n")
+		os.Exit(cf.ExitCodeUsage)
+	}
+	return [2]string{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])}
+}
+
+// parseTimeout parses the global --timeout value, exiting with a usage
+// error if it is not a positive duration.
+func parseTimeout(value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		fmt.Fprintf(os.Stderr, "--timeout must be a positive duration, e.g. '30s'.\n")
+		os.Exit(cf.ExitCodeUsage)
+	}
+	return d
+}
+
 func main() {
+	var v plugin.VersionType
+	// Ignore the error. If this doesn't unmarshal, the command package keeps
+	// its default version.
+	if err := json.Unmarshal([]byte(version), &v); err == nil {
+		cf.Version = fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Build)
+	}
+
 	plugin.Start(&LogCacheCLI{})
 }
 
@@ -0,0 +1,107 @@
+package cf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// queryAssertion is a condition every sample of a query result must satisfy,
+// e.g. "> 0.95".
+type queryAssertion struct {
+	raw       string
+	op        string
+	threshold float64
+}
+
+func parseQueryAssertion(s string) (*queryAssertion, error) {
+	parts := strings.Fields(s)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf(`--assert must be of the form "<op> <value>", e.g. "> 0.95"`)
+	}
+
+	switch parts[0] {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return nil, fmt.Errorf("--assert operator must be one of >, >=, <, <=, ==, or !=")
+	}
+
+	threshold, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("--assert value must be a number: %s", parts[1])
+	}
+
+	return &queryAssertion{raw: s, op: parts[0], threshold: threshold}, nil
+}
+
+func (a *queryAssertion) holds(v float64) bool {
+	switch a.op {
+	case ">":
+		return v > a.threshold
+	case ">=":
+		return v >= a.threshold
+	case "<":
+		return v < a.threshold
+	case "<=":
+		return v <= a.threshold
+	case "==":
+		return v == a.threshold
+	default:
+		return v != a.threshold
+	}
+}
+
+// check returns a line per sample that violates the assertion.
+func (a *queryAssertion) check(result json.RawMessage) ([]string, error) {
+	var series []struct {
+		Metric map[string]string   `json:"metric"`
+		Value  []json.RawMessage   `json:"value,omitempty"`
+		Values [][]json.RawMessage `json:"values,omitempty"`
+	}
+	if err := json.Unmarshal(result, &series); err != nil {
+		return nil, err
+	}
+
+	var offending []string
+	for _, s := range series {
+		name := s.Metric["__name__"]
+
+		var labels []string
+		for k, v := range s.Metric {
+			if k == "__name__" {
+				continue
+			}
+			labels = append(labels, fmt.Sprintf("%s=%q", k, v))
+		}
+		sort.Strings(labels)
+
+		metric := name
+		if len(labels) > 0 {
+			metric = fmt.Sprintf("%s{%s}", name, strings.Join(labels, ","))
+		}
+
+		values := s.Values
+		if len(values) == 0 && len(s.Value) == 2 {
+			values = [][]json.RawMessage{s.Value}
+		}
+
+		for _, v := range values {
+			if len(v) != 2 {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(strings.Trim(string(v[1]), `"`), 64)
+			if err != nil {
+				continue
+			}
+
+			if !a.holds(value) {
+				offending = append(offending, fmt.Sprintf("%s %s", metric, strings.Trim(string(v[1]), `"`)))
+			}
+		}
+	}
+
+	return offending, nil
+}
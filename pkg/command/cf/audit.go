@@ -0,0 +1,61 @@
+package cf
+
+import (
+	"fmt"
+	"time"
+)
+
+// auditGapThreshold is the spacing between consecutive envelopes of a source
+// above which the audit reports a gap.
+const auditGapThreshold = time.Minute
+
+// envelopeAudit records per-source ordering anomalies observed during a
+// read/follow session. It is used to validate Log Cache storage correctness
+// from the outside.
+type envelopeAudit struct {
+	lastTimestamp map[string]int64
+
+	envelopes  int
+	violations int
+	duplicates int
+	gaps       int
+}
+
+func newEnvelopeAudit() *envelopeAudit {
+	return &envelopeAudit{
+		lastTimestamp: make(map[string]int64),
+	}
+}
+
+// observe feeds an envelope to the audit. Envelopes are expected in ascending
+// timestamp order per source; anything else counts against the source.
+func (a *envelopeAudit) observe(sourceID string, timestamp int64) {
+	a.envelopes++
+
+	last, seen := a.lastTimestamp[sourceID]
+	if seen {
+		switch {
+		case timestamp < last:
+			a.violations++
+		case timestamp == last:
+			a.duplicates++
+		case time.Duration(timestamp-last) > auditGapThreshold:
+			a.gaps++
+		}
+	}
+
+	if !seen || timestamp > last {
+		a.lastTimestamp[sourceID] = timestamp
+	}
+}
+
+// report renders the audit summary.
+func (a *envelopeAudit) report() []string {
+	return []string{
+		"Audit report:",
+		fmt.Sprintf("  Envelopes:               %d", a.envelopes),
+		fmt.Sprintf("  Monotonicity violations: %d", a.violations),
+		fmt.Sprintf("  Duplicate timestamps:    %d", a.duplicates),
+		fmt.Sprintf("  Gaps over %s:          %d", auditGapThreshold, a.gaps),
+	}
+}
@@ -0,0 +1,124 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+	flags "github.com/jessevdk/go-flags"
+)
+
+type BenchOption func(*benchOptionFlags)
+
+// Bench repeatedly reads a window of envelopes for a source and reports
+// requests/sec, envelopes/sec, and latency percentiles for the read path, so
+// operators can validate log-cache performance after scaling changes.
+func Bench(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	bopts ...BenchOption,
+) {
+	opts := getBenchOptions(args, log, bopts...)
+	v := newVerboseLogger(log, len(opts.Verbose))
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+
+	sourceID, _ := getGUID(opts.source, cli, log)
+	if sourceID == "" {
+		// fall back to provided name
+		sourceID = opts.source
+	}
+
+	var (
+		latencies []time.Duration
+		envelopes int
+		errors    int
+	)
+
+	benchStart := time.Now()
+	for i := 0; i < opts.Requests; i++ {
+		readStart := time.Now()
+		batch, err := client.Read(
+			ctx,
+			sourceID,
+			time.Unix(0, 0),
+			logcache.WithLimit(opts.Limit),
+			logcache.WithDescending(),
+		)
+		latencies = append(latencies, time.Since(readStart))
+
+		if err != nil {
+			errors++
+			v.V(1, "Read failed: %s", err)
+			continue
+		}
+		envelopes += len(batch)
+	}
+	elapsed := time.Since(benchStart)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	seconds := elapsed.Seconds()
+	if seconds == 0 {
+		seconds = 1
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintf(tw, "Requests\t%d\n", opts.Requests)
+	fmt.Fprintf(tw, "Errors\t%d\n", errors)
+	fmt.Fprintf(tw, "Envelopes\t%d\n", envelopes)
+	fmt.Fprintf(tw, "Duration\t%s\n", elapsed.Truncate(time.Millisecond))
+	fmt.Fprintf(tw, "Requests/sec\t%.1f\n", float64(opts.Requests)/seconds)
+	fmt.Fprintf(tw, "Envelopes/sec\t%.1f\n", float64(envelopes)/seconds)
+	fmt.Fprintf(tw, "Latency p50\t%s\n", percentile(latencies, 0.50))
+	fmt.Fprintf(tw, "Latency p90\t%s\n", percentile(latencies, 0.90))
+	fmt.Fprintf(tw, "Latency p99\t%s\n", percentile(latencies, 0.99))
+	if err := tw.Flush(); err != nil {
+		log.Fatalf("Error writing results")
+	}
+}
+
+type benchOptionFlags struct {
+	Requests     int    `long:"requests" default:"100"`
+	Limit        int    `long:"limit" default:"1000"`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Verbose      []bool `short:"v" long:"verbose"`
+
+	source string
+}
+
+func getBenchOptions(args []string, log Logger, bopts ...BenchOption) benchOptionFlags {
+	var opts benchOptionFlags
+
+	for _, o := range bopts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Expected 1 argument, got %d.", len(args))
+	}
+	opts.source = args[0]
+
+	if opts.Requests < 1 {
+		log.Fatalf("--requests must be at least 1.")
+	}
+
+	if opts.Limit < 1 || opts.Limit > MaximumBatchSize {
+		log.Fatalf("--limit must be between 1 and %d.", MaximumBatchSize)
+	}
+
+	return opts
+}
@@ -0,0 +1,113 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bench", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+		cliConn.usernameResp = "a-user"
+		tableWriter = bytes.NewBuffer(nil)
+	})
+
+	It("reads the source repeatedly and reports throughput", func() {
+		httpClient.responseBody = []string{
+			`{"envelopes":{"batch":[
+				{"timestamp":"10000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}},
+				{"timestamp":"20000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}}
+			]}}`,
+			`{"envelopes":{"batch":[
+				{"timestamp":"30000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}}
+			]}}`,
+			`{"envelopes":{"batch":[]}}`,
+		}
+
+		cf.Bench(
+			context.Background(),
+			cliConn,
+			[]string{"--requests", "3", "app-name"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(httpClient.requestCount()).To(Equal(3))
+
+		lines := strings.Split(tableWriter.String(), "\n")
+		Expect(lines).To(HaveLen(10))
+		Expect(lines[0]).To(Equal("Requests       3"))
+		Expect(lines[1]).To(Equal("Errors         0"))
+		Expect(lines[2]).To(Equal("Envelopes      3"))
+		Expect(lines[3]).To(MatchRegexp(`^Duration       \S+$`))
+		Expect(lines[4]).To(MatchRegexp(`^Requests/sec   [0-9.]+$`))
+		Expect(lines[5]).To(MatchRegexp(`^Envelopes/sec  [0-9.]+$`))
+		Expect(lines[6]).To(MatchRegexp(`^Latency p50    \S+$`))
+		Expect(lines[7]).To(MatchRegexp(`^Latency p90    \S+$`))
+		Expect(lines[8]).To(MatchRegexp(`^Latency p99    \S+$`))
+	})
+
+	It("counts failed reads as errors", func() {
+		httpClient.responseCode = 500
+
+		cf.Bench(
+			context.Background(),
+			cliConn,
+			[]string{"--requests", "2", "app-name"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		lines := strings.Split(tableWriter.String(), "\n")
+		Expect(lines[1]).To(Equal("Errors         2"))
+		Expect(lines[2]).To(Equal("Envelopes      0"))
+	})
+
+	It("fatally logs when given too many arguments", func() {
+		Expect(func() {
+			cf.Bench(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "other"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Expected 1 argument, got 2."))
+	})
+
+	It("fatally logs when --requests is less than 1", func() {
+		Expect(func() {
+			cf.Bench(
+				context.Background(),
+				cliConn,
+				[]string{"--requests", "0", "app-name"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--requests must be at least 1."))
+	})
+})
@@ -0,0 +1,194 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+	flags "github.com/jessevdk/go-flags"
+)
+
+type BlackboxOption func(*blackboxOptionFlags)
+
+// WithBlackboxTag fixes the tag used to mark test envelopes, used in tests.
+func WithBlackboxTag(tag string) BlackboxOption {
+	return func(o *blackboxOptionFlags) {
+		o.tag = tag
+	}
+}
+
+// WithBlackboxPollDuration overrides the delay between reads, used in tests.
+func WithBlackboxPollDuration(d time.Duration) BlackboxOption {
+	return func(o *blackboxOptionFlags) {
+		o.pollDuration = &d
+	}
+}
+
+// Blackbox emits uniquely tagged test envelopes via an emit endpoint and
+// reads them back from log-cache, reporting end-to-end loss and latency.
+// It replaces the reliability checks operators otherwise cobble together
+// with separate tools.
+func Blackbox(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	bopts ...BlackboxOption,
+) {
+	opts := getBlackboxOptions(args, log, bopts...)
+	v := newVerboseLogger(log, len(opts.Verbose))
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+
+	sourceID, _ := getGUID(opts.source, cli, log)
+	if sourceID == "" {
+		// fall back to provided name
+		sourceID = opts.source
+	}
+
+	emitStart := time.Now()
+	sent := make(map[string]time.Time, opts.Count)
+	for i := 0; i < opts.Count; i++ {
+		payload := fmt.Sprintf("%s-%d", opts.tag, i)
+		req, err := http.NewRequest("POST", opts.EmitURL, strings.NewReader(payload))
+		if err != nil {
+			fatalf(log, ExitCodeUsage, "Invalid emit URL: %s", err)
+		}
+		sent[payload] = time.Now()
+
+		resp, err := c.Do(req)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to emit test envelope: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			fatalf(log, ExitCodeNetwork, "Failed to emit test envelope: expected 200 response code, got %d.", resp.StatusCode)
+		}
+	}
+	v.V(1, "Emitted %d test envelopes in %s", opts.Count, time.Since(emitStart))
+
+	poll := time.Second
+	if opts.pollDuration != nil {
+		poll = *opts.pollDuration
+	}
+
+	latencies := make(map[string]time.Duration, opts.Count)
+	deadline := time.Now().Add(opts.wait)
+	for len(latencies) < opts.Count && time.Now().Before(deadline) {
+		envelopes, err := client.Read(
+			ctx,
+			sourceID,
+			emitStart,
+			logcache.WithLimit(MaximumBatchSize),
+		)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read envelopes: %s", err)
+		}
+
+		now := time.Now()
+		for _, e := range envelopes {
+			l, ok := e.Message.(*loggregator_v2.Envelope_Log)
+			if !ok {
+				continue
+			}
+
+			payload := string(l.Log.GetPayload())
+			sendTime, ok := sent[payload]
+			if !ok {
+				continue
+			}
+			if _, seen := latencies[payload]; seen {
+				continue
+			}
+			latencies[payload] = now.Sub(sendTime)
+		}
+
+		if len(latencies) < opts.Count {
+			time.Sleep(poll)
+		}
+	}
+
+	received := len(latencies)
+	lost := opts.Count - received
+
+	var sorted []time.Duration
+	for _, d := range latencies {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintf(tw, "Emitted\t%d\n", opts.Count)
+	fmt.Fprintf(tw, "Received\t%d\n", received)
+	fmt.Fprintf(tw, "Lost\t%d (%.1f%%)\n", lost, float64(lost)/float64(opts.Count)*100)
+	if received > 0 {
+		fmt.Fprintf(tw, "Latency p50\t%s\n", percentile(sorted, 0.50))
+		fmt.Fprintf(tw, "Latency p90\t%s\n", percentile(sorted, 0.90))
+		fmt.Fprintf(tw, "Latency p99\t%s\n", percentile(sorted, 0.99))
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatalf("Error writing results")
+	}
+}
+
+type blackboxOptionFlags struct {
+	EmitURL      string `long:"emit-url"`
+	Count        int    `long:"count" default:"100"`
+	Wait         string `long:"wait" default:"30s"`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Verbose      []bool `short:"v" long:"verbose"`
+
+	source       string
+	tag          string
+	wait         time.Duration
+	pollDuration *time.Duration
+}
+
+func getBlackboxOptions(args []string, log Logger, bopts ...BlackboxOption) blackboxOptionFlags {
+	var opts blackboxOptionFlags
+
+	for _, o := range bopts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Expected 1 argument, got %d.", len(args))
+	}
+	opts.source = args[0]
+
+	if opts.EmitURL == "" {
+		log.Fatalf("--emit-url is required.")
+	}
+
+	if opts.Count < 1 {
+		log.Fatalf("--count must be at least 1.")
+	}
+
+	opts.wait, err = time.ParseDuration(opts.Wait)
+	if err != nil {
+		log.Fatalf("--wait must be a duration, e.g. '30s': %s", err)
+	}
+	if opts.wait <= 0 {
+		log.Fatalf("--wait must be positive.")
+	}
+
+	if opts.tag == "" {
+		opts.tag = fmt.Sprintf("blackbox-%d", time.Now().UnixNano())
+	}
+
+	return opts
+}
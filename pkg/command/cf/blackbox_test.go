@@ -0,0 +1,125 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Blackbox", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+		cliConn.usernameResp = "a-user"
+		tableWriter = bytes.NewBuffer(nil)
+	})
+
+	It("emits tagged envelopes and reads them back", func() {
+		httpClient.responseBody = []string{
+			// emit responses
+			"", "",
+			// base64 of "test-tag-0" and "test-tag-1"
+			`{"envelopes":{"batch":[
+				{"timestamp":"10000000000","source_id":"app-guid","log":{"payload":"dGVzdC10YWctMA=="}},
+				{"timestamp":"20000000000","source_id":"app-guid","log":{"payload":"dGVzdC10YWctMQ=="}}
+			]}}`,
+		}
+
+		cf.Blackbox(
+			context.Background(),
+			cliConn,
+			[]string{"--emit-url", "https://emit.some-system.com/emit", "--count", "2", "app-name"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithBlackboxTag("test-tag"),
+			cf.WithBlackboxPollDuration(0),
+		)
+
+		Expect(httpClient.requestCount()).To(Equal(3))
+		Expect(httpClient.requestURLs[0]).To(Equal("https://emit.some-system.com/emit"))
+		Expect(httpClient.requestBodies[0]).To(Equal("test-tag-0"))
+		Expect(httpClient.requestBodies[1]).To(Equal("test-tag-1"))
+
+		lines := strings.Split(tableWriter.String(), "\n")
+		Expect(lines).To(HaveLen(7))
+		Expect(lines[0]).To(Equal("Emitted      2"))
+		Expect(lines[1]).To(Equal("Received     2"))
+		Expect(lines[2]).To(Equal("Lost         0 (0.0%)"))
+		Expect(lines[3]).To(MatchRegexp(`^Latency p50  \S+$`))
+		Expect(lines[4]).To(MatchRegexp(`^Latency p90  \S+$`))
+		Expect(lines[5]).To(MatchRegexp(`^Latency p99  \S+$`))
+	})
+
+	It("reports envelopes that never come back as lost", func() {
+		httpClient.responseBody = []string{
+			"", "",
+			`{"envelopes":{"batch":[
+				{"timestamp":"10000000000","source_id":"app-guid","log":{"payload":"dGVzdC10YWctMA=="}}
+			]}}`,
+		}
+
+		cf.Blackbox(
+			context.Background(),
+			cliConn,
+			[]string{"--emit-url", "https://emit.some-system.com/emit", "--count", "2", "--wait", "1ms", "app-name"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithBlackboxTag("test-tag"),
+			cf.WithBlackboxPollDuration(5*time.Millisecond),
+		)
+
+		lines := strings.Split(tableWriter.String(), "\n")
+		Expect(lines[0]).To(Equal("Emitted      2"))
+		Expect(lines[1]).To(Equal("Received     1"))
+		Expect(lines[2]).To(Equal("Lost         1 (50.0%)"))
+	})
+
+	It("fatally logs without an emit URL", func() {
+		Expect(func() {
+			cf.Blackbox(
+				context.Background(),
+				cliConn,
+				[]string{"app-name"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--emit-url is required."))
+	})
+
+	It("fatally logs when an emit fails", func() {
+		httpClient.responseCode = 503
+
+		Expect(func() {
+			cf.Blackbox(
+				context.Background(),
+				cliConn,
+				[]string{"--emit-url", "https://emit.some-system.com/emit", "--count", "1", "app-name"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Failed to emit test envelope: expected 200 response code, got 503."))
+	})
+})
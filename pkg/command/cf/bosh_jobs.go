@@ -0,0 +1,43 @@
+package cf
+
+import (
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// loadBoshMapping reads a YAML mapping of platform source IDs to BOSH
+// job/instance names, e.g.:
+//
+//	doppler: doppler/0a1b2c3d-...
+//	rep: diego-cell/4e5f6a7b-...
+//
+// The file is written by hand or exported from a director with
+// 'bosh instances'. It lets meta output name the actual VM behind a
+// platform source instead of a bare source string.
+func loadBoshMapping(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping map[string]string
+	if err := yaml.Unmarshal(data, &mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// applyBoshMapping renames platform rows to their BOSH job/instance names.
+// Application and service sources already have friendly names and are left
+// alone.
+func applyBoshMapping(rows []displayRow, mapping map[string]string) {
+	for i, r := range rows {
+		if r.Type != sourceTypePlatform {
+			continue
+		}
+		if job, ok := mapping[r.SourceID]; ok {
+			rows[i].Source = job
+		}
+	}
+}
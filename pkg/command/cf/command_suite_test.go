@@ -35,17 +35,36 @@ func (l *stubLogger) Printf(format string, args ...interface{}) {
 	l.printfMessages = append(l.printfMessages, fmt.Sprintf(format, args...))
 }
 
+// stubCodeLogger additionally records the exit code of classified fatal
+// errors, like the plugin binary's logger does.
+type stubCodeLogger struct {
+	stubLogger
+	fatalfCode int
+}
+
+func (l *stubCodeLogger) FatalfWithCode(code int, format string, args ...interface{}) {
+	l.fatalfCode = code
+	l.Fatalf(format, args...)
+}
+
 type stubWriter struct {
+	mu    sync.Mutex
 	bytes []byte
 }
 
 // stubWriter implements io.Writer
 func (w *stubWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	w.bytes = append(w.bytes, p...)
 	return len(p), nil
 }
 
 func (w *stubWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	return strings.Split(strings.TrimRight(string(w.bytes), "\n\t "), "\n")
 }
 
@@ -58,6 +77,7 @@ type stubHTTPClient struct {
 
 	requestURLs    []string
 	requestHeaders []http.Header
+	requestBodies  []string
 
 	serverVersion string
 }
@@ -86,6 +106,13 @@ func (s *stubHTTPClient) Do(r *http.Request) (*http.Response, error) {
 	s.requestURLs = append(s.requestURLs, r.URL.String())
 	s.requestHeaders = append(s.requestHeaders, r.Header)
 
+	var requestBody string
+	if r.Body != nil {
+		b, _ := ioutil.ReadAll(r.Body)
+		requestBody = string(b)
+	}
+	s.requestBodies = append(s.requestBodies, requestBody)
+
 	var body string
 	if s.responseCount < len(s.responseBody) {
 		body = s.responseBody[s.responseCount]
@@ -130,6 +157,17 @@ type stubCliConnection struct {
 	spaceName    string
 	spaceErr     error
 
+	getAppName   string
+	getAppResult plugin_models.GetAppModel
+	getAppErr    error
+
+	getAppsResult []plugin_models.GetAppsModel
+	getAppsErr    error
+
+	getSpaceName   string
+	getSpaceResult plugin_models.GetSpace_Model
+	getSpaceErr    error
+
 	accessTokenCount int
 	accessToken      string
 	accessTokenErr   error
@@ -150,6 +188,9 @@ func (s *stubCliConnection) HasAPIEndpoint() (bool, error) {
 }
 
 func (s *stubCliConnection) CliCommandWithoutTerminalOutput(args ...string) ([]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
 	s.cliCommandArgs = append(s.cliCommandArgs, args)
 	commandIndex := len(s.cliCommandArgs) - 1
 
@@ -183,6 +224,29 @@ func (s *stubCliConnection) GetCurrentSpace() (plugin_models.Space, error) {
 	}, s.spaceErr
 }
 
+func (s *stubCliConnection) GetApp(name string) (plugin_models.GetAppModel, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.getAppName = name
+	return s.getAppResult, s.getAppErr
+}
+
+func (s *stubCliConnection) GetApps() ([]plugin_models.GetAppsModel, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.getAppsResult, s.getAppsErr
+}
+
+func (s *stubCliConnection) GetSpace(name string) (plugin_models.GetSpace_Model, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.getSpaceName = name
+	return s.getSpaceResult, s.getSpaceErr
+}
+
 func (s *stubCliConnection) AccessToken() (string, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -0,0 +1,125 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// completionFlags lists the long flags completed for each plugin command.
+// The lists mirror the options advertised in the plugin metadata.
+var completionFlags = map[string][]string{
+	"tail": {
+		"--start-time", "--end-time", "--envelope-type", "--envelope-class",
+		"--follow", "--json", "--lines", "--new-line", "--name-filter",
+		"--forward", "--filter", "--filter-regex", "--audit", "--interactive",
+		"--source-colors", "--counter-rates", "--endpoints", "--dedup",
+		"--dedupe-window", "--space", "--wrap", "--no-wrap", "--wrap-indent",
+		"--version-check", "--strict-compat", "--insecure-http", "--verbose",
+	},
+	"log-meta": {
+		"--source-type", "--sort-by", "--noise", "--guid", "--by-type",
+		"--parallelism", "--show-crashes", "--output", "--show-timestamps",
+		"--no-name-cache", "--from-snapshot", "--threshold", "--only-above",
+		"--require-rate-above", "--require-rate-below", "--version-check",
+		"--strict-compat", "--insecure-http", "--verbose",
+	},
+	"log-cache-stats": {
+		"--start-time", "--end-time", "--bucket", "--insecure-http", "--verbose",
+	},
+	"meta-daemon": {
+		"--snapshot", "--interval", "--iterations", "--insecure-http", "--verbose",
+	},
+	"log-cache-top": {
+		"--interval", "--lines", "--sort-by", "--iterations", "--insecure-http",
+		"--verbose",
+	},
+	"export": {
+		"--start-time", "--end-time", "--split-by", "--output-dir",
+		"--insecure-http", "--verbose",
+	},
+	"query": {
+		"--time", "--start", "--end", "--step", "--query", "--query-file",
+		"--relative-times", "--assert", "--force", "--profiles", "--output",
+		"--version-check", "--strict-compat", "--insecure-http", "--verbose",
+	},
+	"usage-report": {},
+	"completion":   {},
+}
+
+// completionSourceCommands take app names or source IDs as positional
+// arguments, so their non-flag completions come from the targeted space.
+var completionSourceCommands = map[string]bool{
+	"tail":            true,
+	"export":          true,
+	"log-cache-stats": true,
+}
+
+// Completion prints a shell completion script for the plugin's commands that
+// completes flag names and app names from the targeted space.
+func Completion(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+) {
+	if len(args) != 1 {
+		log.Fatalf("Expected 1 argument, got %d.", len(args))
+	}
+
+	shell := strings.ToLower(args[0])
+	if shell != "bash" && shell != "zsh" {
+		log.Fatalf("Unsupported shell: %s. Available: 'bash' and 'zsh'.", args[0])
+	}
+
+	lw := lineWriter{w: w}
+	if shell == "zsh" {
+		// zsh runs bash completion functions via its compatibility layer
+		lw.Write("autoload -U +X bashcompinit && bashcompinit")
+	}
+	lw.Write(completionScript())
+}
+
+func completionScript() string {
+	var names []string
+	for name := range completionFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("_cf_log_cache() {\n")
+	b.WriteString("    local cur cmd\n")
+	b.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    cmd=\"${COMP_WORDS[1]}\"\n")
+	b.WriteString("\n")
+	b.WriteString("    if [[ $COMP_CWORD -eq 1 ]]; then\n")
+	b.WriteString(fmt.Sprintf("        COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", strings.Join(names, " ")))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("\n")
+	b.WriteString("    case \"$cmd\" in\n")
+	for _, name := range names {
+		b.WriteString(fmt.Sprintf("    %s)\n", name))
+		b.WriteString("        if [[ \"$cur\" == -* ]]; then\n")
+		b.WriteString(fmt.Sprintf("            COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", strings.Join(completionFlags[name], " ")))
+		if completionSourceCommands[name] {
+			b.WriteString("        else\n")
+			b.WriteString("            COMPREPLY=( $(compgen -W \"$(cf apps | awk 'NR>4 {print $1}')\" -- \"$cur\") )\n")
+		}
+		b.WriteString("        fi\n")
+		b.WriteString("        ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	b.WriteString("\n")
+	b.WriteString("complete -F _cf_log_cache cf")
+
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package cf_test
+
+import (
+	"context"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Completion", func() {
+	var (
+		logger *stubLogger
+		writer *stubWriter
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		writer = &stubWriter{}
+	})
+
+	completion := func(args ...string) string {
+		cf.Completion(
+			context.Background(),
+			newStubCliConnection(),
+			args,
+			newStubHTTPClient(),
+			logger,
+			writer,
+		)
+		return strings.Join(writer.lines(), "\n")
+	}
+
+	It("prints a bash completion script covering commands, flags, and app names", func() {
+		script := completion("bash")
+
+		Expect(script).To(ContainSubstring("complete -F _cf_log_cache cf"))
+		Expect(script).To(ContainSubstring("completion export log-cache-stats log-cache-top log-meta meta-daemon query tail usage-report"))
+		Expect(script).To(ContainSubstring("--envelope-type"))
+		Expect(script).To(ContainSubstring("cf apps"))
+		Expect(script).ToNot(ContainSubstring("bashcompinit"))
+	})
+
+	It("prefixes the zsh script with the bash compatibility layer", func() {
+		script := completion("zsh")
+
+		Expect(writer.lines()[0]).To(Equal("autoload -U +X bashcompinit && bashcompinit"))
+		Expect(script).To(ContainSubstring("complete -F _cf_log_cache cf"))
+	})
+
+	It("fatally logs when no shell is given", func() {
+		Expect(func() {
+			completion()
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Expected 1 argument, got 0."))
+	})
+
+	It("fatally logs for an unsupported shell", func() {
+		Expect(func() {
+			completion("fish")
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Unsupported shell: fish. Available: 'bash' and 'zsh'."))
+	})
+})
@@ -0,0 +1,54 @@
+package cf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// userDefaults are per-user output defaults read from
+// ~/.config/log-cache-cli/config.yml. Every value is optional and any flag
+// given on the command line wins over the file.
+type userDefaults struct {
+	TimestampFormat string `yaml:"timestamp_format"`
+	NoColor         bool   `yaml:"no_color"`
+	Lines           int    `yaml:"lines"`
+	EnvelopeType    string `yaml:"envelope_type"`
+	NoiseWindow     string `yaml:"noise_window"`
+}
+
+func userDefaultsPath() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(base, "log-cache-cli", "config.yml")
+}
+
+// loadUserDefaults returns the configured defaults, or the zero value when
+// the file is missing or malformed; defaults must never break a command.
+func loadUserDefaults() userDefaults {
+	path := userDefaultsPath()
+	if path == "" {
+		return userDefaults{}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return userDefaults{}
+	}
+
+	var d userDefaults
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return userDefaults{}
+	}
+
+	return d
+}
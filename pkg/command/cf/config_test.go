@@ -0,0 +1,137 @@
+package cf_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("User defaults", func() {
+	var (
+		logger     *stubLogger
+		writer     *stubWriter
+		httpClient *stubHTTPClient
+		cliConn    *stubCliConnection
+		configHome string
+		startTime  time.Time
+	)
+
+	BeforeEach(func() {
+		startTime = time.Now().Truncate(time.Second).Add(-time.Minute)
+		logger = &stubLogger{}
+		writer = &stubWriter{}
+
+		httpClient = newStubHTTPClient()
+		httpClient.responseBody = []string{responseBody(startTime)}
+
+		cliConn = newStubCliConnection()
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+
+		var err error
+		configHome, err = ioutil.TempDir("", "config")
+		Expect(err).ToNot(HaveOccurred())
+		os.Setenv("XDG_CONFIG_HOME", configHome)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("XDG_CONFIG_HOME")
+	})
+
+	writeConfig := func(content string) {
+		dir := filepath.Join(configHome, "log-cache-cli")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(
+			filepath.Join(dir, "config.yml"),
+			[]byte(content),
+			0644,
+		)).To(Succeed())
+	}
+
+	It("applies lines and envelope_type defaults from the config file", func() {
+		writeConfig("lines: 2\nenvelope_type: gauge\n")
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(httpClient.requestURLs).To(HaveLen(1))
+		requestURL, err := url.Parse(httpClient.requestURLs[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requestURL.Query().Get("limit")).To(Equal("2"))
+		Expect(requestURL.Query().Get("envelope_types")).To(Equal("GAUGE"))
+	})
+
+	It("lets flags win over configured defaults", func() {
+		writeConfig("lines: 2\n")
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name", "--lines", "3"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(httpClient.requestURLs).To(HaveLen(1))
+		requestURL, err := url.Parse(httpClient.requestURLs[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requestURL.Query().Get("limit")).To(Equal("3"))
+	})
+
+	It("disables match highlighting with no_color", func() {
+		writeConfig("no_color: true\n")
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name", "--filter", "ERR"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(
+				"   %s [APP/PROC/WEB/0] ERR log body",
+				startTime.Format("2006-01-02T15:04:05.00-0700"),
+			),
+		}))
+	})
+
+	It("is ignored when the config file is missing or malformed", func() {
+		writeConfig("lines: [not, a, number]\n")
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(httpClient.requestURLs).To(HaveLen(1))
+		requestURL, err := url.Parse(httpClient.requestURLs[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requestURL.Query().Get("limit")).To(Equal("10"))
+	})
+})
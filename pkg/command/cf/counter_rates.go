@@ -0,0 +1,66 @@
+package cf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// counterRateTracker remembers the previous observation of each counter so
+// --counter-rates can render deltas and per-second rates alongside the raw
+// monotonically increasing totals.
+type counterRateTracker struct {
+	prev map[string]counterObservation
+}
+
+type counterObservation struct {
+	total     uint64
+	timestamp int64
+}
+
+func newCounterRateTracker() *counterRateTracker {
+	return &counterRateTracker{prev: make(map[string]counterObservation)}
+}
+
+// observe returns a suffix describing the change since the previous
+// observation of the same counter name and tags, e.g.
+// " (delta: 10, rate: 5.00/s)". The first observation has nothing to compare
+// against and returns false.
+func (t *counterRateTracker) observe(e *loggregator_v2.Envelope) (string, bool) {
+	c, ok := e.Message.(*loggregator_v2.Envelope_Counter)
+	if !ok {
+		return "", false
+	}
+
+	key := counterKey(e)
+	prev, seen := t.prev[key]
+	t.prev[key] = counterObservation{
+		total:     c.Counter.GetTotal(),
+		timestamp: e.GetTimestamp(),
+	}
+	if !seen {
+		return "", false
+	}
+
+	delta := int64(c.Counter.GetTotal()) - int64(prev.total)
+	elapsed := time.Duration(e.GetTimestamp() - prev.timestamp)
+	if elapsed <= 0 {
+		return fmt.Sprintf(" (delta: %d)", delta), true
+	}
+
+	rate := float64(delta) / elapsed.Seconds()
+	return fmt.Sprintf(" (delta: %d, rate: %.2f/s)", delta, rate), true
+}
+
+func counterKey(e *loggregator_v2.Envelope) string {
+	tags := make([]string, 0, len(e.GetTags()))
+	for k, v := range e.GetTags() {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+
+	return e.GetSourceId() + "/" + e.GetCounter().GetName() + "{" + strings.Join(tags, ",") + "}"
+}
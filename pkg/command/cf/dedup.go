@@ -0,0 +1,123 @@
+package cf
+
+import (
+	"fmt"
+	"sort"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// deduper collapses consecutive identical log messages into a single line
+// with an "(xN)" suffix, like syslog's "last message repeated" behavior.
+// A line is held back until a different message arrives or the session ends.
+// With a window larger than one, a message that repeats any of the last N
+// emitted lines is dropped entirely, which also catches duplicates that
+// arrive interleaved with other output.
+type deduper struct {
+	write   func(string) error
+	window  int
+	recent  []string
+	lastKey string
+	line    string
+	count   int
+	removed map[string]int
+}
+
+func newDeduper(write func(string) error, window int) *deduper {
+	return &deduper{
+		write:   write,
+		window:  window,
+		removed: make(map[string]int),
+	}
+}
+
+// observe buffers the line when it repeats the previous message. An empty
+// key marks a line that never deduplicates, e.g. a metric envelope.
+func (d *deduper) observe(sourceID, key, line string) error {
+	if key != "" && key == d.lastKey {
+		d.count++
+		d.removed[sourceID]++
+		return nil
+	}
+
+	if key != "" && d.inWindow(key) {
+		d.removed[sourceID]++
+		return nil
+	}
+
+	if err := d.flush(); err != nil {
+		return err
+	}
+
+	if key == "" {
+		return d.write(line)
+	}
+
+	d.lastKey = key
+	d.line = line
+	d.count = 1
+	return nil
+}
+
+func (d *deduper) inWindow(key string) bool {
+	for _, k := range d.recent {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// remember keeps the keys of the last window-1 emitted lines; the held line
+// itself occupies the remaining slot.
+func (d *deduper) remember(key string) {
+	if d.window <= 1 {
+		return
+	}
+
+	d.recent = append(d.recent, key)
+	if len(d.recent) > d.window-1 {
+		d.recent = d.recent[1:]
+	}
+}
+
+// summary reports, per source, how many duplicate lines were removed.
+func (d *deduper) summary() []string {
+	var ids []string
+	for id := range d.removed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var lines []string
+	for _, id := range ids {
+		lines = append(lines, fmt.Sprintf("Removed %d duplicate lines for %s.", d.removed[id], id))
+	}
+	return lines
+}
+
+// flush emits the held line, annotated with how often it repeated.
+func (d *deduper) flush() error {
+	if d.count == 0 {
+		return nil
+	}
+
+	line := d.line
+	if d.count > 1 {
+		line = fmt.Sprintf("%s (x%d)", line, d.count)
+	}
+
+	d.remember(d.lastKey)
+	d.lastKey = ""
+	d.count = 0
+	return d.write(line)
+}
+
+func dedupKey(e *loggregator_v2.Envelope) string {
+	l, ok := e.Message.(*loggregator_v2.Envelope_Log)
+	if !ok {
+		return ""
+	}
+
+	return e.GetSourceId() + "/" + e.GetInstanceId() + "/" + l.Log.GetType().String() + "/" + string(l.Log.GetPayload())
+}
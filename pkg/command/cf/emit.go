@@ -0,0 +1,176 @@
+package cf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"github.com/golang/protobuf/jsonpb"
+	flags "github.com/jessevdk/go-flags"
+)
+
+type EmitOption func(*emitOptionFlags)
+
+// WithEmitSleepDuration overrides the --interval sleep, used in tests.
+func WithEmitSleepDuration(d time.Duration) EmitOption {
+	return func(o *emitOptionFlags) {
+		o.sleepDuration = &d
+	}
+}
+
+// Emit sends synthetic log, counter, or gauge envelopes to a log-cache
+// ingress over HTTP, so developers working on templates, drains, and alert
+// rules have deterministic test data without standing up an app.
+func Emit(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	eopts ...EmitOption,
+) {
+	opts := getEmitOptions(args, log, eopts...)
+	lw := lineWriter{w: w}
+
+	marshaler := jsonpb.Marshaler{}
+	sleep := opts.interval
+	if opts.sleepDuration != nil {
+		sleep = *opts.sleepDuration
+	}
+
+	for i := 0; i < opts.Count; i++ {
+		batch := &loggregator_v2.EnvelopeBatch{
+			Batch: []*loggregator_v2.Envelope{
+				buildEnvelope(opts, i),
+			},
+		}
+
+		var body bytes.Buffer
+		if err := marshaler.Marshal(&body, batch); err != nil {
+			log.Fatalf("Failed to marshal envelope: %s", err)
+		}
+
+		req, err := http.NewRequest("POST", opts.IngressAddr+"/v2/envelopes", &body)
+		if err != nil {
+			fatalf(log, ExitCodeUsage, "Invalid ingress address: %s", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.Do(req)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to emit envelope: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			fatalf(log, ExitCodeNetwork, "Failed to emit envelope: expected 200 response code, got %d.", resp.StatusCode)
+		}
+
+		if i != opts.Count-1 {
+			time.Sleep(sleep)
+		}
+	}
+
+	lw.Write(fmt.Sprintf("Emitted %d %s envelopes for %s.", opts.Count, opts.Type, opts.source))
+}
+
+// buildEnvelope constructs the i'th synthetic envelope for the run.
+func buildEnvelope(opts emitOptionFlags, i int) *loggregator_v2.Envelope {
+	e := &loggregator_v2.Envelope{
+		Timestamp: time.Now().UnixNano(),
+		SourceId:  opts.source,
+		Tags:      map[string]string{"origin": "log-cache-emit"},
+	}
+
+	switch opts.Type {
+	case "counter":
+		e.Message = &loggregator_v2.Envelope_Counter{
+			Counter: &loggregator_v2.Counter{
+				Name:  opts.Name,
+				Delta: uint64(opts.Value),
+			},
+		}
+	case "gauge":
+		e.Message = &loggregator_v2.Envelope_Gauge{
+			Gauge: &loggregator_v2.Gauge{
+				Metrics: map[string]*loggregator_v2.GaugeValue{
+					opts.Name: {
+						Unit:  opts.Unit,
+						Value: opts.Value,
+					},
+				},
+			},
+		}
+	default:
+		e.Message = &loggregator_v2.Envelope_Log{
+			Log: &loggregator_v2.Log{
+				Payload: []byte(fmt.Sprintf("%s %d", opts.Message, i)),
+				Type:    loggregator_v2.Log_OUT,
+			},
+		}
+	}
+
+	return e
+}
+
+type emitOptionFlags struct {
+	IngressAddr string  `long:"ingress-addr"`
+	Type        string  `long:"type" default:"log"`
+	Count       int     `long:"count" default:"10"`
+	Interval    string  `long:"interval" default:"0s"`
+	Message     string  `long:"message" default:"log-cache-emit test log"`
+	Name        string  `long:"name" default:"log_cache_emit"`
+	Value       float64 `long:"value" default:"1"`
+	Unit        string  `long:"unit"`
+
+	source        string
+	interval      time.Duration
+	sleepDuration *time.Duration
+}
+
+func getEmitOptions(args []string, log Logger, eopts ...EmitOption) emitOptionFlags {
+	var opts emitOptionFlags
+
+	for _, o := range eopts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Expected 1 argument, got %d.", len(args))
+	}
+	opts.source = args[0]
+
+	if opts.IngressAddr == "" {
+		log.Fatalf("--ingress-addr is required.")
+	}
+
+	switch opts.Type {
+	case "log", "counter", "gauge":
+	default:
+		log.Fatalf("--type must be 'log', 'counter', or 'gauge'.")
+	}
+
+	if opts.Count < 1 {
+		log.Fatalf("--count must be at least 1.")
+	}
+
+	opts.interval, err = time.ParseDuration(opts.Interval)
+	if err != nil {
+		log.Fatalf("--interval must be a duration, e.g. '1s': %s", err)
+	}
+	if opts.interval < 0 {
+		log.Fatalf("--interval must not be negative.")
+	}
+
+	return opts
+}
@@ -0,0 +1,101 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Emit", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		tableWriter = bytes.NewBuffer(nil)
+	})
+
+	It("posts log envelopes to the ingress", func() {
+		cf.Emit(
+			context.Background(),
+			cliConn,
+			[]string{"--ingress-addr", "https://ingress.some-system.com", "--count", "2", "source-1"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithEmitSleepDuration(0),
+		)
+
+		Expect(httpClient.requestCount()).To(Equal(2))
+		Expect(httpClient.requestURLs[0]).To(Equal("https://ingress.some-system.com/v2/envelopes"))
+		Expect(httpClient.requestBodies[0]).To(ContainSubstring(`"source_id":"source-1"`))
+		Expect(httpClient.requestBodies[0]).To(ContainSubstring(`"log":`))
+		Expect(httpClient.requestBodies[1]).ToNot(Equal(httpClient.requestBodies[0]))
+
+		Expect(tableWriter.String()).To(Equal("Emitted 2 log envelopes for source-1.\n"))
+	})
+
+	It("posts counter envelopes with the given name and delta", func() {
+		cf.Emit(
+			context.Background(),
+			cliConn,
+			[]string{
+				"--ingress-addr", "https://ingress.some-system.com",
+				"--type", "counter",
+				"--name", "requests",
+				"--value", "5",
+				"--count", "1",
+				"source-1",
+			},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(httpClient.requestCount()).To(Equal(1))
+		Expect(httpClient.requestBodies[0]).To(ContainSubstring(`"name":"requests"`))
+		Expect(httpClient.requestBodies[0]).To(ContainSubstring(`"delta":"5"`))
+
+		Expect(tableWriter.String()).To(Equal("Emitted 1 counter envelopes for source-1.\n"))
+	})
+
+	It("fatally logs without an ingress address", func() {
+		Expect(func() {
+			cf.Emit(
+				context.Background(),
+				cliConn,
+				[]string{"source-1"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--ingress-addr is required."))
+	})
+
+	It("fatally logs for an unknown envelope type", func() {
+		Expect(func() {
+			cf.Emit(
+				context.Background(),
+				cliConn,
+				[]string{"--ingress-addr", "https://ingress.some-system.com", "--type", "timer", "source-1"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--type must be 'log', 'counter', or 'gauge'."))
+	})
+})
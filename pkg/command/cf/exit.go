@@ -0,0 +1,34 @@
+package cf
+
+// Exit codes let scripts distinguish failure classes without scraping
+// stderr. A command that fails for a classified reason exits with the
+// matching code; anything unclassified keeps the conventional exit 1.
+const (
+	// ExitCodeUsage is returned for bad flags or arguments.
+	ExitCodeUsage = 2
+	// ExitCodeAuth is returned when credentials cannot be obtained.
+	ExitCodeAuth = 3
+	// ExitCodeNotFound is returned when the requested source or space
+	// does not exist.
+	ExitCodeNotFound = 4
+	// ExitCodeNetwork is returned when Log Cache or CAPI cannot be
+	// reached or read.
+	ExitCodeNetwork = 5
+)
+
+// ExitCodeLogger is implemented by loggers that can terminate with a
+// specific exit code. The plugin binary installs one; loggers without it
+// fall back to a plain Fatalf, which exits 1.
+type ExitCodeLogger interface {
+	FatalfWithCode(code int, format string, args ...interface{})
+}
+
+// fatalf logs fatally with the given exit code when the logger supports
+// codes, and degrades to Fatalf otherwise.
+func fatalf(log Logger, code int, format string, args ...interface{}) {
+	if el, ok := log.(ExitCodeLogger); ok {
+		el.FatalfWithCode(code, format, args...)
+		return
+	}
+	log.Fatalf(format, args...)
+}
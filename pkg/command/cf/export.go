@@ -0,0 +1,322 @@
+package cf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+	"github.com/golang/protobuf/jsonpb"
+	flags "github.com/jessevdk/go-flags"
+)
+
+const (
+	splitByNone = ""
+	splitByHour = "hour"
+	splitByDay  = "day"
+
+	exportTimeFormat = "20060102T150405Z"
+)
+
+type ExportOption func(*exportOptions)
+
+func WithExportNoHeaders() ExportOption {
+	return func(o *exportOptions) {
+		o.noHeaders = true
+	}
+}
+
+// Export writes the envelopes of a source over a given time range to newline
+// delimited JSON files, optionally partitioned along hour or day boundaries.
+func Export(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	opts ...ExportOption,
+) {
+	o, err := newExportOptions(cli, args, log)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "%s", err)
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	lw := lineWriter{w: w}
+
+	v := newVerboseLogger(log, o.verbosity)
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, o.insecureHTTP)
+
+	sourceID := o.guid
+	if sourceID == "" {
+		// fall back to provided name
+		sourceID = o.providedName
+	}
+
+	if !o.noHeaders {
+		username, err := cli.Username()
+		if err != nil {
+			log.Fatalf("Could not get username: %s", err)
+		}
+		lw.Write(fmt.Sprintf("Exporting logs for %s as %s...", o.providedName, username))
+		lw.Write("")
+	}
+
+	files := make(map[time.Time]*exportedFile)
+	defer func() {
+		for _, ef := range files {
+			ef.f.Close()
+		}
+	}()
+
+	marshaler := jsonpb.Marshaler{}
+	start := o.startTime
+	exportStart := time.Now()
+	var exported int
+
+	for {
+		envelopes, err := client.Read(
+			ctx,
+			sourceID,
+			time.Unix(0, start),
+			logcache.WithEndTime(time.Unix(0, o.endTime)),
+			logcache.WithLimit(MaximumBatchSize),
+		)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read %s: %s", sourceID, err)
+		}
+
+		if len(envelopes) == 0 {
+			break
+		}
+
+		for _, e := range envelopes {
+			ef, err := exportFile(files, o, sourceID, e)
+			if err != nil {
+				log.Fatalf("Failed to create export file: %s", err)
+			}
+
+			line, err := marshaler.MarshalToString(e)
+			if err != nil {
+				log.Fatalf("Failed to marshal envelope: %s", err)
+			}
+
+			if _, err := fmt.Fprintln(ef.f, line); err != nil {
+				log.Fatalf("Failed to write envelope: %s", err)
+			}
+			ef.envelopes++
+		}
+
+		start = envelopes[len(envelopes)-1].Timestamp + 1
+		exported += len(envelopes)
+
+		if len(envelopes) < MaximumBatchSize {
+			break
+		}
+	}
+
+	v.V(1, "Exported %d envelopes in %s", exported, time.Since(exportStart).Truncate(time.Millisecond))
+
+	if err := writeExportManifest(o, sourceID, exported, files); err != nil {
+		log.Fatalf("Failed to write export manifest: %s", err)
+	}
+
+	if !o.noHeaders {
+		lw.Write(fmt.Sprintf("Wrote %d files.", len(files)))
+	}
+}
+
+// exportedFile is an open export file along with the bookkeeping the manifest
+// needs.
+type exportedFile struct {
+	f         *os.File
+	name      string
+	envelopes int
+}
+
+type exportManifest struct {
+	SourceID   string               `json:"source_id"`
+	StartTime  time.Time            `json:"start_time"`
+	EndTime    time.Time            `json:"end_time"`
+	Envelopes  int                  `json:"envelopes"`
+	CLIVersion string               `json:"cli_version"`
+	Files      []exportManifestFile `json:"files"`
+}
+
+type exportManifestFile struct {
+	Name      string `json:"name"`
+	Envelopes int    `json:"envelopes"`
+	SHA256    string `json:"sha256"`
+}
+
+// writeExportManifest emits a manifest.json alongside the exported files so
+// archived incident data can be verified and traced later.
+func writeExportManifest(o exportOptions, sourceID string, exported int, files map[time.Time]*exportedFile) error {
+	manifest := exportManifest{
+		SourceID:   sourceID,
+		StartTime:  time.Unix(0, o.startTime).UTC(),
+		EndTime:    time.Unix(0, o.endTime).UTC(),
+		Envelopes:  exported,
+		CLIVersion: Version,
+	}
+
+	var buckets []time.Time
+	for bucket := range files {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Before(buckets[j]) })
+
+	for _, bucket := range buckets {
+		ef := files[bucket]
+		data, err := ioutil.ReadFile(filepath.Join(o.outputDir, ef.name))
+		if err != nil {
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, exportManifestFile{
+			Name:      ef.name,
+			Envelopes: ef.envelopes,
+			SHA256:    fmt.Sprintf("%x", sha256.Sum256(data)),
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(o.outputDir, "manifest.json"), data, 0644)
+}
+
+// exportFile returns the open file for the envelope's time bucket, creating
+// it on first use.
+func exportFile(files map[time.Time]*exportedFile, o exportOptions, sourceID string, e *loggregator_v2.Envelope) (*exportedFile, error) {
+	bucketStart, bucketEnd := exportBucket(o, e.Timestamp)
+
+	if ef, ok := files[bucketStart]; ok {
+		return ef, nil
+	}
+
+	name := fmt.Sprintf(
+		"%s-%s-%s.ndjson",
+		sourceID,
+		bucketStart.Format(exportTimeFormat),
+		bucketEnd.Format(exportTimeFormat),
+	)
+
+	f, err := os.Create(filepath.Join(o.outputDir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	ef := &exportedFile{f: f, name: name}
+	files[bucketStart] = ef
+	return ef, nil
+}
+
+// exportBucket returns the time range of the file an envelope with the given
+// timestamp belongs to.
+func exportBucket(o exportOptions, timestamp int64) (time.Time, time.Time) {
+	ts := time.Unix(0, timestamp).UTC()
+
+	switch o.splitBy {
+	case splitByHour:
+		start := ts.Truncate(time.Hour)
+		return start, start.Add(time.Hour)
+	case splitByDay:
+		start := time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.Add(24 * time.Hour)
+	default:
+		return time.Unix(0, o.startTime).UTC(), time.Unix(0, o.endTime).UTC()
+	}
+}
+
+type exportOptions struct {
+	startTime int64
+	endTime   int64
+	splitBy   string
+	outputDir string
+
+	guid         string
+	providedName string
+	verbosity    int
+	insecureHTTP bool
+
+	noHeaders bool
+}
+
+type exportOptionFlags struct {
+	StartTime    string `long:"start-time"`
+	EndTime      string `long:"end-time"`
+	SplitBy      string `long:"split-by"`
+	OutputDir    string `long:"output-dir" default:"."`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Quiet        bool   `long:"quiet" short:"q"`
+	Verbose      []bool `short:"v" long:"verbose"`
+}
+
+func newExportOptions(cli plugin.CliConnection, args []string, log Logger) (exportOptions, error) {
+	var opts exportOptionFlags
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		return exportOptions{}, err
+	}
+
+	startTime := int64(0)
+	if opts.StartTime != "" {
+		startTime, err = parseTimeFlag(opts.StartTime, time.Now())
+		if err != nil {
+			return exportOptions{}, fmt.Errorf("Couldn't parse --start-time: %s", err)
+		}
+	}
+
+	endTime := time.Now().UnixNano()
+	if opts.EndTime != "" {
+		endTime, err = parseTimeFlag(opts.EndTime, time.Now())
+		if err != nil {
+			return exportOptions{}, fmt.Errorf("Couldn't parse --end-time: %s", err)
+		}
+	}
+
+	if len(args) != 1 {
+		return exportOptions{}, fmt.Errorf("Expected 1 argument, got %d.", len(args))
+	}
+
+	opts.SplitBy = strings.ToLower(opts.SplitBy)
+	if opts.SplitBy != splitByNone && opts.SplitBy != splitByHour && opts.SplitBy != splitByDay {
+		return exportOptions{}, fmt.Errorf("--split-by must be 'hour' or 'day'")
+	}
+
+	if startTime > endTime {
+		return exportOptions{}, fmt.Errorf("Invalid date/time range. Ensure your start time is prior or equal the end time.")
+	}
+
+	id, _ := getGUID(args[0], cli, log)
+
+	return exportOptions{
+		startTime:    startTime,
+		endTime:      endTime,
+		splitBy:      opts.SplitBy,
+		outputDir:    opts.OutputDir,
+		guid:         id,
+		providedName: args[0],
+		verbosity:    len(opts.Verbose),
+		insecureHTTP: opts.InsecureHTTP,
+		noHeaders:    opts.Quiet,
+	}, nil
+}
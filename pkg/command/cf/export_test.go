@@ -0,0 +1,159 @@
+package cf_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Export", func() {
+	var (
+		logger     *stubLogger
+		writer     *stubWriter
+		httpClient *stubHTTPClient
+		cliConn    *stubCliConnection
+		outputDir  string
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		writer = &stubWriter{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+		cliConn.usernameResp = "a-user"
+
+		var err error
+		outputDir, err = ioutil.TempDir("", "export")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("splits output files along hour boundaries with --split-by hour", func() {
+		ts1 := time.Date(2018, 2, 21, 18, 30, 0, 0, time.UTC).UnixNano()
+		ts2 := time.Date(2018, 2, 21, 19, 10, 0, 0, time.UTC).UnixNano()
+
+		httpClient.responseBody = []string{fmt.Sprintf(
+			`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"app-guid","log":{"payload":"bG9n"}},
+				{"timestamp":"%d","source_id":"app-guid","log":{"payload":"bG9n"}}
+			]}}`,
+			ts1,
+			ts2,
+		)}
+
+		cf.Export(
+			context.Background(),
+			cliConn,
+			[]string{
+				"app-name",
+				"--start-time", "0",
+				"--end-time", fmt.Sprint(ts2 + 1),
+				"--split-by", "hour",
+				"--output-dir", outputDir,
+			},
+			httpClient,
+			logger,
+			writer,
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			"Exporting logs for app-name as a-user...",
+			"",
+			"Wrote 2 files.",
+		}))
+
+		first, err := ioutil.ReadFile(filepath.Join(
+			outputDir,
+			"app-guid-20180221T180000Z-20180221T190000Z.ndjson",
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(first)).To(ContainSubstring(fmt.Sprintf(`"timestamp":"%d"`, ts1)))
+
+		second, err := ioutil.ReadFile(filepath.Join(
+			outputDir,
+			"app-guid-20180221T190000Z-20180221T200000Z.ndjson",
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(second)).To(ContainSubstring(fmt.Sprintf(`"timestamp":"%d"`, ts2)))
+	})
+
+	It("writes a manifest.json describing the exported files", func() {
+		ts1 := time.Date(2018, 2, 21, 18, 30, 0, 0, time.UTC).UnixNano()
+		ts2 := time.Date(2018, 2, 21, 19, 10, 0, 0, time.UTC).UnixNano()
+
+		httpClient.responseBody = []string{fmt.Sprintf(
+			`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"app-guid","log":{"payload":"bG9n"}},
+				{"timestamp":"%d","source_id":"app-guid","log":{"payload":"bG9n"}}
+			]}}`,
+			ts1,
+			ts2,
+		)}
+
+		cf.Export(
+			context.Background(),
+			cliConn,
+			[]string{
+				"app-name",
+				"--start-time", "0",
+				"--end-time", fmt.Sprint(ts2 + 1),
+				"--split-by", "hour",
+				"--output-dir", outputDir,
+			},
+			httpClient,
+			logger,
+			writer,
+		)
+
+		data, err := ioutil.ReadFile(filepath.Join(outputDir, "manifest.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var manifest struct {
+			SourceID   string `json:"source_id"`
+			Envelopes  int    `json:"envelopes"`
+			CLIVersion string `json:"cli_version"`
+			Files      []struct {
+				Name      string `json:"name"`
+				Envelopes int    `json:"envelopes"`
+				SHA256    string `json:"sha256"`
+			} `json:"files"`
+		}
+		Expect(json.Unmarshal(data, &manifest)).To(Succeed())
+
+		Expect(manifest.SourceID).To(Equal("app-guid"))
+		Expect(manifest.Envelopes).To(Equal(2))
+		Expect(manifest.CLIVersion).To(Equal("dev"))
+		Expect(manifest.Files).To(HaveLen(2))
+
+		for _, f := range manifest.Files {
+			Expect(f.Envelopes).To(Equal(1))
+
+			content, err := ioutil.ReadFile(filepath.Join(outputDir, f.Name))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.SHA256).To(Equal(fmt.Sprintf("%x", sha256.Sum256(content))))
+		}
+	})
+
+	It("fatally logs when --split-by is not 'hour' or 'day'", func() {
+		Expect(func() {
+			cf.Export(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--split-by", "week"},
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--split-by must be 'hour' or 'day'"))
+	})
+})
@@ -0,0 +1,54 @@
+package cf
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+)
+
+// fallbackFailureThreshold is how many consecutive read failures are
+// tolerated before moving on to the next endpoint in the chain.
+const fallbackFailureThreshold = 3
+
+// fallbackReader reads through an ordered chain of Log Cache endpoints,
+// e.g. gateway, reverse proxy, direct node, switching to the next on
+// persistent failure so follow sessions survive a gateway rolling deploy.
+type fallbackReader struct {
+	addrs    []string
+	clients  []*logcache.Client
+	current  int
+	failures int
+	log      Logger
+}
+
+func newFallbackReader(addrs []string, c HTTPClient, log Logger) *fallbackReader {
+	clients := make([]*logcache.Client, 0, len(addrs))
+	for _, addr := range addrs {
+		clients = append(clients, logcache.NewClient(addr, logcache.WithHTTPClient(c)))
+	}
+
+	return &fallbackReader{addrs: addrs, clients: clients, log: log}
+}
+
+func (r *fallbackReader) Read(
+	ctx context.Context,
+	sourceID string,
+	start time.Time,
+	opts ...logcache.ReadOption,
+) ([]*loggregator_v2.Envelope, error) {
+	envelopes, err := r.clients[r.current].Read(ctx, sourceID, start, opts...)
+	if err != nil {
+		r.failures++
+		if r.failures >= fallbackFailureThreshold && len(r.clients) > 1 {
+			r.failures = 0
+			r.current = (r.current + 1) % len(r.clients)
+			r.log.Printf("Switching to Log Cache endpoint %s after repeated read failures.", r.addrs[r.current])
+		}
+		return nil, err
+	}
+
+	r.failures = 0
+	return envelopes, nil
+}
@@ -2,6 +2,7 @@ package cf
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"sort"
@@ -16,6 +17,7 @@ import (
 const (
 	prettyFormat formatterKind = iota
 	jsonFormat
+	jsonLinesFormat
 	templateFormat
 )
 
@@ -35,7 +37,7 @@ type formatter interface {
 	flush() (string, bool)
 }
 
-func newFormatter(sourceID string, following bool, kind formatterKind, log Logger, t *template.Template, newLineReplacer rune) formatter {
+func newFormatter(sourceID string, following bool, kind formatterKind, log Logger, t *template.Template, newLineReplacer rune, prettyJSON bool, fields []string, showTags bool, promqlNames bool) formatter {
 	bf := baseFormatter{
 		log: log,
 	}
@@ -46,12 +48,22 @@ func newFormatter(sourceID string, following bool, kind formatterKind, log Logge
 			baseFormatter: bf,
 			sourceID:      sourceID,
 			newLine:       newLineReplacer,
+			prettyJSON:    prettyJSON,
+			fields:        fields,
+			showTags:      showTags,
+			promqlNames:   promqlNames,
 		}
 	case jsonFormat:
 		return &jsonFormatter{
 			following:     following,
 			baseFormatter: bf,
 		}
+	case jsonLinesFormat:
+		return &jsonFormatter{
+			following:     following,
+			lines:         true,
+			baseFormatter: bf,
+		}
 	case templateFormat:
 		return templateFormatter{
 			baseFormatter:  bf,
@@ -89,8 +101,12 @@ func (f baseFormatter) formatEnvelope(e *loggregator_v2.Envelope) (string, bool)
 
 type prettyFormatter struct {
 	baseFormatter
-	sourceID string
-	newLine  rune
+	sourceID    string
+	newLine     rune
+	prettyJSON  bool
+	fields      []string
+	showTags    bool
+	promqlNames bool
 }
 
 func (f prettyFormatter) appHeader(app, org, space, user string) (string, bool) {
@@ -122,19 +138,22 @@ func (f prettyFormatter) sourceHeader(sourceID, _, _, user string) (string, bool
 }
 
 func (f prettyFormatter) formatEnvelope(e *loggregator_v2.Envelope) (string, bool) {
-	return fmt.Sprintf("%s", envelopeWrapper{sourceID: f.sourceID, Envelope: e, newLine: f.newLine}), true
+	return fmt.Sprintf("%s", envelopeWrapper{sourceID: f.sourceID, Envelope: e, newLine: f.newLine, prettyJSON: f.prettyJSON, fields: f.fields, showTags: f.showTags, promqlNames: f.promqlNames}), true
 }
 
 type jsonFormatter struct {
 	baseFormatter
 
 	following bool
+	// lines emits one compact document per envelope even when not
+	// following, so output can be piped into jq -c or a log shipper
+	lines     bool
 	es        []*loggregator_v2.Envelope
 	marshaler jsonpb.Marshaler
 }
 
 func (f *jsonFormatter) formatEnvelope(e *loggregator_v2.Envelope) (string, bool) {
-	if f.following {
+	if f.following || f.lines {
 		output, err := f.marshaler.MarshalToString(e)
 		if err != nil {
 			log.Printf("failed to marshal envelope: %s", err)
@@ -150,7 +169,7 @@ func (f *jsonFormatter) formatEnvelope(e *loggregator_v2.Envelope) (string, bool
 }
 
 func (f *jsonFormatter) flush() (string, bool) {
-	if f.following {
+	if f.following || f.lines {
 		return "", false
 	}
 
@@ -214,11 +233,50 @@ func (f templateFormatter) formatEnvelope(e *loggregator_v2.Envelope) (string, b
 
 type envelopeWrapper struct {
 	*loggregator_v2.Envelope
-	sourceID string
-	newLine  rune
+	sourceID    string
+	newLine     rune
+	prettyJSON  bool
+	fields      []string
+	showTags    bool
+	promqlNames bool
 }
 
 func (e envelopeWrapper) String() string {
+	s := e.render()
+	if e.showTags {
+		if suffix := e.tagSuffix(); suffix != "" {
+			s += suffix
+		}
+	}
+
+	return s
+}
+
+// tagSuffix renders the envelope's tags as sorted key=value pairs, so context
+// like origin and process_id survives into the pretty output.
+func (e envelopeWrapper) tagSuffix() string {
+	tags := make(map[string]string)
+	for k, t := range e.GetDeprecatedTags() {
+		tags[k] = t.GetText()
+	}
+	for k, v := range e.GetTags() {
+		tags[k] = v
+	}
+
+	if len(tags) == 0 {
+		return ""
+	}
+
+	var pairs []string
+	for k, v := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+
+	return fmt.Sprintf(" [%s]", strings.Join(pairs, " "))
+}
+
+func (e envelopeWrapper) render() string {
 	ts := time.Unix(0, e.Timestamp)
 
 	switch e.Message.(type) {
@@ -233,6 +291,13 @@ func (e envelopeWrapper) String() string {
 		if e.newLine != 0 {
 			payload = strings.Map(sanitizer, payload)
 		}
+		if len(e.fields) > 0 {
+			if extracted, ok := extractJSONFields(payload, e.fields); ok {
+				payload = extracted
+			}
+		} else if e.prettyJSON {
+			payload = prettyJSONPayload(payload)
+		}
 
 		return fmt.Sprintf("%s%s %s",
 			e.header(ts),
@@ -242,13 +307,13 @@ func (e envelopeWrapper) String() string {
 	case *loggregator_v2.Envelope_Counter:
 		return fmt.Sprintf("%sCOUNTER %s:%d",
 			e.header(ts),
-			e.GetCounter().GetName(),
+			e.metricName(e.GetCounter().GetName()),
 			e.GetCounter().GetTotal(),
 		)
 	case *loggregator_v2.Envelope_Gauge:
 		var values []string
 		for k, v := range e.GetGauge().GetMetrics() {
-			values = append(values, fmt.Sprintf("%s:%f %s", k, v.Value, v.Unit))
+			values = append(values, fmt.Sprintf("%s:%s", e.metricName(k), formatGaugeValue(v.Value, v.Unit)))
 		}
 
 		sort.Sort(sort.StringSlice(values))
@@ -259,10 +324,12 @@ func (e envelopeWrapper) String() string {
 		)
 	case *loggregator_v2.Envelope_Timer:
 		timer := e.GetTimer()
-		return fmt.Sprintf("%sTIMER %s %f ms",
+		// render the elapsed time as a duration, so gorouter's http timers
+		// read as '1.5ms' or '2s' rather than a raw float
+		return fmt.Sprintf("%sTIMER %s %s",
 			e.header(ts),
-			timer.GetName(),
-			float64(timer.GetStop()-timer.GetStart())/1000000.0,
+			e.metricName(timer.GetName()),
+			time.Duration(timer.GetStop()-timer.GetStart()).Truncate(10*time.Microsecond),
 		)
 	case *loggregator_v2.Envelope_Event:
 		return fmt.Sprintf("%sEVENT %s:%s",
@@ -275,6 +342,118 @@ func (e envelopeWrapper) String() string {
 	}
 }
 
+// prettyJSONPayload re-indents a log payload when it is a JSON object, so
+// single-line structured-logging blobs become readable. Anything else is
+// returned untouched.
+func prettyJSONPayload(payload string) string {
+	trimmed := strings.TrimSpace(payload)
+	if !strings.HasPrefix(trimmed, "{") {
+		return payload
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(trimmed), "", "  "); err != nil {
+		return payload
+	}
+
+	return out.String()
+}
+
+// metricName optionally renders a metric name through the same sanitization
+// Log Cache's PromQL layer applies, so the names shown by tail match what
+// queries must use.
+func (e envelopeWrapper) metricName(name string) string {
+	if !e.promqlNames {
+		return name
+	}
+
+	return sanitizePromQLName(name)
+}
+
+// sanitizePromQLName mirrors Log Cache's gateway: every character outside
+// [a-zA-Z0-9_:] becomes '_', and a leading digit is prefixed with '_'.
+func sanitizePromQLName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+
+	if sanitized != "" && sanitized[0] >= '0' && sanitized[0] <= '9' {
+		sanitized = "_" + sanitized
+	}
+
+	return sanitized
+}
+
+// extractJSONFields pulls the named fields out of a JSON object payload and
+// joins their values into columns, so `--field level --field msg` shows just
+// the interesting parts of a structured log line. Nested fields use dot
+// paths, e.g. 'request.path'. Missing fields render as '-'. The second
+// return value is false when the payload is not a JSON object.
+func extractJSONFields(payload string, fields []string) (string, bool) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &obj); err != nil {
+		return "", false
+	}
+
+	var values []string
+	for _, field := range fields {
+		var cur interface{} = obj
+		for _, part := range strings.Split(field, ".") {
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				cur = nil
+				break
+			}
+			cur = m[part]
+		}
+
+		switch v := cur.(type) {
+		case nil:
+			values = append(values, "-")
+		case string:
+			values = append(values, v)
+		default:
+			values = append(values, fmt.Sprintf("%v", v))
+		}
+	}
+
+	return strings.Join(values, " "), true
+}
+
+// formatGaugeValue renders a gauge value using its unit, scaling raw byte and
+// nanosecond readings into something readable in a terminal. Other units are
+// printed as-is.
+func formatGaugeValue(value float64, unit string) string {
+	switch unit {
+	case "bytes", "B":
+		return formatBytes(value)
+	case "nanoseconds", "ns":
+		if value >= float64(time.Millisecond) {
+			return fmt.Sprintf("%.2f ms", value/float64(time.Millisecond))
+		}
+	}
+
+	return fmt.Sprintf("%f %s", value, unit)
+}
+
+func formatBytes(value float64) string {
+	switch {
+	case value >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", value/(1<<30))
+	case value >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", value/(1<<20))
+	case value >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", value/(1<<10))
+	default:
+		return fmt.Sprintf("%f bytes", value)
+	}
+}
+
 func (e envelopeWrapper) header(ts time.Time) string {
 	if e.InstanceId == "" {
 		return fmt.Sprintf("   %s [%s] ",
@@ -0,0 +1,82 @@
+package cf
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type grafanaField struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type grafanaFrame struct {
+	Schema struct {
+		Name   string         `json:"name"`
+		Fields []grafanaField `json:"fields"`
+	} `json:"schema"`
+	Data struct {
+		Values [][]interface{} `json:"values"`
+	} `json:"data"`
+}
+
+// grafanaFrames renders a matrix result as Grafana's JSON dataframe
+// structure, one frame per series, for pasting into or serving to panels.
+func grafanaFrames(result json.RawMessage) (string, error) {
+	var series []struct {
+		Metric map[string]string   `json:"metric"`
+		Values [][]json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(result, &series); err != nil {
+		return "", err
+	}
+
+	frames := make([]grafanaFrame, 0, len(series))
+	for _, s := range series {
+		labels := make(map[string]string)
+		for k, v := range s.Metric {
+			if k != "__name__" {
+				labels[k] = v
+			}
+		}
+
+		var frame grafanaFrame
+		frame.Schema.Name = s.Metric["__name__"]
+		frame.Schema.Fields = []grafanaField{
+			{Name: "time", Type: "time"},
+			{Name: "value", Type: "number", Labels: labels},
+		}
+
+		times := []interface{}{}
+		values := []interface{}{}
+		for _, v := range s.Values {
+			if len(v) != 2 {
+				continue
+			}
+
+			ts, err := strconv.ParseFloat(string(v[0]), 64)
+			if err != nil {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.Trim(string(v[1]), `"`), 64)
+			if err != nil {
+				continue
+			}
+
+			// Grafana expects milliseconds
+			times = append(times, int64(ts*1000))
+			values = append(values, value)
+		}
+		frame.Data.Values = [][]interface{}{times, values}
+
+		frames = append(frames, frame)
+	}
+
+	body, err := json.Marshal(frames)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
@@ -0,0 +1,64 @@
+package cf
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gzipHTTPClient asks log-cache for compressed responses and transparently
+// decompresses them, so exporting large windows over WAN links is not
+// bandwidth-bound.
+type gzipHTTPClient struct {
+	c HTTPClient
+}
+
+// NewGzipClient wraps the client with gzip response handling.
+func NewGzipClient(c HTTPClient) HTTPClient {
+	return &gzipHTTPClient{c: c}
+}
+
+func (g *gzipHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	resp, err := g.c.Do(req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp, nil
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	resp.Body = &gzipReadCloser{r: gz, underlying: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+// gzipReadCloser closes the gzip reader and the underlying body together.
+type gzipReadCloser struct {
+	r          *gzip.Reader
+	underlying io.ReadCloser
+}
+
+func (g *gzipReadCloser) Read(p []byte) (int, error) {
+	return g.r.Read(p)
+}
+
+func (g *gzipReadCloser) Close() error {
+	if err := g.r.Close(); err != nil {
+		g.underlying.Close()
+		return err
+	}
+	return g.underlying.Close()
+}
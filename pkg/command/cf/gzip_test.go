@@ -0,0 +1,65 @@
+package cf_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewGzipClient", func() {
+	It("asks for gzip and transparently decompresses the response", func() {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, err := gw.Write([]byte(`{"envelopes":{"batch":[]}}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gw.Close()).To(Succeed())
+
+		stub := &sequencedHTTPClient{responses: []*http.Response{
+			{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Encoding": []string{"gzip"}},
+				Body:       ioutil.NopCloser(&buf),
+			},
+		}}
+
+		req, err := http.NewRequest("GET", "https://log-cache.some-system.com/api/v1/read/app-guid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := cf.NewGzipClient(stub).Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(req.Header.Get("Accept-Encoding")).To(Equal("gzip"))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(`{"envelopes":{"batch":[]}}`))
+		Expect(resp.Header.Get("Content-Encoding")).To(BeEmpty())
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("passes uncompressed responses through untouched", func() {
+		stub := &sequencedHTTPClient{responses: []*http.Response{
+			{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"envelopes":{"batch":[]}}`)),
+			},
+		}}
+
+		req, err := http.NewRequest("GET", "https://log-cache.some-system.com/api/v1/read/app-guid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := cf.NewGzipClient(stub).Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(`{"envelopes":{"batch":[]}}`))
+	})
+})
@@ -0,0 +1,36 @@
+package cf_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewHeaderClient", func() {
+	It("sets the given headers on every request", func() {
+		stub := &sequencedHTTPClient{responses: []*http.Response{
+			{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			},
+		}}
+
+		req, err := http.NewRequest("GET", "https://log-cache.some-system.com/api/v1/meta", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		c := cf.NewHeaderClient(stub, [][2]string{
+			{"X-Routing-Key", "log-cache"},
+			{"X-Feature-Flag", "on"},
+		})
+		_, err = c.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(req.Header.Get("X-Routing-Key")).To(Equal("log-cache"))
+		Expect(req.Header.Get("X-Feature-Flag")).To(Equal("on"))
+	})
+})
@@ -0,0 +1,24 @@
+package cf
+
+import (
+	"net/http"
+	"time"
+)
+
+// newPooledTransport returns the tuned transport shared across all log-cache
+// requests. Noise mode issues hundreds of requests back to back; without
+// keep-alives each one pays for a fresh TLS handshake.
+func newPooledTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = 100
+	t.MaxIdleConnsPerHost = 100
+	t.IdleConnTimeout = 90 * time.Second
+	t.ForceAttemptHTTP2 = true
+	return t
+}
+
+// NewPooledClient returns the HTTP client shared by meta, tail, and noise
+// paths: keep-alives on, a sane idle pool per host, and HTTP/2 enabled.
+func NewPooledClient() *http.Client {
+	return &http.Client{Transport: newPooledTransport()}
+}
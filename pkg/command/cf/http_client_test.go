@@ -0,0 +1,22 @@
+package cf_test
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewPooledClient", func() {
+	It("enables keep-alives with a per-host idle pool and HTTP/2", func() {
+		transport, ok := cf.NewPooledClient().Transport.(*http.Transport)
+		Expect(ok).To(BeTrue())
+
+		Expect(transport.DisableKeepAlives).To(BeFalse())
+		Expect(transport.MaxIdleConns).To(Equal(100))
+		Expect(transport.MaxIdleConnsPerHost).To(Equal(100))
+		Expect(transport.ForceAttemptHTTP2).To(BeTrue())
+	})
+})
@@ -0,0 +1,18 @@
+package cf
+
+import "strings"
+
+// checkPlainHTTP enforces the HTTPS-only default for the Log Cache address.
+// Plain HTTP gateways, e.g. Log Cache running locally without TLS, require
+// the explicit --insecure-http opt-in and are loudly called out when used.
+func checkPlainHTTP(addr string, allowInsecure bool, log Logger) {
+	if !strings.HasPrefix(addr, "http://") {
+		return
+	}
+
+	if !allowInsecure {
+		log.Fatalf("%s is a plain HTTP address. Pass --insecure-http to send unencrypted traffic.", addr)
+	}
+
+	log.Printf("WARNING: Sending unencrypted traffic to %s", addr)
+}
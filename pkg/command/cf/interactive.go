@@ -0,0 +1,159 @@
+package cf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// interactiveScrollback bounds how many lines the interactive session keeps
+// for replay.
+const interactiveScrollback = 1000
+
+// interactivePauseBuffer bounds how many lines are held while the stream is
+// paused. Beyond the cap the oldest buffered lines are dropped, so resuming
+// shows the most recent output.
+const interactivePauseBuffer = 1000
+
+// interactiveTail adds keyboard control on top of a streamed tail:
+//
+//	space    pause/resume the stream
+//	b        replay the scrollback buffer
+//	/text\n  only show lines containing text ('/' alone clears the filter)
+//	q        quit
+//
+// Lines that arrive while paused are buffered (up to interactivePauseBuffer)
+// and flushed on resume with a buffered-line count, and the scrollback keeps
+// lines that have already streamed past.
+type interactiveTail struct {
+	mu sync.Mutex
+	lw lineWriter
+
+	scrollback []string
+	pending    []string
+	paused     bool
+	filter     string
+	quit       bool
+}
+
+func newInteractiveTail(w io.Writer) *interactiveTail {
+	return &interactiveTail{lw: lineWriter{w: w}}
+}
+
+// write feeds a streamed line to the session.
+func (t *interactiveTail) write(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.scrollback = append(t.scrollback, line)
+	if len(t.scrollback) > interactiveScrollback {
+		t.scrollback = t.scrollback[1:]
+	}
+
+	if t.filter != "" && !strings.Contains(line, t.filter) {
+		return
+	}
+
+	if t.paused {
+		t.pending = append(t.pending, line)
+		if len(t.pending) > interactivePauseBuffer {
+			t.pending = t.pending[1:]
+		}
+		return
+	}
+
+	t.lw.Write(line)
+}
+
+// done reports whether the session has been quit.
+func (t *interactiveTail) done() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.quit
+}
+
+// handleInput consumes key presses until the reader is exhausted or the
+// session quits. It is meant to run on its own goroutine.
+func (t *interactiveTail) handleInput(r io.Reader) {
+	buf := make([]byte, 1)
+	var search *strings.Builder
+
+	for {
+		n, err := r.Read(buf)
+		if n == 0 {
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		c := buf[0]
+
+		if search != nil {
+			if c == '\n' || c == '\r' {
+				t.setFilter(search.String())
+				search = nil
+			} else {
+				search.WriteByte(c)
+			}
+			continue
+		}
+
+		switch c {
+		case ' ':
+			t.togglePause()
+		case 'b':
+			t.replayScrollback()
+		case '/':
+			search = &strings.Builder{}
+		case 'q':
+			t.mu.Lock()
+			t.quit = true
+			t.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (t *interactiveTail) togglePause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.paused {
+		t.paused = true
+		t.lw.Write("-- paused --")
+		return
+	}
+
+	t.paused = false
+	t.lw.Write(fmt.Sprintf("-- resumed, %d buffered --", len(t.pending)))
+	for _, line := range t.pending {
+		t.lw.Write(line)
+	}
+	t.pending = nil
+}
+
+func (t *interactiveTail) replayScrollback() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lw.Write("-- scrollback --")
+	for _, line := range t.scrollback {
+		t.lw.Write(line)
+	}
+	t.lw.Write("-- end of scrollback --")
+}
+
+func (t *interactiveTail) setFilter(filter string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.filter = filter
+	if filter == "" {
+		t.lw.Write("-- filter cleared --")
+		return
+	}
+	t.lw.Write(fmt.Sprintf("-- filter: %q --", filter))
+}
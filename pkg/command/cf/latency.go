@@ -0,0 +1,184 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+	flags "github.com/jessevdk/go-flags"
+)
+
+// latencyReadLimit caps how many envelopes each read of the range requests.
+const latencyReadLimit = 1000
+
+type LatencyOption func(*latencyOptionFlags)
+
+// Latency walks the timer envelopes of a source over a time range and prints
+// p50/p90/p99 durations per timer name, so latency percentiles don't have to
+// be computed from raw envelopes by hand.
+func Latency(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	lopts ...LatencyOption,
+) {
+	opts := getLatencyOptions(args, log, lopts...)
+	v := newVerboseLogger(log, len(opts.Verbose))
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+
+	sourceID, _ := getGUID(opts.source, cli, log)
+	if sourceID == "" {
+		// fall back to provided name
+		sourceID = opts.source
+	}
+
+	durations := make(map[string][]time.Duration)
+	readStart := time.Now()
+	var total, pages int
+	start := opts.start
+	for start.Before(opts.end) {
+		envelopes, err := client.Read(
+			ctx,
+			sourceID,
+			start,
+			logcache.WithEndTime(opts.end),
+			logcache.WithLimit(latencyReadLimit),
+		)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read envelopes: %s", err)
+		}
+
+		if len(envelopes) == 0 {
+			break
+		}
+		total += len(envelopes)
+		pages++
+
+		for _, e := range envelopes {
+			timer, ok := e.Message.(*loggregator_v2.Envelope_Timer)
+			if !ok {
+				continue
+			}
+
+			name := timer.Timer.GetName()
+			durations[name] = append(durations[name], time.Duration(timer.Timer.GetStop()-timer.Timer.GetStart()))
+		}
+
+		next := time.Unix(0, envelopes[len(envelopes)-1].GetTimestamp()+1)
+		if !next.After(start) {
+			break
+		}
+		start = next
+	}
+
+	v.V(1, "Read %d envelopes in %s (%d pages, %s to %s)",
+		total,
+		time.Since(readStart).Truncate(time.Millisecond),
+		pages,
+		opts.start.UTC().Format(time.RFC3339),
+		opts.end.UTC().Format(time.RFC3339),
+	)
+
+	var names []string
+	for name := range durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "Timer\tCount\tp50\tp90\tp99")
+	for _, name := range names {
+		ds := durations[name]
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n",
+			name,
+			len(ds),
+			percentile(ds, 0.50),
+			percentile(ds, 0.90),
+			percentile(ds, 0.99),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatalf("Error writing results")
+	}
+}
+
+// percentile returns the nearest-rank percentile of a sorted slice of
+// durations, truncated so the table stays readable.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	idx := int(q*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+
+	return sorted[idx].Truncate(10 * time.Microsecond)
+}
+
+type latencyOptionFlags struct {
+	StartTime    string `long:"start-time"`
+	EndTime      string `long:"end-time"`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Verbose      []bool `short:"v" long:"verbose"`
+
+	source string
+	start  time.Time
+	end    time.Time
+}
+
+func getLatencyOptions(args []string, log Logger, lopts ...LatencyOption) latencyOptionFlags {
+	var opts latencyOptionFlags
+
+	for _, o := range lopts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Expected 1 argument, got %d.", len(args))
+	}
+	opts.source = args[0]
+
+	startTime := int64(0)
+	if opts.StartTime != "" {
+		startTime, err = parseTimeFlag(opts.StartTime, time.Now())
+		if err != nil {
+			log.Fatalf("Couldn't parse --start-time: %s", err)
+		}
+	}
+
+	endTime := time.Now().UnixNano()
+	if opts.EndTime != "" {
+		endTime, err = parseTimeFlag(opts.EndTime, time.Now())
+		if err != nil {
+			log.Fatalf("Couldn't parse --end-time: %s", err)
+		}
+	}
+
+	opts.start = time.Unix(0, startTime)
+	opts.end = time.Unix(0, endTime)
+	if !opts.start.Before(opts.end) {
+		log.Fatalf("--start-time must be before --end-time.")
+	}
+
+	return opts
+}
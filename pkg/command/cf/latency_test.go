@@ -0,0 +1,89 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Latency", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+		cliConn.usernameResp = "a-user"
+		tableWriter = bytes.NewBuffer(nil)
+	})
+
+	It("prints latency percentiles per timer name", func() {
+		httpClient.responseBody = []string{
+			`{"envelopes":{"batch":[
+				{"timestamp":"10000000000","source_id":"app-guid","timer":{"name":"http","start":"0","stop":"1000000"}},
+				{"timestamp":"20000000000","source_id":"app-guid","timer":{"name":"http","start":"0","stop":"2000000"}},
+				{"timestamp":"30000000000","source_id":"app-guid","timer":{"name":"http","start":"0","stop":"3000000"}},
+				{"timestamp":"40000000000","source_id":"app-guid","timer":{"name":"db","start":"0","stop":"10000000"}},
+				{"timestamp":"50000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}}
+			]}}`,
+			`{"envelopes":{"batch":[]}}`,
+		}
+
+		cf.Latency(
+			context.Background(),
+			cliConn,
+			[]string{"--start-time", "0", "--end-time", "180000000000", "app-name"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Timer  Count  p50   p90   p99",
+			"db     1      10ms  10ms  10ms",
+			"http   3      2ms   3ms   3ms",
+			"",
+		}))
+	})
+
+	It("fatally logs when start-time is not before end-time", func() {
+		Expect(func() {
+			cf.Latency(
+				context.Background(),
+				cliConn,
+				[]string{"--start-time", "200", "--end-time", "100", "app-name"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--start-time must be before --end-time."))
+	})
+
+	It("fatally logs when given too many arguments", func() {
+		Expect(func() {
+			cf.Latency(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "other"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Expected 1 argument, got 2."))
+	})
+})
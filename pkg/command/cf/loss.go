@@ -0,0 +1,61 @@
+package cf
+
+import (
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// lossWarnInterval limits how often the lossy-stream warning repeats for the
+// same counter, so a steadily dropping platform doesn't flood stderr.
+const lossWarnInterval = time.Minute
+
+// lossMonitor watches the envelope stream for loss indicators — counters
+// whose name mentions 'dropped', as emitted by doppler and the reverse log
+// proxy — and warns when the stream is likely missing envelopes. Without the
+// warning users assume the absence of logs means the app didn't log.
+type lossMonitor struct {
+	log      Logger
+	now      func() time.Time
+	totals   map[string]uint64
+	warnedAt map[string]time.Time
+}
+
+func newLossMonitor(log Logger) *lossMonitor {
+	return &lossMonitor{
+		log:      log,
+		now:      time.Now,
+		totals:   make(map[string]uint64),
+		warnedAt: make(map[string]time.Time),
+	}
+}
+
+// observe inspects an envelope before any filtering, so loss indicators are
+// not hidden by --envelope-type or --filter.
+func (m *lossMonitor) observe(e *loggregator_v2.Envelope) {
+	c := e.GetCounter()
+	if c == nil || !strings.Contains(strings.ToLower(c.GetName()), "dropped") {
+		return
+	}
+
+	key := e.GetSourceId() + "/" + c.GetName()
+	prev, seen := m.totals[key]
+	m.totals[key] = c.GetTotal()
+
+	if !seen || c.GetTotal() <= prev {
+		return
+	}
+
+	if m.now().Sub(m.warnedAt[key]) < lossWarnInterval {
+		return
+	}
+	m.warnedAt[key] = m.now()
+
+	m.log.Printf(
+		"WARNING: %s reports %d dropped envelopes (%s); the stream is likely missing logs.",
+		e.GetSourceId(),
+		c.GetTotal()-prev,
+		c.GetName(),
+	)
+}
@@ -2,23 +2,33 @@ package cf
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
 	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	lc "code.cloudfoundry.org/log-cache-cli/v4/pkg/logcache"
 	logcache "code.cloudfoundry.org/log-cache/pkg/client"
 	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
 	flags "github.com/jessevdk/go-flags"
 )
 
+const (
+	outputFormatTable = "table"
+	outputFormatCSV   = "csv"
+)
+
 const (
 	sourceTypeApplication sourceType = "application"
 	sourceTypeService     sourceType = "service"
@@ -52,9 +62,9 @@ func (sb sortBy) Equal(value string) bool {
 }
 
 type source struct {
-	GUID string `json:"guid"`
-	Name string `json:"name"`
-	Type sourceType
+	GUID string     `json:"guid"`
+	Name string     `json:"name"`
+	Type sourceType `json:"type"`
 }
 
 type sourceInfo struct {
@@ -77,13 +87,47 @@ type servicesResponse struct {
 type Tailer func(sourceID string) []string
 
 type optionsFlags struct {
-	SourceType  string `long:"source-type"`
-	EnableNoise bool   `long:"noise"`
-	ShowGUID    bool   `long:"guid"`
-	SortBy      string `long:"sort-by"`
+	SourceType     string `long:"source-type"`
+	EnableNoise    bool   `long:"noise"`
+	ShowGUID       bool   `long:"guid"`
+	ShowTimestamps bool   `long:"show-timestamps"`
+	NoNameCache    bool   `long:"no-name-cache"`
+	FromSnapshot   string `long:"from-snapshot"`
+	InsecureHTTP   bool   `long:"insecure-http"`
+	Parallelism    int    `long:"parallelism" default:"10"`
+	SortBy         string `long:"sort-by"`
+	Output         string `long:"output"`
+	ByType         bool   `long:"by-type"`
+	ByOrg          bool   `long:"by-org"`
+	Threshold      int64  `long:"threshold"`
+	OnlyAbove      bool   `long:"only-above"`
+	ShowCrashes    bool   `long:"show-crashes"`
+
+	RequireRateAbove int64  `long:"require-rate-above"`
+	RequireRateBelow int64  `long:"require-rate-below"`
+	TargetRetention  string `long:"target-retention"`
+	VersionCheck     bool   `long:"version-check"`
+	StrictCompat     bool   `long:"strict-compat"`
+	Wide             bool   `long:"wide"`
+	Columns          string `long:"columns"`
+	Sample           string `long:"sample"`
+	SampleDuration   string `long:"duration"`
+	SampleFile       string `long:"sample-file"`
+	RawSourceNames   bool   `long:"raw-source-names"`
+	BoshMapping      string `long:"bosh-mapping"`
+	CapiBatchSize    int    `long:"capi-batch-size"`
+	Quiet            bool   `long:"quiet" short:"q"`
+	Verbose          []bool `short:"v" long:"verbose"`
 
 	withHeaders            bool
+	terminalWidth          int
+	columns                []string
+	sampleInterval         time.Duration
+	sampleDuration         time.Duration
 	metaNoiseSleepDuration time.Duration
+	metaTimeout            time.Duration
+	requestHeaders         [][2]string
+	targetRetention        time.Duration
 }
 
 var (
@@ -98,12 +142,36 @@ func WithMetaNoHeaders() MetaOption {
 	}
 }
 
+// WithMetaTerminalWidth sets the detected terminal width so the narrow
+// layout can truncate long source names instead of wrapping the table.
+func WithMetaTerminalWidth(width int) MetaOption {
+	return func(o *optionsFlags) {
+		o.terminalWidth = width
+	}
+}
+
 func WithMetaNoiseSleepDuration(d time.Duration) MetaOption {
 	return func(o *optionsFlags) {
 		o.metaNoiseSleepDuration = d
 	}
 }
 
+// WithMetaTimeout bounds the whole meta read with a deadline, letting
+// embedders of the command package fail fast without forking flag parsing.
+func WithMetaTimeout(d time.Duration) MetaOption {
+	return func(o *optionsFlags) {
+		o.metaTimeout = d
+	}
+}
+
+// WithMetaRequestHeader adds a header to every request the command sends,
+// e.g. for routing or tracing in front of the gateway.
+func WithMetaRequestHeader(name, value string) MetaOption {
+	return func(o *optionsFlags) {
+		o.requestHeaders = append(o.requestHeaders, [2]string{name, value})
+	}
+}
+
 // Meta returns the metadata from Log Cache
 func Meta(
 	ctx context.Context,
@@ -115,61 +183,354 @@ func Meta(
 	mopts ...MetaOption,
 ) {
 	opts := getOptions(args, log, mopts...)
-	client := createLogCacheClient(c, log, cli)
+	if opts.metaTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.metaTimeout)
+		defer cancel()
+	}
+	if len(opts.requestHeaders) > 0 {
+		c = &headerHTTPClient{c: c, headers: opts.requestHeaders}
+	}
+	v := newVerboseLogger(log, len(opts.Verbose))
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+	if opts.VersionCheck {
+		checkGatewayCompatibility(ctx, client, log, opts.StrictCompat)
+	}
 	tw := tabwriter.NewWriter(tableWriter, 0, 2, 2, ' ', 0)
 	username, err := cli.Username()
 	if err != nil {
 		log.Fatalf("Could not get username: %s", err)
 	}
 
+	// push --source-type filtering to the gateway when it supports it; the
+	// client-side filter below still runs either way
+	readMeta := func() (map[string]*logcache_v1.MetaInfo, error) {
+		if scope := serverSideScope(opts.SourceType); scope != "" {
+			meta, ok, err := scopedMeta(ctx, client, authenticatedHTTPClient(newVerboseHTTPClient(c, v), log, cli), cli, scope)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return meta, nil
+			}
+		}
+		return client.Meta(ctx)
+	}
+
+	if opts.sampleInterval > 0 {
+		sampleMeta(opts, readMeta, log, tableWriter)
+		return
+	}
+
 	var originalMeta map[string]*logcache_v1.MetaInfo
 	var currentMeta map[string]*logcache_v1.MetaInfo
 	writeRetrievingMetaHeader(opts, tw, username)
-	currentMeta, err = client.Meta(ctx)
-	if err != nil {
-		log.Fatalf("Failed to read Meta information: %s", err)
+	metaStart := time.Now()
+	if opts.FromSnapshot != "" {
+		currentMeta, err = readMetaSnapshot(opts.FromSnapshot)
+		if err != nil {
+			log.Fatalf("Failed to read meta snapshot: %s", err)
+		}
+	} else {
+		currentMeta, err = readMeta()
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+		}
 	}
+	v.V(1, "Retrieved meta for %d sources in %s", len(currentMeta), time.Since(metaStart).Truncate(time.Millisecond))
 
+	// noise mode used to serialize its slow phases: wait out the window,
+	// re-read meta, then resolve every source name. The parallelism-limited
+	// CAPI lookups now run concurrently with the window instead, so the only
+	// wall time noise mode adds over a plain meta is the window itself.
+	var pendingNames chan nameLookup
 	if opts.EnableNoise {
 		originalMeta = currentMeta
+		if !opts.ShowGUID {
+			pendingNames = make(chan nameLookup, 1)
+			go func(meta map[string]*logcache_v1.MetaInfo) {
+				r, err := getSourceInfoWithCache(meta, cli, opts.NoNameCache, newProgressReporter(log))
+				pendingNames <- nameLookup{resources: r, err: err}
+			}(originalMeta)
+		}
 		writeWaiting(opts, tw, username)
-		time.Sleep(opts.metaNoiseSleepDuration)
+		noiseWait(opts.metaNoiseSleepDuration, log)
 		writeRetrievingMetaHeader(opts, tw, username)
-		currentMeta, err = client.Meta(ctx)
+		currentMeta, err = readMeta()
 		if err != nil {
-			log.Fatalf("Failed to read Meta information: %s", err)
+			fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
 		}
 	}
 
 	resources := make(map[string]source)
 	if !opts.ShowGUID {
 		writeAppsAndServicesHeader(opts, tw, username)
-		resources, err = getSourceInfo(currentMeta, cli)
+		lookupStart := time.Now()
+		if pendingNames != nil {
+			lookup := <-pendingNames
+			if lookup.err != nil {
+				fatalf(log, ExitCodeNetwork, "Failed to read application information: %s", lookup.err)
+			}
+			resources = lookup.resources
+
+			// sources that appeared during the window still need names
+			appeared := make(map[string]*logcache_v1.MetaInfo)
+			for sourceID, m := range currentMeta {
+				if _, ok := originalMeta[sourceID]; !ok {
+					appeared[sourceID] = m
+				}
+			}
+			if len(appeared) > 0 {
+				extra, err := getSourceInfoWithCache(appeared, cli, opts.NoNameCache, newProgressReporter(log))
+				if err != nil {
+					fatalf(log, ExitCodeNetwork, "Failed to read application information: %s", err)
+				}
+				for sourceID, s := range extra {
+					resources[sourceID] = s
+				}
+			}
+		} else {
+			resources, err = getSourceInfoWithCache(currentMeta, cli, opts.NoNameCache, newProgressReporter(log))
+			if err != nil {
+				fatalf(log, ExitCodeNetwork, "Failed to read application information: %s", err)
+			}
+		}
+		v.V(1, "Resolved %d source names in %s", len(resources), time.Since(lookupStart).Truncate(time.Millisecond))
+	}
+
+	var typeCounts map[string]envelopeTypeCounts
+	if opts.ByType {
+		typeCounts = getEnvelopeTypeCounts(ctx, client, currentMeta, opts.Parallelism, newProgressReporter(log))
+	}
+
+	var crashCounts map[string]int64
+	if opts.ShowCrashes {
+		var guids []string
+		for sourceID := range currentMeta {
+			guids = append(guids, sourceID)
+		}
+		sort.Strings(guids)
+
+		crashCounts, err = getCrashCounts(guids, cli)
 		if err != nil {
-			log.Fatalf("Failed to read application information: %s", err)
+			fatalf(log, ExitCodeNetwork, "Failed to read crash events: %s", err)
 		}
 	}
 
-	writeHeaders(opts, tw, username)
+	if !opts.ByOrg {
+		if len(opts.columns) > 0 {
+			writeColumnHeaders(opts, tw)
+		} else {
+			writeHeaders(opts, tw, username)
+		}
+	}
 
-	rows := toDisplayRows(resources, currentMeta, originalMeta)
+	rows := toDisplayRows(resources, currentMeta, originalMeta, typeCounts, opts.RawSourceNames, opts.metaNoiseSleepDuration)
+	if opts.BoshMapping != "" {
+		mapping, err := loadBoshMapping(opts.BoshMapping)
+		if err != nil {
+			log.Fatalf("Could not read --bosh-mapping: %s", err)
+		}
+		applyBoshMapping(rows, mapping)
+	}
+	for i := range rows {
+		rows[i].Crashes = crashCounts[rows[i].SourceID]
+	}
+	if !opts.ShowGUID {
+		disambiguateRows(rows)
+	}
 	rows = filterRows(opts, rows)
+	rows = filterRowsByRate(opts, rows)
 	sortRows(opts, rows)
 
+	if opts.ByOrg {
+		var guids []string
+		for sourceID := range currentMeta {
+			guids = append(guids, sourceID)
+		}
+		sort.Strings(guids)
+
+		orgs, err := getSourceOrgs(guids, cli, newProgressReporter(log))
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read organization information: %s", err)
+		}
+
+		rollups := rollupByOrg(rows, orgs)
+		if opts.Output == outputFormatCSV {
+			if err := writeOrgCSVRows(opts, tableWriter, rollups); err != nil {
+				log.Fatalf("Error writing results")
+			}
+			return
+		}
+
+		writeOrgHeaders(opts, tw)
+		for _, r := range rollups {
+			format, items := orgTableFormat(opts, r)
+			fmt.Fprintf(tw, format, items...)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("Error writing results")
+		}
+		return
+	}
+
+	var exceeded int
 	for _, r := range rows {
-		format, items := tableFormat(opts, r)
-		fmt.Fprintf(tw, format, items...)
+		if exceedsThreshold(opts, r) {
+			exceeded++
+		}
 	}
 
-	if err = tw.Flush(); err != nil {
-		log.Fatalf("Error writing results")
+	if opts.OnlyAbove {
+		aboveRows := []displayRow{}
+		for _, r := range rows {
+			if exceedsThreshold(opts, r) {
+				aboveRows = append(aboveRows, r)
+			}
+		}
+		rows = aboveRows
+	}
+
+	if opts.Output == outputFormatCSV {
+		if err := writeCSVRows(opts, tableWriter, rows); err != nil {
+			log.Fatalf("Error writing results")
+		}
+	} else {
+		for _, r := range rows {
+			if len(opts.columns) > 0 {
+				fmt.Fprintf(tw, "%s\n", strings.Join(columnCells(opts, r), "\t"))
+				continue
+			}
+			format, items := tableFormat(opts, r)
+			fmt.Fprintf(tw, format, items...)
+		}
+
+		if err = tw.Flush(); err != nil {
+			log.Fatalf("Error writing results")
+		}
+	}
+
+	if exceeded > 0 {
+		log.Fatalf("%d sources exceeded the rate threshold of %d envelopes/minute", exceeded, opts.Threshold)
 	}
 }
 
-func toDisplayRows(resources map[string]source, currentMeta, originalMeta map[string]*logcache_v1.MetaInfo) []displayRow {
+func filterRowsByRate(opts optionsFlags, rows []displayRow) []displayRow {
+	if opts.RequireRateAbove < 0 && opts.RequireRateBelow < 0 {
+		return rows
+	}
+
+	filteredRows := []displayRow{}
+	for _, row := range rows {
+		if opts.RequireRateAbove >= 0 && row.Delta <= opts.RequireRateAbove {
+			continue
+		}
+		if opts.RequireRateBelow >= 0 && row.Delta >= opts.RequireRateBelow {
+			continue
+		}
+		filteredRows = append(filteredRows, row)
+	}
+	return filteredRows
+}
+
+func exceedsThreshold(opts optionsFlags, row displayRow) bool {
+	return opts.Threshold > 0 && row.Delta > opts.Threshold
+}
+
+func writeCSVRows(opts optionsFlags, w io.Writer, rows []displayRow) error {
+	cw := csv.NewWriter(w)
+
+	if len(opts.columns) > 0 {
+		var header []string
+		for _, col := range opts.columns {
+			header = append(header, metaColumnTitles[col])
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err := cw.Write(columnCells(opts, r)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	header := []string{"Source", "Source Type"}
+	if opts.ShowGUID {
+		header = []string{"Source ID"}
+	}
+	header = append(header, "Count", "Expired", "Cache Duration")
+	if opts.ShowTimestamps {
+		header = append(header, "Oldest", "Newest")
+	}
+	if opts.EnableNoise {
+		header = append(header, "Rate/minute")
+	}
+	if opts.ByType {
+		header = append(header, "Logs", "Counters", "Gauges", "Timers", "Events")
+	}
+	if opts.ShowCrashes {
+		header = append(header, "Crashes")
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range rows {
+		record := []string{r.Source, string(r.Type)}
+		if opts.ShowGUID {
+			record = []string{r.SourceID}
+		}
+		duration := r.CacheDuration.String()
+		if opts.targetRetention > 0 {
+			duration = retentionCell(opts, r.CacheDuration)
+		}
+		record = append(record,
+			strconv.FormatInt(r.Count, 10),
+			strconv.FormatInt(r.Expired, 10),
+			duration,
+		)
+		if opts.ShowTimestamps {
+			record = append(record,
+				r.Oldest.Format(time.RFC3339),
+				r.Newest.Format(time.RFC3339),
+			)
+		}
+		if opts.EnableNoise {
+			record = append(record, strconv.FormatInt(r.Delta, 10))
+		}
+		if opts.ByType {
+			record = append(record,
+				strconv.FormatInt(r.TypeCounts.Logs, 10),
+				strconv.FormatInt(r.TypeCounts.Counters, 10),
+				strconv.FormatInt(r.TypeCounts.Gauges, 10),
+				strconv.FormatInt(r.TypeCounts.Timers, 10),
+				strconv.FormatInt(r.TypeCounts.Events, 10),
+			)
+		}
+		if opts.ShowCrashes {
+			record = append(record, strconv.FormatInt(r.Crashes, 10))
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func toDisplayRows(resources map[string]source, currentMeta, originalMeta map[string]*logcache_v1.MetaInfo, typeCounts map[string]envelopeTypeCounts, rawNames bool, noiseWindow time.Duration) []displayRow {
 	var rows []displayRow
 	for sourceID, m := range currentMeta {
 		dR := displayRow{Source: sourceID, SourceID: sourceID, Count: m.Count, Expired: m.Expired, CacheDuration: cacheDuration(m)}
+		dR.TypeCounts = typeCounts[sourceID]
+		dR.Oldest = time.Unix(0, m.OldestTimestamp).UTC()
+		dR.Newest = time.Unix(0, m.NewestTimestamp).UTC()
 		source, isAppOrService := resources[sourceID]
 		if isAppOrService {
 			dR.Type = source.Type
@@ -178,10 +539,13 @@ func toDisplayRows(resources map[string]source, currentMeta, originalMeta map[st
 			dR.Type = sourceTypeUnknown
 		} else {
 			dR.Type = sourceTypePlatform
+			if !rawNames {
+				dR.Source = friendlySourceName(sourceID)
+			}
 		}
 		if originalMeta[sourceID] != nil {
 			diff := (m.Count + m.Expired) - (originalMeta[sourceID].Count + originalMeta[sourceID].Expired)
-			dR.Delta = diff / 5
+			dR.Delta = int64(float64(diff) / noiseRateWindow(noiseWindow).Minutes())
 		} else {
 			dR.Delta = -1
 		}
@@ -191,6 +555,31 @@ func toDisplayRows(resources map[string]source, currentMeta, originalMeta map[st
 	return rows
 }
 
+// disambiguateRows appends a short GUID suffix to sources that share a name
+// with another source, e.g. two apps named the same in different spaces.
+func disambiguateRows(rows []displayRow) {
+	names := make(map[string]int)
+	for _, r := range rows {
+		names[r.Source]++
+	}
+
+	for i, r := range rows {
+		if names[r.Source] < 2 || r.Source == r.SourceID {
+			continue
+		}
+		rows[i].Source = fmt.Sprintf("%s (%s)", r.Source, shortGUID(r.SourceID))
+	}
+}
+
+// shortGUID returns the first segment of a GUID, which is enough to tell two
+// sources apart in a table.
+func shortGUID(guid string) string {
+	if idx := strings.Index(guid, "-"); idx > 0 {
+		return guid[:idx]
+	}
+	return guid
+}
+
 func filterRows(opts optionsFlags, rows []displayRow) []displayRow {
 	if sourceTypeAll.Equal(opts.SourceType) {
 		return rows
@@ -225,53 +614,356 @@ type displayRow struct {
 	Expired       int64
 	CacheDuration time.Duration
 	Delta         int64
+	Crashes       int64
+	TypeCounts    envelopeTypeCounts
+	Oldest        time.Time
+	Newest        time.Time
+}
+
+type envelopeTypeCounts struct {
+	Logs     int64
+	Counters int64
+	Gauges   int64
+	Timers   int64
+	Events   int64
 }
 
-func createLogCacheClient(c HTTPClient, log Logger, cli plugin.CliConnection) *logcache.Client {
+// getEnvelopeTypeCounts samples the most recent envelopes for each source and
+// counts them by envelope type. Sources are read concurrently, bounded by
+// parallelism. Sources that fail to read report zero counts.
+func getEnvelopeTypeCounts(ctx context.Context, client *logcache.Client, metaInfo map[string]*logcache_v1.MetaInfo, parallelism int, progress progressFunc) map[string]envelopeTypeCounts {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		done int
+	)
+	counts := make(map[string]envelopeTypeCounts)
+	sem := make(chan struct{}, parallelism)
+
+	for sourceID := range metaInfo {
+		wg.Add(1)
+		go func(sourceID string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			defer func() {
+				mu.Lock()
+				done++
+				progress(done, len(metaInfo))
+				mu.Unlock()
+			}()
+
+			envelopes, err := client.Read(
+				ctx,
+				sourceID,
+				time.Unix(0, 0),
+				logcache.WithDescending(),
+				logcache.WithLimit(MaximumBatchSize),
+			)
+			if err != nil {
+				return
+			}
+
+			var c envelopeTypeCounts
+			for _, e := range envelopes {
+				switch e.Message.(type) {
+				case *loggregator_v2.Envelope_Log:
+					c.Logs++
+				case *loggregator_v2.Envelope_Counter:
+					c.Counters++
+				case *loggregator_v2.Envelope_Gauge:
+					c.Gauges++
+				case *loggregator_v2.Envelope_Timer:
+					c.Timers++
+				case *loggregator_v2.Envelope_Event:
+					c.Events++
+				}
+			}
+
+			mu.Lock()
+			counts[sourceID] = c
+			mu.Unlock()
+		}(sourceID)
+	}
+
+	wg.Wait()
+	return counts
+}
+
+func createLogCacheClient(c HTTPClient, log Logger, cli plugin.CliConnection, allowInsecure bool) *logcache.Client {
 	logCacheEndpoint, err := logCacheEndpoint(cli)
 	if err != nil {
 		log.Fatalf("Could not determine Log Cache endpoint: %s", err)
 	}
 
-	if strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) != "true" {
-		c = &tokenHTTPClient{
-			c: c,
-			tokenFunc: func() string {
-				token, err := cli.AccessToken()
-				if err != nil {
-					log.Fatalf("Unable to get Access Token: %s", err)
-				}
-				return token
-			},
-		}
-	}
+	checkPlainHTTP(logCacheEndpoint, allowInsecure, log)
 
 	return logcache.NewClient(
 		logCacheEndpoint,
-		logcache.WithHTTPClient(c),
+		logcache.WithHTTPClient(authenticatedHTTPClient(c, log, cli)),
 	)
 }
 
+// authenticatedHTTPClient wraps the client with CF token handling unless
+// LOG_CACHE_SKIP_AUTH is set.
+func authenticatedHTTPClient(c HTTPClient, log Logger, cli plugin.CliConnection) HTTPClient {
+	if strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) == "true" {
+		return c
+	}
+
+	return lc.NewTokenClient(c, func() string {
+		token, err := cli.AccessToken()
+		if err != nil {
+			fatalf(log, ExitCodeAuth, "Unable to get Access Token: %s", err)
+		}
+		return token
+	})
+}
+
+// retentionCell renders a cache duration against the target retention, e.g.
+// "9m0s (60%)". On a terminal the cell is colored by how close the source is
+// to the target: green at 90% or above, yellow at 50%, red below.
+func retentionCell(opts optionsFlags, d time.Duration) string {
+	pct := int(float64(d) / float64(opts.targetRetention) * 100)
+	cell := fmt.Sprintf("%s (%d%%)", d, pct)
+
+	if !opts.withHeaders {
+		return cell
+	}
+
+	switch {
+	case pct >= 90:
+		return "\x1b[32m" + cell + "\x1b[0m"
+	case pct >= 50:
+		return "\x1b[33m" + cell + "\x1b[0m"
+	default:
+		return "\x1b[31m" + cell + "\x1b[0m"
+	}
+}
+
 func tableFormat(opts optionsFlags, row displayRow) (string, []interface{}) {
 	tableFormat := "%d\t%d\t%s\n"
-	items := []interface{}{interface{}(row.Count), interface{}(row.Expired), interface{}(row.CacheDuration)}
+	duration := interface{}(row.CacheDuration)
+	if opts.targetRetention > 0 {
+		duration = interface{}(retentionCell(opts, row.CacheDuration))
+	}
+	items := []interface{}{interface{}(row.Count), interface{}(row.Expired), duration}
 
 	if opts.ShowGUID {
 		tableFormat = "%s\t" + tableFormat
 		items = append([]interface{}{interface{}(row.SourceID)}, items...)
 	} else {
 		tableFormat = "%s\t%s\t" + tableFormat
-		items = append([]interface{}{interface{}(row.Source), interface{}(row.Type)}, items...)
+		source := truncateSource(row.Source, sourceColumnWidth(opts))
+		items = append([]interface{}{interface{}(source), interface{}(row.Type)}, items...)
+	}
+
+	if opts.ShowTimestamps {
+		tableFormat = strings.Replace(tableFormat, "\n", "\t%s\t%s\n", 1)
+		items = append(items,
+			interface{}(row.Oldest.Format(time.RFC3339)),
+			interface{}(row.Newest.Format(time.RFC3339)),
+		)
 	}
 
 	if opts.EnableNoise {
+		if exceedsThreshold(opts, row) {
+			// mark sources above the threshold so they stand out in the table
+			tableFormat = strings.Replace(tableFormat, "\n", "\t%s\n", 1)
+			items = append(items, interface{}(fmt.Sprintf("%d *", row.Delta)))
+		} else {
+			tableFormat = strings.Replace(tableFormat, "\n", "\t%d\n", 1)
+			items = append(items, interface{}(row.Delta))
+		}
+	}
+
+	if opts.ByType {
+		tableFormat = strings.Replace(tableFormat, "\n", "\t%d\t%d\t%d\t%d\t%d\n", 1)
+		items = append(items,
+			interface{}(row.TypeCounts.Logs),
+			interface{}(row.TypeCounts.Counters),
+			interface{}(row.TypeCounts.Gauges),
+			interface{}(row.TypeCounts.Timers),
+			interface{}(row.TypeCounts.Events),
+		)
+	}
+
+	if opts.ShowCrashes {
 		tableFormat = strings.Replace(tableFormat, "\n", "\t%d\n", 1)
-		items = append(items, interface{}(row.Delta))
+		items = append(items, interface{}(row.Crashes))
 	}
 
 	return tableFormat, items
 }
 
+// sampleMeta appends one timestamped row per source per sample to a CSV or
+// NDJSON file, giving capacity planners cheap longitudinal data about cache
+// pressure without extra monitoring. The format follows the file extension:
+// '.ndjson' appends one JSON object per line, anything else appends CSV.
+func sampleMeta(opts optionsFlags, readMeta func() (map[string]*logcache_v1.MetaInfo, error), log Logger, w io.Writer) {
+	f, err := os.OpenFile(opts.SampleFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Fatalf("Could not open --sample-file: %s", err)
+	}
+	defer f.Close()
+
+	ndjson := strings.HasSuffix(opts.SampleFile, ".ndjson")
+	if !ndjson {
+		if info, err := f.Stat(); err == nil && info.Size() == 0 {
+			fmt.Fprintln(f, "timestamp,source_id,count,expired,cache_duration")
+		}
+	}
+
+	lw := lineWriter{w: w}
+	deadline := time.Now().Add(opts.sampleDuration)
+	for {
+		meta, err := readMeta()
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+		}
+
+		var ids []string
+		for sourceID := range meta {
+			ids = append(ids, sourceID)
+		}
+		sort.Strings(ids)
+
+		now := time.Now().UTC().Format(time.RFC3339)
+		for _, sourceID := range ids {
+			m := meta[sourceID]
+			if ndjson {
+				line, err := json.Marshal(map[string]interface{}{
+					"timestamp":      now,
+					"source_id":      sourceID,
+					"count":          m.Count,
+					"expired":        m.Expired,
+					"cache_duration": cacheDuration(m).String(),
+				})
+				if err != nil {
+					log.Fatalf("Failed to write sample: %s", err)
+				}
+				fmt.Fprintln(f, string(line))
+				continue
+			}
+			fmt.Fprintf(f, "%s,%s,%d,%d,%s\n", now, sourceID, m.Count, m.Expired, cacheDuration(m))
+		}
+
+		lw.Write(fmt.Sprintf("Appended %d sources to %s.", len(meta), opts.SampleFile))
+
+		if !time.Now().Add(opts.sampleInterval).Before(deadline) {
+			return
+		}
+		time.Sleep(opts.sampleInterval)
+	}
+}
+
+// metaColumnTitles maps --columns names to their table headings.
+var metaColumnTitles = map[string]string{
+	"source":         "Source",
+	"source-id":      "Source ID",
+	"source-type":    "Source Type",
+	"count":          "Count",
+	"expired":        "Expired",
+	"cache-duration": "Cache Duration",
+	"rate":           "Rate/minute",
+	"oldest":         "Oldest",
+	"newest":         "Newest",
+	"logs":           "Logs",
+	"counters":       "Counters",
+	"gauges":         "Gauges",
+	"timers":         "Timers",
+	"events":         "Events",
+	"crashes":        "Crashes",
+}
+
+func writeColumnHeaders(opts optionsFlags, tableWriter io.Writer) {
+	if !opts.withHeaders {
+		return
+	}
+
+	var titles []string
+	for _, col := range opts.columns {
+		titles = append(titles, metaColumnTitles[col])
+	}
+	fmt.Fprintf(tableWriter, "%s\n", strings.Join(titles, "\t"))
+}
+
+// columnCells renders the selected columns of a row, in the order the user
+// asked for them.
+func columnCells(opts optionsFlags, row displayRow) []string {
+	var cells []string
+	for _, col := range opts.columns {
+		cells = append(cells, columnCell(opts, col, row))
+	}
+	return cells
+}
+
+func columnCell(opts optionsFlags, col string, row displayRow) string {
+	switch col {
+	case "source":
+		return truncateSource(row.Source, sourceColumnWidth(opts))
+	case "source-id":
+		return row.SourceID
+	case "source-type":
+		return string(row.Type)
+	case "count":
+		return strconv.FormatInt(row.Count, 10)
+	case "expired":
+		return strconv.FormatInt(row.Expired, 10)
+	case "cache-duration":
+		if opts.targetRetention > 0 {
+			return retentionCell(opts, row.CacheDuration)
+		}
+		return row.CacheDuration.String()
+	case "rate":
+		return strconv.FormatInt(row.Delta, 10)
+	case "oldest":
+		return row.Oldest.Format(time.RFC3339)
+	case "newest":
+		return row.Newest.Format(time.RFC3339)
+	case "logs":
+		return strconv.FormatInt(row.TypeCounts.Logs, 10)
+	case "counters":
+		return strconv.FormatInt(row.TypeCounts.Counters, 10)
+	case "gauges":
+		return strconv.FormatInt(row.TypeCounts.Gauges, 10)
+	case "timers":
+		return strconv.FormatInt(row.TypeCounts.Timers, 10)
+	case "events":
+		return strconv.FormatInt(row.TypeCounts.Events, 10)
+	case "crashes":
+		return strconv.FormatInt(row.Crashes, 10)
+	}
+	return ""
+}
+
+// sourceColumnWidth returns how many characters of a source name fit the
+// terminal in the narrow layout, or 0 when names should not be truncated.
+func sourceColumnWidth(opts optionsFlags) int {
+	if opts.Wide || opts.terminalWidth <= 0 {
+		return 0
+	}
+
+	// leave room for the Source Type, Count, Expired, and Cache Duration
+	// columns rendered after the name
+	width := opts.terminalWidth - 50
+	if width < 16 {
+		width = 16
+	}
+	return width
+}
+
+// truncateSource shortens a long source name with an ellipsis so
+// 36-character GUIDs plus long app names don't wrap and destroy the table
+// alignment.
+func truncateSource(name string, max int) string {
+	if max <= 0 || len(name) <= max {
+		return name
+	}
+	return name[:max-3] + "..."
+}
+
 func writeRetrievingMetaHeader(opts optionsFlags, tableWriter io.Writer, username string) {
 	if opts.withHeaders {
 		fmt.Fprintf(tableWriter, fmt.Sprintf(
@@ -303,10 +995,25 @@ func writeHeaders(opts optionsFlags, tableWriter io.Writer, username string) {
 			headerFormat = "%s\t%s\t" + headerFormat
 		}
 
+		if opts.ShowTimestamps {
+			headerArgs = append(headerArgs, "Oldest", "Newest")
+			headerFormat = strings.Replace(headerFormat, "\n", "\t%s\t%s\n", 1)
+		}
+
 		if opts.EnableNoise {
 			headerArgs = append(headerArgs, "Rate/minute")
 			headerFormat = strings.Replace(headerFormat, "\n", "\t%s\n", 1)
 		}
+
+		if opts.ByType {
+			headerArgs = append(headerArgs, "Logs", "Counters", "Gauges", "Timers", "Events")
+			headerFormat = strings.Replace(headerFormat, "\n", "\t%s\t%s\t%s\t%s\t%s\n", 1)
+		}
+
+		if opts.ShowCrashes {
+			headerArgs = append(headerArgs, "Crashes")
+			headerFormat = strings.Replace(headerFormat, "\n", "\t%s\n", 1)
+		}
 		fmt.Fprintf(tableWriter, headerFormat, headerArgs...)
 	}
 
@@ -314,8 +1021,32 @@ func writeHeaders(opts optionsFlags, tableWriter io.Writer, username string) {
 
 func writeWaiting(opts optionsFlags, tableWriter io.Writer, username string) {
 	if opts.withHeaders {
-		fmt.Fprintf(tableWriter, "Waiting 5 minutes then comparing log output...\n\n")
+		fmt.Fprintf(tableWriter, "Waiting %s then comparing log output...\n\n", noiseWindowLabel(noiseRateWindow(opts.metaNoiseSleepDuration)))
+	}
+}
+
+// noiseRateWindow is the window noise rates are averaged over. A
+// non-positive configured window (e.g. tests skipping the wait) falls back
+// to the 5 minute default so rates stay meaningful.
+func noiseRateWindow(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 5 * time.Minute
+	}
+	return d
+}
+
+// noiseWindowLabel renders whole-minute windows the way the original fixed
+// message did, e.g. "5 minutes", and falls back to the duration string for
+// anything else.
+func noiseWindowLabel(d time.Duration) string {
+	if d%time.Minute == 0 {
+		minutes := int64(d / time.Minute)
+		if minutes == 1 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
 	}
+	return d.String()
 }
 
 func getOptions(args []string, log Logger, mopts ...MetaOption) optionsFlags {
@@ -323,26 +1054,139 @@ func getOptions(args []string, log Logger, mopts ...MetaOption) optionsFlags {
 		SourceType:             "default",
 		EnableNoise:            false,
 		ShowGUID:               false,
+		ShowTimestamps:         false,
+		NoNameCache:            false,
+		InsecureHTTP:           false,
 		SortBy:                 "",
+		Output:                 outputFormatTable,
+		ByType:                 false,
+		Threshold:              0,
+		OnlyAbove:              false,
+		RequireRateAbove:       -1,
+		RequireRateBelow:       -1,
+		VersionCheck:           false,
+		StrictCompat:           false,
 		withHeaders:            true,
 		metaNoiseSleepDuration: 5 * time.Minute,
 	}
 
+	if w := loadUserDefaults().NoiseWindow; w != "" {
+		if d, err := time.ParseDuration(w); err == nil && d > 0 {
+			opts.metaNoiseSleepDuration = d
+		}
+	}
+
 	for _, o := range mopts {
 		o(&opts)
 	}
 
 	args, err := flags.ParseArgs(&opts, args)
 	if err != nil {
-		log.Fatalf("Could not parse flags: %s", err)
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
 	}
 
 	if len(args) > 0 {
 		log.Fatalf("Invalid arguments, expected 0, got %d.", len(args))
 	}
 
+	if opts.CapiBatchSize == 0 {
+		if v := os.Getenv("LOG_CACHE_CAPI_BATCH_SIZE"); v != "" {
+			opts.CapiBatchSize, err = strconv.Atoi(v)
+			if err != nil {
+				log.Fatalf("LOG_CACHE_CAPI_BATCH_SIZE must be an integer.")
+			}
+		}
+	}
+	capiBatchSize = 50
+	if opts.CapiBatchSize != 0 {
+		if opts.CapiBatchSize < 1 || opts.CapiBatchSize > 200 {
+			log.Fatalf("--capi-batch-size must be between 1 and 200.")
+		}
+		capiBatchSize = opts.CapiBatchSize
+	}
+
+	if opts.Wide {
+		// show every optional column
+		opts.ShowTimestamps = true
+		opts.ByType = true
+		opts.ShowCrashes = true
+	}
+
+	if opts.Sample != "" || opts.SampleDuration != "" {
+		if opts.Sample == "" || opts.SampleDuration == "" {
+			log.Fatalf("--sample and --duration must be used together.")
+		}
+		if opts.SampleFile == "" {
+			log.Fatalf("--sample-file is required with --sample.")
+		}
+		opts.sampleInterval, err = time.ParseDuration(opts.Sample)
+		if err != nil || opts.sampleInterval <= 0 {
+			log.Fatalf("--sample must be a positive duration, e.g. '1m'.")
+		}
+		opts.sampleDuration, err = time.ParseDuration(opts.SampleDuration)
+		if err != nil || opts.sampleDuration <= 0 {
+			log.Fatalf("--duration must be a positive duration, e.g. '1h'.")
+		}
+	}
+
+	if opts.Columns != "" {
+		for _, col := range strings.Split(opts.Columns, ",") {
+			col = strings.TrimSpace(col)
+			if col == "" {
+				continue
+			}
+			if _, ok := metaColumnTitles[col]; !ok {
+				fatalf(log, ExitCodeUsage, "Unknown column: %s", col)
+			}
+			opts.columns = append(opts.columns, col)
+		}
+
+		for _, col := range opts.columns {
+			switch col {
+			case "rate":
+				if !opts.EnableNoise {
+					fatalf(log, ExitCodeUsage, "Column 'rate' is only valid with --noise.")
+				}
+			case "logs", "counters", "gauges", "timers", "events":
+				if !opts.ByType {
+					fatalf(log, ExitCodeUsage, "Column '%s' is only valid with --by-type.", col)
+				}
+			case "crashes":
+				if !opts.ShowCrashes {
+					fatalf(log, ExitCodeUsage, "Column 'crashes' is only valid with --show-crashes.")
+				}
+			}
+		}
+	}
+
 	opts.SourceType = strings.ToLower(opts.SourceType)
 	opts.SortBy = strings.ToLower(opts.SortBy)
+	opts.Output = strings.ToLower(opts.Output)
+
+	if opts.Output != outputFormatTable && opts.Output != outputFormatCSV {
+		log.Fatalf("Output format must be 'table' or 'csv'.")
+	}
+
+	if opts.Output == outputFormatCSV {
+		// CSV is machine consumable, so never write the decorative headers
+		opts.withHeaders = false
+	}
+
+	if opts.Quiet {
+		opts.withHeaders = false
+	}
+
+	if opts.Parallelism < 1 {
+		log.Fatalf("--parallelism must be at least 1.")
+	}
+
+	if opts.FromSnapshot != "" && opts.EnableNoise {
+		log.Fatalf("Cannot use --noise with --from-snapshot.")
+	}
+
+	if opts.ByOrg && opts.ShowGUID {
+		log.Fatalf("Cannot use --by-org with --guid.")
+	}
 
 	if opts.ShowGUID && (sortBySource.Equal(opts.SortBy) || sortBySourceType.Equal(opts.SortBy)) {
 		log.Fatalf("When using --guid, sort by must be 'source-id', 'count', 'expired', 'cache-duration', or 'rate'.")
@@ -372,6 +1216,30 @@ func getOptions(args []string, log Logger, mopts ...MetaOption) optionsFlags {
 		log.Fatalf("Can't sort by rate column without --noise flag")
 	}
 
+	if opts.Threshold < 0 {
+		log.Fatalf("--threshold must be a positive rate of envelopes per minute")
+	}
+
+	if opts.Threshold > 0 && !opts.EnableNoise {
+		log.Fatalf("Can't use --threshold without --noise flag")
+	}
+
+	if opts.OnlyAbove && opts.Threshold == 0 {
+		log.Fatalf("Can't use --only-above without --threshold flag")
+	}
+
+	if (opts.RequireRateAbove >= 0 || opts.RequireRateBelow >= 0) && !opts.EnableNoise {
+		log.Fatalf("Can't use --require-rate-above or --require-rate-below without --noise flag")
+	}
+
+	if opts.TargetRetention != "" {
+		d, err := time.ParseDuration(opts.TargetRetention)
+		if err != nil || d <= 0 {
+			log.Fatalf("--target-retention must be a positive duration, e.g. '15m'")
+		}
+		opts.targetRetention = d
+	}
+
 	return opts
 }
 
@@ -432,7 +1300,89 @@ func sortRows(opts optionsFlags, rows []displayRow) {
 	}
 }
 
-func getSourceInfo(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConnection) (map[string]source, error) {
+// progressFunc receives completion updates for long-running fan-out work.
+type progressFunc func(done, total int)
+
+// newProgressReporter logs an `n/m sources processed` line so long runs do
+// not look hung. Runs that fit in a single CAPI batch stay quiet.
+func newProgressReporter(log Logger) progressFunc {
+	return func(done, total int) {
+		if total <= 50 {
+			return
+		}
+		log.Printf("%d/%d sources processed", done, total)
+	}
+}
+
+// noiseWait sleeps until the second meta read, reporting the remaining time
+// so the long pause does not look hung.
+func noiseWait(d time.Duration, log Logger) {
+	const interval = 30 * time.Second
+
+	for d > 0 {
+		step := interval
+		if d < step {
+			step = d
+		}
+		time.Sleep(step)
+		d -= step
+
+		if d > 0 {
+			log.Printf("%s remaining until the second meta read", d)
+		}
+	}
+}
+
+// getSourceInfoWithCache resolves names through the on-disk cache, only
+// consulting CAPI for sources the cache does not know about.
+// nameLookup carries the result of a source name resolution that ran in the
+// background, e.g. concurrently with the noise window.
+type nameLookup struct {
+	resources map[string]source
+	err       error
+}
+
+func getSourceInfoWithCache(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConnection, skipCache bool, progress progressFunc) (map[string]source, error) {
+	if skipCache {
+		return getSourceInfo(metaInfo, cli, progress)
+	}
+
+	cache := loadNameCache()
+
+	resources := make(map[string]source)
+	missing := make(map[string]*logcache_v1.MetaInfo)
+	for sourceID, m := range metaInfo {
+		if s, ok := cache.Sources[sourceID]; ok {
+			resources[sourceID] = s
+		} else {
+			missing[sourceID] = m
+		}
+	}
+
+	if len(missing) == 0 {
+		return resources, nil
+	}
+
+	resolved, err := getSourceInfo(missing, cli, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache.Sources == nil {
+		cache = nameCache{CreatedAt: time.Now(), Sources: make(map[string]source)}
+	}
+	for sourceID, s := range resolved {
+		resources[sourceID] = s
+		cache.Sources[sourceID] = s
+	}
+
+	// a failure to persist only costs a lookup on the next invocation
+	_ = cache.save()
+
+	return resources, nil
+}
+
+func getSourceInfo(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConnection, progress progressFunc) (map[string]source, error) {
 	var (
 		resources map[string]source
 		sourceIDs []string
@@ -445,7 +1395,7 @@ func getSourceInfo(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConn
 		sourceIDs = append(sourceIDs, k)
 	}
 
-	appInfo, err := getSourceInfoFromCAPI(sourceIDs, "/v3/apps", cli)
+	appInfo, err := getSourceInfoFromCAPI(sourceIDs, "/v3/apps", cli, progress)
 	if err != nil {
 		return nil, err
 	}
@@ -470,52 +1420,183 @@ func getSourceInfo(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConn
 		s = append(s, id)
 	}
 
-	serviceInfo, err := getSourceInfoFromCAPI(s, "/v2/service_instances", cli)
+	serviceInfo, err := getSourceInfoFromCAPI(s, "/v3/service_instances", cli, progress)
 	if err != nil {
 		return nil, err
 	}
 
+	if capiErrorResponse(serviceInfo) {
+		// old CAPIs do not serve /v3/service_instances, fall back to v2
+		serviceInfo, err = getSourceInfoFromCAPI(s, "/v2/service_instances", cli, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rb := range serviceInfo {
+			var r servicesResponse
+			err := json.NewDecoder(strings.NewReader(rb)).Decode(&r)
+			if err != nil {
+				return nil, err
+			}
+			for _, res := range r.Resources {
+				resources[res.Metadata.GUID] = source{
+					GUID: res.Metadata.GUID,
+					Name: res.Entity.Name,
+					Type: sourceTypeService,
+				}
+			}
+		}
+
+		return resources, nil
+	}
+
 	for _, rb := range serviceInfo {
-		var r servicesResponse
+		var r sourceInfo
 		err := json.NewDecoder(strings.NewReader(rb)).Decode(&r)
 		if err != nil {
 			return nil, err
 		}
 		for _, res := range r.Resources {
-			resources[res.Metadata.GUID] = source{
-				GUID: res.Metadata.GUID,
-				Name: res.Entity.Name,
-				Type: sourceTypeService,
-			}
+			res.Type = sourceTypeService
+			resources[res.GUID] = res
 		}
 	}
 
 	return resources, nil
 }
 
-func getSourceInfoFromCAPI(sourceIDs []string, endpoint string, cli plugin.CliConnection) ([]string, error) {
-	var responses []string
-	for len(sourceIDs) > 0 {
-		n := 50
-		if len(sourceIDs) < 50 {
-			n = len(sourceIDs)
+// capiErrorResponse reports whether any response is a CAPI error document,
+// which is what old CAPIs return for unknown v3 endpoints.
+func capiErrorResponse(responses []string) bool {
+	for _, rb := range responses {
+		var e struct {
+			Errors []struct {
+				Title string `json:"title"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal([]byte(rb), &e); err != nil {
+			continue
 		}
+		if len(e.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
 
-		lines, err := cli.CliCommandWithoutTerminalOutput(
-			"curl",
-			endpoint+"?guids="+strings.Join(sourceIDs[0:n], ","),
-		)
-		if err != nil {
-			return nil, err
+// capiBatchWorkers bounds how many CAPI batch requests are in flight at
+// once.
+const capiBatchWorkers = 5
+
+// capiBatchSize is how many GUIDs are packed into one CAPI query string.
+// Some foundations reject the default as too long a URL, so it is tunable
+// via --capi-batch-size or LOG_CACHE_CAPI_BATCH_SIZE.
+var capiBatchSize = 50
+
+func getSourceInfoFromCAPI(sourceIDs []string, endpoint string, cli plugin.CliConnection, progress progressFunc) ([]string, error) {
+	total := len(sourceIDs)
+
+	var batches [][]string
+	for len(sourceIDs) > 0 {
+		n := capiBatchSize
+		if len(sourceIDs) < n {
+			n = len(sourceIDs)
 		}
 
+		batches = append(batches, sourceIDs[0:n])
 		sourceIDs = sourceIDs[n:]
-		rb := strings.Join(lines, "")
-		responses = append(responses, rb)
+	}
+
+	var (
+		mu        sync.Mutex
+		responses []string
+		firstErr  error
+		done      int
+		wg        sync.WaitGroup
+	)
+	sem := make(chan struct{}, capiBatchWorkers)
+
+	for _, batch := range batches {
+		wg.Add(1)
+		go func(batch []string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			requestPath := endpoint + "?guids=" + strings.Join(batch, ",")
+			for requestPath != "" {
+				lines, err := cli.CliCommandWithoutTerminalOutput(
+					"curl",
+					requestPath,
+				)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+
+				rb := strings.Join(lines, "")
+				mu.Lock()
+				responses = append(responses, rb)
+				mu.Unlock()
+
+				requestPath, err = nextPagePath(rb)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+
+			mu.Lock()
+			done += len(batch)
+			progress(done, total)
+			mu.Unlock()
+		}(batch)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
 	}
 	return responses, nil
 }
 
+// nextPagePath returns the request path of the next page of a paginated CAPI
+// v3 response, or an empty string on the last page.
+func nextPagePath(responseBody string) (string, error) {
+	var paginated struct {
+		Pagination struct {
+			Next struct {
+				Href string `json:"href"`
+			} `json:"next"`
+		} `json:"pagination"`
+	}
+
+	// a response that does not decode is surfaced by the caller's decode
+	if err := json.Unmarshal([]byte(responseBody), &paginated); err != nil {
+		return "", nil
+	}
+
+	if paginated.Pagination.Next.Href == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(paginated.Pagination.Next.Href)
+	if err != nil {
+		return "", err
+	}
+
+	return u.RequestURI(), nil
+}
+
 func cacheDuration(m *logcache_v1.MetaInfo) time.Duration {
 	new := time.Unix(0, m.NewestTimestamp)
 	old := time.Unix(0, m.OldestTimestamp)
@@ -0,0 +1,62 @@
+package cf
+
+import (
+	"encoding/json"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// getCrashCounts queries CF audit events for app process crashes and returns
+// a count per target GUID, so the meta table can correlate noisy or silent
+// sources with app instability.
+func getCrashCounts(sourceIDs []string, cli plugin.CliConnection) (map[string]int64, error) {
+	counts := make(map[string]int64)
+
+	var batches [][]string
+	for len(sourceIDs) > 0 {
+		n := 50
+		if len(sourceIDs) < 50 {
+			n = len(sourceIDs)
+		}
+
+		batches = append(batches, sourceIDs[0:n])
+		sourceIDs = sourceIDs[n:]
+	}
+
+	for _, batch := range batches {
+		requestPath := "/v3/audit_events?types=audit.app.process.crash&target_guids=" + strings.Join(batch, ",")
+		for requestPath != "" {
+			lines, err := cli.CliCommandWithoutTerminalOutput(
+				"curl",
+				requestPath,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			rb := strings.Join(lines, "")
+			var resp struct {
+				Resources []struct {
+					Target struct {
+						GUID string `json:"guid"`
+					} `json:"target"`
+				} `json:"resources"`
+			}
+			if err := json.Unmarshal([]byte(rb), &resp); err != nil {
+				return nil, err
+			}
+
+			for _, r := range resp.Resources {
+				counts[r.Target.GUID]++
+			}
+
+			requestPath, err = nextPagePath(rb)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,166 @@
+package cf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
+	flags "github.com/jessevdk/go-flags"
+)
+
+// metaSnapshot is the on-disk format written by meta-daemon and consumed by
+// --from-snapshot.
+type metaSnapshot struct {
+	WrittenAt time.Time                          `json:"written_at"`
+	Meta      map[string]*logcache_v1.MetaInfo   `json:"meta"`
+	History   []map[string]*logcache_v1.MetaInfo `json:"history,omitempty"`
+}
+
+// metaSnapshotHistory bounds how many previous refreshes the snapshot keeps
+// for trend analysis.
+const metaSnapshotHistory = 10
+
+type MetaDaemonOption func(*metaDaemonOptionFlags)
+
+// WithMetaDaemonSleepDuration overrides the refresh interval sleep, used in
+// tests.
+func WithMetaDaemonSleepDuration(d time.Duration) MetaDaemonOption {
+	return func(o *metaDaemonOptionFlags) {
+		o.sleepDuration = &d
+	}
+}
+
+// MetaDaemon periodically reads Meta information and maintains a rolling JSON
+// snapshot on disk so other commands can consume recent meta without issuing
+// their own reads.
+func MetaDaemon(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	mopts ...MetaDaemonOption,
+) {
+	opts := getMetaDaemonOptions(args, log, mopts...)
+	v := newVerboseLogger(log, len(opts.Verbose))
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+	lw := lineWriter{w: w}
+
+	sleep := opts.interval
+	if opts.sleepDuration != nil {
+		sleep = *opts.sleepDuration
+	}
+
+	var history []map[string]*logcache_v1.MetaInfo
+	for i := 0; opts.Iterations == 0 || i < opts.Iterations; i++ {
+		if i > 0 {
+			time.Sleep(sleep)
+		}
+
+		meta, err := client.Meta(ctx)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+		}
+
+		snapshot := metaSnapshot{
+			WrittenAt: time.Now(),
+			Meta:      meta,
+			History:   history,
+		}
+		if err := writeMetaSnapshot(opts.Snapshot, snapshot); err != nil {
+			log.Fatalf("Failed to write meta snapshot: %s", err)
+		}
+		lw.Write(fmt.Sprintf("Wrote meta snapshot for %d sources to %s.", len(meta), opts.Snapshot))
+
+		history = append(history, meta)
+		if len(history) > metaSnapshotHistory {
+			history = history[1:]
+		}
+	}
+}
+
+// writeMetaSnapshot writes the snapshot to a temporary file and renames it
+// into place so readers never observe a partial snapshot.
+func writeMetaSnapshot(path string, snapshot metaSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readMetaSnapshot(path string) (map[string]*logcache_v1.MetaInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot metaSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot.Meta, nil
+}
+
+type metaDaemonOptionFlags struct {
+	Interval     string `long:"interval" default:"1m"`
+	Snapshot     string `long:"snapshot"`
+	Iterations   int    `long:"iterations"`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Verbose      []bool `short:"v" long:"verbose"`
+
+	interval      time.Duration
+	sleepDuration *time.Duration
+}
+
+func getMetaDaemonOptions(args []string, log Logger, mopts ...MetaDaemonOption) metaDaemonOptionFlags {
+	opts := metaDaemonOptionFlags{}
+
+	for _, o := range mopts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	if len(args) > 0 {
+		log.Fatalf("Invalid arguments, expected 0, got %d.", len(args))
+	}
+
+	if opts.Snapshot == "" {
+		log.Fatalf("--snapshot is required.")
+	}
+
+	opts.interval, err = time.ParseDuration(opts.Interval)
+	if err != nil {
+		log.Fatalf("--interval must be a duration, e.g. '1m': %s", err)
+	}
+
+	if opts.interval < time.Second {
+		log.Fatalf("--interval must be at least 1 second.")
+	}
+
+	if opts.Iterations < 0 {
+		log.Fatalf("--iterations cannot be negative.")
+	}
+
+	return opts
+}
@@ -0,0 +1,104 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MetaDaemon", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+		snapshot    string
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.usernameResp = "a-user"
+		tableWriter = bytes.NewBuffer(nil)
+
+		dir, err := ioutil.TempDir("", "")
+		Expect(err).ToNot(HaveOccurred())
+		snapshot = filepath.Join(dir, "meta.json")
+	})
+
+	It("writes a rolling snapshot for each refresh", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			metaResponseInfoButHigher("source-1"),
+		}
+
+		cf.MetaDaemon(
+			context.Background(),
+			cliConn,
+			[]string{"--snapshot", snapshot, "--iterations", "2"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaDaemonSleepDuration(0),
+		)
+
+		line := fmt.Sprintf("Wrote meta snapshot for 1 sources to %s.", snapshot)
+		Expect(tableWriter.String()).To(Equal(line + "\n" + line + "\n"))
+		Expect(httpClient.requestCount()).To(Equal(2))
+
+		data, err := ioutil.ReadFile(snapshot)
+		Expect(err).ToNot(HaveOccurred())
+
+		var written struct {
+			Meta map[string]struct {
+				Count int64 `json:"count"`
+			} `json:"meta"`
+			History []map[string]struct {
+				Count int64 `json:"count"`
+			} `json:"history"`
+		}
+		Expect(json.Unmarshal(data, &written)).To(Succeed())
+		Expect(written.Meta["source-1"].Count).To(Equal(int64(100004)))
+		Expect(written.History).To(HaveLen(1))
+		Expect(written.History[0]["source-1"].Count).To(Equal(int64(100000)))
+	})
+
+	It("fatally logs when --snapshot is not provided", func() {
+		Expect(func() {
+			cf.MetaDaemon(
+				context.Background(),
+				cliConn,
+				nil,
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--snapshot is required."))
+	})
+
+	It("fatally logs when the interval is not a duration", func() {
+		Expect(func() {
+			cf.MetaDaemon(
+				context.Background(),
+				cliConn,
+				[]string{"--snapshot", snapshot, "--interval", "bogus"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(HavePrefix("--interval must be a duration"))
+	})
+})
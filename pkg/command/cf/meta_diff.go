@@ -0,0 +1,183 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
+	flags "github.com/jessevdk/go-flags"
+)
+
+type MetaDiffOption func(*metaDiffOptionFlags)
+
+// WithMetaDiffSleepDuration overrides the --interval sleep, used in tests.
+func WithMetaDiffSleepDuration(d time.Duration) MetaDiffOption {
+	return func(o *metaDiffOptionFlags) {
+		o.sleepDuration = &d
+	}
+}
+
+// MetaDiff compares two meta samples — either two snapshots written by
+// meta-daemon, or two live reads separated by --interval — and shows the
+// per-source change in count, expired, and cache duration, so it is easy to
+// see which sources grew between yesterday and today.
+func MetaDiff(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	tableWriter io.Writer,
+	mopts ...MetaDiffOption,
+) {
+	opts := getMetaDiffOptions(args, log, mopts...)
+
+	var before, after map[string]*logcache_v1.MetaInfo
+	var err error
+	if len(opts.snapshots) == 2 {
+		before, err = readMetaSnapshot(opts.snapshots[0])
+		if err != nil {
+			log.Fatalf("Failed to read meta snapshot: %s", err)
+		}
+		after, err = readMetaSnapshot(opts.snapshots[1])
+		if err != nil {
+			log.Fatalf("Failed to read meta snapshot: %s", err)
+		}
+	} else {
+		v := newVerboseLogger(log, len(opts.Verbose))
+		client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+
+		before, err = client.Meta(ctx)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+		}
+
+		sleep := opts.interval
+		if opts.sleepDuration != nil {
+			sleep = *opts.sleepDuration
+		}
+		time.Sleep(sleep)
+
+		after, err = client.Meta(ctx)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+		}
+	}
+
+	sourceIDs := make(map[string]struct{})
+	for sourceID := range before {
+		sourceIDs[sourceID] = struct{}{}
+	}
+	for sourceID := range after {
+		sourceIDs[sourceID] = struct{}{}
+	}
+
+	var ids []string
+	for sourceID := range sourceIDs {
+		ids = append(ids, sourceID)
+	}
+	sort.Strings(ids)
+
+	tw := tabwriter.NewWriter(tableWriter, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "Source ID\tCount\tExpired\tCache Duration")
+	for _, sourceID := range ids {
+		b, a := before[sourceID], after[sourceID]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			sourceID,
+			signedDelta(metaCount(a)-metaCount(b)),
+			signedDelta(metaExpired(a)-metaExpired(b)),
+			signedDuration(metaCacheDuration(a)-metaCacheDuration(b)),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatalf("Error writing results")
+	}
+}
+
+func metaCount(m *logcache_v1.MetaInfo) int64 {
+	if m == nil {
+		return 0
+	}
+	return m.Count
+}
+
+func metaExpired(m *logcache_v1.MetaInfo) int64 {
+	if m == nil {
+		return 0
+	}
+	return m.Expired
+}
+
+func metaCacheDuration(m *logcache_v1.MetaInfo) time.Duration {
+	if m == nil {
+		return 0
+	}
+	return cacheDuration(m)
+}
+
+// signedDelta renders a change with an explicit sign so growth and shrinkage
+// scan apart in the table.
+func signedDelta(d int64) string {
+	if d > 0 {
+		return fmt.Sprintf("+%d", d)
+	}
+	return fmt.Sprintf("%d", d)
+}
+
+func signedDuration(d time.Duration) string {
+	if d > 0 {
+		return fmt.Sprintf("+%s", d)
+	}
+	return d.String()
+}
+
+type metaDiffOptionFlags struct {
+	Interval     string `long:"interval"`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Verbose      []bool `short:"v" long:"verbose"`
+
+	snapshots     []string
+	interval      time.Duration
+	sleepDuration *time.Duration
+}
+
+func getMetaDiffOptions(args []string, log Logger, mopts ...MetaDiffOption) metaDiffOptionFlags {
+	opts := metaDiffOptionFlags{}
+
+	for _, o := range mopts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	switch len(args) {
+	case 0:
+		if opts.Interval == "" {
+			log.Fatalf("Expected 2 snapshots or --interval.")
+		}
+		opts.interval, err = time.ParseDuration(opts.Interval)
+		if err != nil {
+			log.Fatalf("--interval must be a duration, e.g. '1m': %s", err)
+		}
+		if opts.interval <= 0 {
+			log.Fatalf("--interval must be positive.")
+		}
+	case 2:
+		if opts.Interval != "" {
+			log.Fatalf("--interval cannot be combined with snapshots.")
+		}
+		opts.snapshots = args
+	default:
+		log.Fatalf("Expected 2 snapshots or --interval, got %d arguments.", len(args))
+	}
+
+	return opts
+}
@@ -0,0 +1,102 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MetaDiff", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.usernameResp = "a-user"
+		tableWriter = bytes.NewBuffer(nil)
+	})
+
+	It("diffs two live samples with --interval", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			metaResponseInfoButHigher("source-1"),
+		}
+
+		cf.MetaDiff(
+			context.Background(),
+			cliConn,
+			[]string{"--interval", "1ms"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaDiffSleepDuration(0),
+		)
+
+		Expect(httpClient.requestCount()).To(Equal(2))
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Source ID  Count  Expired  Cache Duration",
+			"source-1   +4     +1       0s",
+			"",
+		}))
+	})
+
+	It("diffs two snapshot files", func() {
+		dir, err := ioutil.TempDir("", "meta-diff")
+		Expect(err).ToNot(HaveOccurred())
+
+		before := filepath.Join(dir, "before.json")
+		after := filepath.Join(dir, "after.json")
+		Expect(ioutil.WriteFile(before, []byte(`{"meta":{
+			"source-1":{"count":100000,"expired":85008,"oldestTimestamp":1519256863100000000,"newestTimestamp":1519256863110000000}
+		}}`), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(after, []byte(`{"meta":{
+			"source-1":{"count":100004,"expired":85009,"oldestTimestamp":1519256863100000000,"newestTimestamp":1519256863110000000},
+			"source-2":{"count":7,"expired":0,"oldestTimestamp":1519256863100000000,"newestTimestamp":1519256863110000000}
+		}}`), 0600)).To(Succeed())
+
+		cf.MetaDiff(
+			context.Background(),
+			cliConn,
+			[]string{before, after},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(httpClient.requestCount()).To(Equal(0))
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Source ID  Count  Expired  Cache Duration",
+			"source-1   +4     +1       0s",
+			"source-2   +7     0        +1s",
+			"",
+		}))
+	})
+
+	It("fatally logs without snapshots or --interval", func() {
+		Expect(func() {
+			cf.MetaDiff(
+				context.Background(),
+				cliConn,
+				nil,
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Expected 2 snapshots or --interval."))
+	})
+})
@@ -0,0 +1,210 @@
+package cf
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// capiSpaceRelationship decodes the space a CAPI resource belongs to.
+type capiSpaceRelationship struct {
+	Resources []struct {
+		GUID          string `json:"guid"`
+		Name          string `json:"name"`
+		Relationships struct {
+			Space struct {
+				Data struct {
+					GUID string `json:"guid"`
+				} `json:"data"`
+			} `json:"space"`
+			Organization struct {
+				Data struct {
+					GUID string `json:"guid"`
+				} `json:"data"`
+			} `json:"organization"`
+		} `json:"relationships"`
+	} `json:"resources"`
+}
+
+// getSourceOrgs resolves app and service instance GUIDs to their organization
+// names by walking CAPI relationships: sources to spaces to organizations.
+func getSourceOrgs(sourceIDs []string, cli plugin.CliConnection, progress progressFunc) (map[string]string, error) {
+	spaceBySource := make(map[string]string)
+	for _, endpoint := range []string{"/v3/apps", "/v3/service_instances"} {
+		responses, err := getSourceInfoFromCAPI(sourceIDs, endpoint, cli, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rb := range responses {
+			var resp capiSpaceRelationship
+			if err := json.Unmarshal([]byte(rb), &resp); err != nil {
+				continue
+			}
+			for _, r := range resp.Resources {
+				if r.Relationships.Space.Data.GUID != "" {
+					spaceBySource[r.GUID] = r.Relationships.Space.Data.GUID
+				}
+			}
+		}
+	}
+
+	orgBySpace := make(map[string]string)
+	for _, rb := range capiResponses(uniqueValues(spaceBySource), "/v3/spaces", cli, progress) {
+		var resp capiSpaceRelationship
+		if err := json.Unmarshal([]byte(rb), &resp); err != nil {
+			continue
+		}
+		for _, r := range resp.Resources {
+			if r.Relationships.Organization.Data.GUID != "" {
+				orgBySpace[r.GUID] = r.Relationships.Organization.Data.GUID
+			}
+		}
+	}
+
+	nameByOrg := make(map[string]string)
+	for _, rb := range capiResponses(uniqueValues(orgBySpace), "/v3/organizations", cli, progress) {
+		var resp capiSpaceRelationship
+		if err := json.Unmarshal([]byte(rb), &resp); err != nil {
+			continue
+		}
+		for _, r := range resp.Resources {
+			nameByOrg[r.GUID] = r.Name
+		}
+	}
+
+	orgs := make(map[string]string)
+	for sourceID, space := range spaceBySource {
+		if name, ok := nameByOrg[orgBySpace[space]]; ok {
+			orgs[sourceID] = name
+		}
+	}
+
+	return orgs, nil
+}
+
+// capiResponses fetches the given GUIDs from a CAPI endpoint, swallowing
+// errors: a source whose org cannot be resolved rolls up under the platform.
+func capiResponses(guids []string, endpoint string, cli plugin.CliConnection, progress progressFunc) []string {
+	responses, err := getSourceInfoFromCAPI(guids, endpoint, cli, progress)
+	if err != nil {
+		return nil
+	}
+	return responses
+}
+
+func uniqueValues(m map[string]string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, v := range m {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+type orgRollup struct {
+	Org     string
+	Sources int
+	Count   int64
+	Expired int64
+	Delta   int64
+}
+
+// rollupByOrg aggregates the display rows per organization. Sources that do
+// not belong to an organization, e.g. platform components, are grouped under
+// "(platform)".
+func rollupByOrg(rows []displayRow, orgs map[string]string) []orgRollup {
+	byOrg := make(map[string]*orgRollup)
+	for _, r := range rows {
+		org, ok := orgs[r.SourceID]
+		if !ok {
+			org = "(platform)"
+		}
+
+		rollup, ok := byOrg[org]
+		if !ok {
+			rollup = &orgRollup{Org: org}
+			byOrg[org] = rollup
+		}
+		rollup.Sources++
+		rollup.Count += r.Count
+		rollup.Expired += r.Expired
+		if r.Delta > 0 {
+			rollup.Delta += r.Delta
+		}
+	}
+
+	var rollups []orgRollup
+	for _, r := range byOrg {
+		rollups = append(rollups, *r)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		return rollups[i].Org < rollups[j].Org
+	})
+
+	return rollups
+}
+
+func writeOrgHeaders(opts optionsFlags, tw *tabwriter.Writer) {
+	if !opts.withHeaders {
+		return
+	}
+
+	if opts.EnableNoise {
+		fmt.Fprintf(tw, "Org\tSources\tCount\tExpired\tRate/minute\n")
+		return
+	}
+	fmt.Fprintf(tw, "Org\tSources\tCount\tExpired\n")
+}
+
+func orgTableFormat(opts optionsFlags, r orgRollup) (string, []interface{}) {
+	format := "%s\t%d\t%d\t%d\n"
+	items := []interface{}{r.Org, r.Sources, r.Count, r.Expired}
+
+	if opts.EnableNoise {
+		format = "%s\t%d\t%d\t%d\t%d\n"
+		items = append(items, r.Delta)
+	}
+
+	return format, items
+}
+
+func writeOrgCSVRows(opts optionsFlags, w io.Writer, rollups []orgRollup) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"Org", "Sources", "Count", "Expired"}
+	if opts.EnableNoise {
+		header = append(header, "Rate/minute")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range rollups {
+		record := []string{
+			r.Org,
+			strconv.Itoa(r.Sources),
+			strconv.FormatInt(r.Count, 10),
+			strconv.FormatInt(r.Expired, 10),
+		}
+		if opts.EnableNoise {
+			record = append(record, strconv.FormatInt(r.Delta, 10))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,74 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/cli/plugin"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
+	"github.com/blang/semver"
+	"github.com/golang/protobuf/jsonpb"
+)
+
+// metaScopeMinVersion is the first gateway version whose /api/v1/meta accepts
+// a scope query parameter for server-side source type filtering.
+var metaScopeMinVersion = semver.MustParse("2.12.0")
+
+// serverSideScope maps a --source-type to the gateway's scope parameter.
+// Types that rely on client-side heuristics return an empty scope.
+func serverSideScope(sourceType string) string {
+	switch {
+	case sourceTypeApplication.Equal(sourceType),
+		sourceTypeService.Equal(sourceType),
+		sourceTypePlatform.Equal(sourceType):
+		return sourceType
+	default:
+		return ""
+	}
+}
+
+// scopedMeta reads meta with server-side scope filtering. The second return
+// value reports whether the gateway supports scoping; callers fall back to an
+// unscoped read and client-side filtering when it does not.
+func scopedMeta(
+	ctx context.Context,
+	client *logcache.Client,
+	c HTTPClient,
+	cli plugin.CliConnection,
+	scope string,
+) (map[string]*logcache_v1.MetaInfo, bool, error) {
+	version, err := client.LogCacheVersion(ctx)
+	if err != nil || version.LT(metaScopeMinVersion) {
+		return nil, false, nil
+	}
+
+	endpoint, err := logCacheEndpoint(cli)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/meta?scope=%s", endpoint, scope), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var metaResponse logcache_v1.MetaResponse
+	if err := jsonpb.Unmarshal(resp.Body, &metaResponse); err != nil {
+		return nil, false, err
+	}
+
+	return metaResponse.Meta, true, nil
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
@@ -32,6 +34,93 @@ var _ = Describe("Meta", func() {
 		cliConn.orgName = "organization"
 		cliConn.spaceName = "space"
 		tableWriter = bytes.NewBuffer(nil)
+
+		cfHome, err := ioutil.TempDir("", "")
+		Expect(err).ToNot(HaveOccurred())
+		_ = os.Setenv("CF_HOME", cfHome)
+	})
+
+	AfterEach(func() {
+		_ = os.Unsetenv("CF_HOME")
+	})
+
+	Context("when displaying platform sources", func() {
+		BeforeEach(func() {
+			httpClient.responseBody = []string{
+				metaResponseInfo("doppler"),
+			}
+
+			cliConn.cliCommandResult = [][]string{
+				{capiAppsResponse(nil)},
+				{capiServiceInstancesResponse(nil)},
+			}
+			cliConn.cliCommandErr = nil
+		})
+
+		It("shows recognizable names for well-known platform sources", func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				nil,
+				httpClient,
+				logger,
+				tableWriter,
+			)
+
+			Expect(strings.Split(tableWriter.String(), "\n")).To(ContainElement(
+				"Doppler (Loggregator)  platform     100000  85008    1s",
+			))
+		})
+
+		It("resolves sources to BOSH jobs with --bosh-mapping", func() {
+			dir, err := ioutil.TempDir("", "bosh-mapping")
+			Expect(err).ToNot(HaveOccurred())
+			mapping := filepath.Join(dir, "mapping.yml")
+			Expect(ioutil.WriteFile(mapping, []byte("doppler: doppler/0a1b2c3d\n"), 0600)).To(Succeed())
+
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--bosh-mapping", mapping},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+
+			Expect(strings.Split(tableWriter.String(), "\n")).To(ContainElement(
+				"doppler/0a1b2c3d  platform     100000  85008    1s",
+			))
+		})
+
+		It("fatally logs when the --bosh-mapping file cannot be read", func() {
+			Expect(func() {
+				cf.Meta(
+					context.Background(),
+					cliConn,
+					[]string{"--bosh-mapping", "/does/not/exist.yml"},
+					httpClient,
+					logger,
+					tableWriter,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(HavePrefix("Could not read --bosh-mapping:"))
+		})
+
+		It("shows the raw source ID with --raw-source-names", func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--raw-source-names"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+
+			Expect(strings.Split(tableWriter.String(), "\n")).To(ContainElement(
+				"doppler  platform     100000  85008    1s",
+			))
+		})
 	})
 
 	Context("when specifying a sort by flag", func() {
@@ -420,17 +509,20 @@ var _ = Describe("Meta", func() {
 		Expect(httpClient.requestCount()).To(Equal(1))
 	})
 
-	It("removes headers when not printing to a tty", func() {
+	It("falls back to the v2 service instance endpoint on old CAPIs", func() {
 		httpClient.responseBody = []string{
 			metaResponseInfo("source-1", "source-2"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(map[string]string{
-					"source-1": "app-2",
-					"source-2": "app-1",
-				}),
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+			{
+				`{"errors": [{"title": "CF-NotFound", "detail": "Unknown request"}]}`,
+			},
+			{
+				capiV2ServiceInstancesResponse(map[string]string{"source-2": "service-2"}),
 			},
 		}
 		cliConn.cliCommandErr = nil
@@ -438,85 +530,101 @@ var _ = Describe("Meta", func() {
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			[]string{"--guid"},
+			nil,
 			httpClient,
 			logger,
 			tableWriter,
-			cf.WithMetaNoHeaders(),
 		)
 
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			"source-1  100000  85008  1s",
-			"source-2  100000  85008  11m45s",
-			"",
-		}))
+		Expect(cliConn.cliCommandArgs).To(HaveLen(3))
+		Expect(cliConn.cliCommandArgs[1][1]).To(Equal("/v3/service_instances?guids=source-2"))
+		Expect(cliConn.cliCommandArgs[2][1]).To(Equal("/v2/service_instances?guids=source-2"))
+
+		Expect(tableWriter.String()).To(ContainSubstring("service-2"))
 	})
 
-	It("does not display the Source ID column by default", func() {
+	It("splits CAPI name resolution into --capi-batch-size batches", func() {
 		httpClient.responseBody = []string{
-			metaResponseInfo("source-1"),
+			metaResponseInfo("source-1", "source-2"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
-			{
-				capiAppsResponse(map[string]string{"source-1": "app-1"}),
-			},
+			{capiAppsResponse(map[string]string{"source-1": "app-1"})},
+			{capiAppsResponse(map[string]string{"source-2": "app-2"})},
 		}
 		cliConn.cliCommandErr = nil
 
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			nil,
+			[]string{"--capi-batch-size", "1"},
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
-		Expect(cliConn.cliCommandArgs).To(HaveLen(1))
-		Expect(cliConn.cliCommandArgs[0]).To(HaveLen(2))
-		Expect(cliConn.cliCommandArgs[0][0]).To(Equal("curl"))
-		Expect(cliConn.cliCommandArgs[0][1]).To(Equal("/v3/apps?guids=source-1"))
+		var urls []string
+		for _, args := range cliConn.cliCommandArgs {
+			urls = append(urls, args[1])
+		}
+		Expect(urls).To(ConsistOf(
+			"/v3/apps?guids=source-1",
+			"/v3/apps?guids=source-2",
+		))
 
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			fmt.Sprintf(
-				"Retrieving app and service names as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source  Source Type  Count   Expired  Cache Duration",
-			"app-1   application  100000  85008    1s",
-			"",
-		}))
+		Expect(tableWriter.String()).To(ContainSubstring("app-1"))
+		Expect(tableWriter.String()).To(ContainSubstring("app-2"))
+	})
 
-		Expect(httpClient.requestCount()).To(Equal(1))
+	It("fatally logs when --capi-batch-size is out of range", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--capi-batch-size", "500"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--capi-batch-size must be between 1 and 200."))
 	})
 
-	It("displays the rate column for each service type", func() {
+	It("fatally logs when LOG_CACHE_CAPI_BATCH_SIZE is not an integer", func() {
+		os.Setenv("LOG_CACHE_CAPI_BATCH_SIZE", "lots")
+		defer os.Unsetenv("LOG_CACHE_CAPI_BATCH_SIZE")
+
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				nil,
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("LOG_CACHE_CAPI_BATCH_SIZE must be an integer."))
+	})
+
+	It("follows pagination.next links in CAPI responses", func() {
 		httpClient.responseBody = []string{
-			metaResponseInfo(
-				"source-1",
-				"source-2",
-				"source-3",
-			),
-			metaResponseInfoButHigher(
-				"source-1",
-				"source-2",
-				"source-3",
-			),
+			metaResponseInfo("source-1", "source-2"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+				`{
+					"pagination": {"next": {"href": "https://api.some-system.com/v3/apps?guids=source-1,source-2&page=2"}},
+					"resources": [{"guid": "source-1", "name": "app-1"}]
+				}`,
 			},
 			{
-				capiServiceInstancesResponse(map[string]string{"source-3": "service-3"}),
+				capiAppsResponse(map[string]string{
+					"source-2": "app-2",
+				}),
 			},
 		}
 		cliConn.cliCommandErr = nil
@@ -524,11 +632,34 @@ var _ = Describe("Meta", func() {
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			[]string{"--noise"},
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(cliConn.cliCommandArgs).To(HaveLen(2))
+		Expect(cliConn.cliCommandArgs[1]).To(Equal([]string{
+			"curl",
+			"/v3/apps?guids=source-1,source-2&page=2",
+		}))
+
+		Expect(tableWriter.String()).To(ContainSubstring("app-1"))
+		Expect(tableWriter.String()).To(ContainSubstring("app-2"))
+	})
+
+	It("shows oldest and newest timestamps with --show-timestamps", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid", "--show-timestamps"},
 			httpClient,
 			logger,
 			tableWriter,
-			cf.WithMetaNoiseSleepDuration(0),
 		)
 
 		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
@@ -537,113 +668,72 @@ var _ = Describe("Meta", func() {
 				cliConn.usernameResp,
 			),
 			"",
-			"Waiting 5 minutes then comparing log output...",
-			"",
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			fmt.Sprintf(
-				"Retrieving app and service names as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source     Source Type  Count   Expired  Cache Duration  Rate/minute",
-			"app-1      application  100004  85009    1s              1",
-			"service-3  service      100004  85009    11m45s          1",
-			"source-2   platform     100004  85009    11m45s          1",
+			"Source ID  Count   Expired  Cache Duration  Oldest                Newest",
+			"source-1   100000  85008    1s              2018-02-21T23:47:43Z  2018-02-21T23:47:43Z",
 			"",
 		}))
-
-		Expect(httpClient.requestCount()).To(Equal(2))
 	})
 
-	It("prints source IDs without app names when CAPI doesn't return info", func() {
+	It("renders cache duration against --target-retention with color thresholds", func() {
 		httpClient.responseBody = []string{
-			metaResponseInfo("source-1", "source-2"),
-		}
-
-		cliConn.cliCommandResult = [][]string{
-			{
-				capiAppsResponse(map[string]string{"source-1": "app-1"}),
-			},
-			{
-				capiServiceInstancesResponse(nil),
-			},
+			metaResponseInfo("source-1"),
 		}
-		cliConn.cliCommandErr = nil
 
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			nil,
+			[]string{"--guid", "--target-retention", "10s"},
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
-		Expect(cliConn.cliCommandArgs).To(HaveLen(2))
+		Expect(tableWriter.String()).To(ContainSubstring("\x1b[31m1s (10%)\x1b[0m"))
+	})
 
-		Expect(cliConn.cliCommandArgs[0]).To(HaveLen(2))
-		Expect(cliConn.cliCommandArgs[0][0]).To(Equal("curl"))
-		uri, err := url.Parse(cliConn.cliCommandArgs[0][1])
-		Expect(err).ToNot(HaveOccurred())
-		Expect(uri.Path).To(Equal("/v3/apps"))
-		guidsParam, ok := uri.Query()["guids"]
-		Expect(ok).To(BeTrue())
-		Expect(len(guidsParam)).To(Equal(1))
-		Expect(strings.Split(guidsParam[0], ",")).To(ConsistOf("source-1", "source-2"))
+	It("renders the retention percentage without color when headers are off", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
 
-		Expect(cliConn.cliCommandArgs[1]).To(HaveLen(2))
-		Expect(cliConn.cliCommandArgs[1][0]).To(Equal("curl"))
-		Expect(cliConn.cliCommandArgs[1][1]).To(Equal("/v2/service_instances?guids=source-2"))
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid", "--target-retention", "2s"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaNoHeaders(),
+		)
 
-		Expect(httpClient.requestCount()).To(Equal(1))
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			fmt.Sprintf(
-				"Retrieving app and service names as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source    Source Type  Count   Expired  Cache Duration",
-			"app-1     application  100000  85008    1s",
-			"source-2  platform     100000  85008    11m45s",
-			"",
-		}))
+		Expect(tableWriter.String()).To(ContainSubstring("1s (50%)"))
+		Expect(tableWriter.String()).ToNot(ContainSubstring("\x1b["))
 	})
 
-	It("prints meta scoped to apps with guids after names", func() {
+	It("rolls counts up per organization with --by-org", func() {
 		httpClient.responseBody = []string{
-			metaResponseInfo(
-				"deadbeef-dead-dead-dead-deaddeafbeef",
-				"source-2",
-				"026fb323-6884-4978-a45f-da188dbf8ecd",
-			),
+			metaResponseInfo("source-1", "source-2", "source-3"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
-			{
-				capiAppsResponse(map[string]string{
-					"deadbeef-dead-dead-dead-deaddeafbeef": "app-1",
-				}),
-			},
-			{
-				capiServiceInstancesResponse(nil),
-			},
+			// name resolution
+			{capiAppsResponse(map[string]string{"source-1": "app-1"})},
+			{capiServiceInstancesResponse(map[string]string{"source-3": "service-3"})},
+			// org resolution: sources to spaces to orgs
+			{`{"resources":[{"guid":"source-1","relationships":{"space":{"data":{"guid":"space-1"}}}}]}`},
+			{`{"resources":[{"guid":"source-3","relationships":{"space":{"data":{"guid":"space-2"}}}}]}`},
+			{`{"resources":[
+				{"guid":"space-1","relationships":{"organization":{"data":{"guid":"org-1"}}}},
+				{"guid":"space-2","relationships":{"organization":{"data":{"guid":"org-2"}}}}
+			]}`},
+			{`{"resources":[{"guid":"org-1","name":"org-a"},{"guid":"org-2","name":"org-b"}]}`},
 		}
 		cliConn.cliCommandErr = nil
 
-		args := []string{"--source-type", "application"}
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			args,
+			[]string{"--by-org"},
 			httpClient,
 			logger,
 			tableWriter,
@@ -660,8 +750,727 @@ var _ = Describe("Meta", func() {
 				cliConn.usernameResp,
 			),
 			"",
-			"Source  Source Type  Count   Expired  Cache Duration",
-			"app-1   application  100000  85008    1s",
+			"Org         Sources  Count   Expired",
+			"(platform)  1        100000  85008",
+			"org-a       1        100000  85008",
+			"org-b       1        100000  85008",
+			"",
+		}))
+	})
+
+	It("fatally logs when --by-org is used with --guid", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--by-org", "--guid"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Cannot use --by-org with --guid."))
+	})
+
+	It("fatally logs when --target-retention is not a positive duration", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--target-retention", "nope"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--target-retention must be a positive duration, e.g. '15m'"))
+	})
+
+	It("shows every optional column with --wide", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			`{"envelopes":{"batch":[
+				{"timestamp":"1","source_id":"source-1","log":{"payload":"bG9n"}},
+				{"timestamp":"2","source_id":"source-1","counter":{"name":"c","total":"1"}}
+			]}}`,
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				`{"resources":[{"target":{"guid":"source-1"}}]}`,
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid", "--wide"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source ID  Count   Expired  Cache Duration  Oldest                Newest                Logs  Counters  Gauges  Timers  Events  Crashes",
+			"source-1   100000  85008    1s              2018-02-21T23:47:43Z  2018-02-21T23:47:43Z  1     1         0       0       0       1",
+			"",
+		}))
+	})
+
+	It("truncates long source names to the terminal width by default", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		longName := strings.Repeat("a", 40)
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": longName}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaTerminalWidth(70),
+		)
+
+		Expect(tableWriter.String()).To(ContainSubstring(longName[:17] + "..."))
+		Expect(tableWriter.String()).ToNot(ContainSubstring(longName))
+	})
+
+	It("shows only the selected columns, in order, with --columns", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid", "--columns", "count,source-id"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Count   Source ID",
+			"100000  source-1",
+			"",
+		}))
+	})
+
+	It("fatally logs when --columns names an unknown column", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--columns", "count,bogus"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Unknown column: bogus"))
+	})
+
+	It("fatally logs when --columns includes rate without --noise", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--columns", "source,rate"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Column 'rate' is only valid with --noise."))
+	})
+
+	It("appends timestamped CSV samples with --sample", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		dir, err := ioutil.TempDir("", "meta-sample")
+		Expect(err).ToNot(HaveOccurred())
+		sampleFile := filepath.Join(dir, "samples.csv")
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--sample", "1ms", "--duration", "1ms", "--sample-file", sampleFile},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(tableWriter.String()).To(Equal(
+			fmt.Sprintf("Appended 1 sources to %s.\n", sampleFile),
+		))
+
+		data, err := ioutil.ReadFile(sampleFile)
+		Expect(err).ToNot(HaveOccurred())
+
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(Equal("timestamp,source_id,count,expired,cache_duration"))
+		Expect(lines[1]).To(MatchRegexp(`^[0-9T:Z+-]+,source-1,100000,85008,1s$`))
+	})
+
+	It("fatally logs when --sample is used without --duration", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--sample", "1m"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--sample and --duration must be used together."))
+	})
+
+	It("adds a Crashes column with --show-crashes", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				`{"resources":[{"target":{"guid":"source-1"}},{"target":{"guid":"source-1"}}]}`,
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid", "--show-crashes"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(cliConn.cliCommandArgs).To(HaveLen(1))
+		Expect(cliConn.cliCommandArgs[0]).To(Equal([]string{
+			"curl",
+			"/v3/audit_events?types=audit.app.process.crash&target_guids=source-1",
+		}))
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source ID  Count   Expired  Cache Duration  Crashes",
+			"source-1   100000  85008    1s              2",
+			"",
+		}))
+	})
+
+	It("writes the meta table as CSV with --output csv", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{
+					"source-1": "app,1",
+				}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--output", "csv"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Source,Source Type,Count,Expired,Cache Duration",
+			`"app,1",application,100000,85008,1s`,
+			"",
+		}))
+	})
+
+	It("fatally logs when --output is not a known format", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--output", "yaml"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Output format must be 'table' or 'csv'."))
+	})
+
+	It("marks noisy sources above --threshold and exits non-zero", func() {
+		httpClient.responseBody = []string{
+			variedMetaResponseInfo("source-1", "source-2", "source-3", "source-4"),
+			variedMetaResponseInfoButHigher([]int{25, 15, 5, 10}, "source-1", "source-2", "source-3", "source-4"),
+		}
+
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--guid", "--noise", "--threshold", "2", "--only-above"},
+				httpClient,
+				logger,
+				tableWriter,
+				cf.WithMetaNoiseSleepDuration(0),
+			)
+		}).To(Panic())
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Waiting 5 minutes then comparing log output...",
+			"",
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source ID  Count   Expired  Cache Duration  Rate/minute",
+			"source-1   100026  84999    1s              5 *",
+			"source-2   100017  84998    4m30s           3 *",
+			"",
+		}))
+
+		Expect(logger.fatalfMessage).To(Equal(
+			"2 sources exceeded the rate threshold of 2 envelopes/minute",
+		))
+	})
+
+	It("filters to sources within the rate bounds with --require-rate-above and --require-rate-below", func() {
+		httpClient.responseBody = []string{
+			variedMetaResponseInfo("source-1", "source-2", "source-3", "source-4"),
+			variedMetaResponseInfoButHigher([]int{25, 15, 5, 10}, "source-1", "source-2", "source-3", "source-4"),
+		}
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid", "--noise", "--require-rate-above", "1", "--require-rate-below", "5"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaNoiseSleepDuration(0),
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Waiting 5 minutes then comparing log output...",
+			"",
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source ID  Count   Expired  Cache Duration  Rate/minute",
+			"source-2   100017  84998    4m30s           3",
+			"source-4   100006  85004    13m30s          2",
+			"",
+		}))
+	})
+
+	It("fatally logs when rate filters are used without --noise", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--require-rate-above", "2"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Can't use --require-rate-above or --require-rate-below without --noise flag"))
+	})
+
+	It("fatally logs when --threshold is used without --noise", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--threshold", "2"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Can't use --threshold without --noise flag"))
+	})
+
+	It("breaks the count down by envelope type with --by-type", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			`{"envelopes":{"batch":[
+				{"timestamp":"1","source_id":"source-1","log":{"payload":"bG9n"}},
+				{"timestamp":"2","source_id":"source-1","log":{"payload":"bG9n"}},
+				{"timestamp":"3","source_id":"source-1","counter":{"name":"c","total":"1"}},
+				{"timestamp":"4","source_id":"source-1","gauge":{"metrics":{"m":{"value":1,"unit":"b"}}}}
+			]}}`,
+		}
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid", "--by-type"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source ID  Count   Expired  Cache Duration  Logs  Counters  Gauges  Timers  Events",
+			"source-1   100000  85008    1s              2     1         1       0       0",
+			"",
+		}))
+
+		Expect(httpClient.requestCount()).To(Equal(2))
+	})
+
+	It("fatally logs when --parallelism is less than 1", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--parallelism", "0"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--parallelism must be at least 1."))
+	})
+
+	It("removes headers when not printing to a tty", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1", "source-2"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{
+					"source-1": "app-2",
+					"source-2": "app-1",
+				}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--guid"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaNoHeaders(),
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"source-1  100000  85008  1s",
+			"source-2  100000  85008  11m45s",
+			"",
+		}))
+	})
+
+	It("does not display the Source ID column by default", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(cliConn.cliCommandArgs).To(HaveLen(1))
+		Expect(cliConn.cliCommandArgs[0]).To(HaveLen(2))
+		Expect(cliConn.cliCommandArgs[0][0]).To(Equal("curl"))
+		Expect(cliConn.cliCommandArgs[0][1]).To(Equal("/v3/apps?guids=source-1"))
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source  Source Type  Count   Expired  Cache Duration",
+			"app-1   application  100000  85008    1s",
+			"",
+		}))
+
+		Expect(httpClient.requestCount()).To(Equal(1))
+	})
+
+	It("displays the rate column for each service type", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo(
+				"source-1",
+				"source-2",
+				"source-3",
+			),
+			metaResponseInfoButHigher(
+				"source-1",
+				"source-2",
+				"source-3",
+			),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+			{
+				capiServiceInstancesResponse(map[string]string{"source-3": "service-3"}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--noise"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaNoiseSleepDuration(0),
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Waiting 5 minutes then comparing log output...",
+			"",
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source     Source Type  Count   Expired  Cache Duration  Rate/minute",
+			"app-1      application  100004  85009    1s              1",
+			"service-3  service      100004  85009    11m45s          1",
+			"source-2   platform     100004  85009    11m45s          1",
+			"",
+		}))
+
+		Expect(httpClient.requestCount()).To(Equal(2))
+	})
+
+	It("resolves names for sources that appear during the noise window", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			metaResponseInfoButHigher("source-1", "source-2"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+			{
+				capiAppsResponse(nil),
+			},
+			{
+				capiServiceInstancesResponse(map[string]string{"source-2": "service-2"}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			[]string{"--noise"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaNoiseSleepDuration(0),
+		)
+
+		Expect(tableWriter.String()).To(ContainSubstring("app-1"))
+		Expect(tableWriter.String()).To(ContainSubstring("service-2"))
+		Expect(httpClient.requestCount()).To(Equal(2))
+	})
+
+	It("prints source IDs without app names when CAPI doesn't return info", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1", "source-2"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(cliConn.cliCommandArgs).To(HaveLen(2))
+
+		Expect(cliConn.cliCommandArgs[0]).To(HaveLen(2))
+		Expect(cliConn.cliCommandArgs[0][0]).To(Equal("curl"))
+		uri, err := url.Parse(cliConn.cliCommandArgs[0][1])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(uri.Path).To(Equal("/v3/apps"))
+		guidsParam, ok := uri.Query()["guids"]
+		Expect(ok).To(BeTrue())
+		Expect(len(guidsParam)).To(Equal(1))
+		Expect(strings.Split(guidsParam[0], ",")).To(ConsistOf("source-1", "source-2"))
+
+		Expect(cliConn.cliCommandArgs[1]).To(HaveLen(2))
+		Expect(cliConn.cliCommandArgs[1][0]).To(Equal("curl"))
+		Expect(cliConn.cliCommandArgs[1][1]).To(Equal("/v3/service_instances?guids=source-2"))
+
+		Expect(httpClient.requestCount()).To(Equal(1))
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source    Source Type  Count   Expired  Cache Duration",
+			"app-1     application  100000  85008    1s",
+			"source-2  platform     100000  85008    11m45s",
+			"",
+		}))
+	})
+
+	It("prints meta scoped to apps with guids after names", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo(
+				"deadbeef-dead-dead-dead-deaddeafbeef",
+				"source-2",
+				"026fb323-6884-4978-a45f-da188dbf8ecd",
+			),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{
+					"deadbeef-dead-dead-dead-deaddeafbeef": "app-1",
+				}),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		args := []string{"--source-type", "application"}
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			args,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source  Source Type  Count   Expired  Cache Duration",
+			"app-1   application  100000  85008    1s",
 			"",
 		}))
 	})
@@ -670,22 +1479,113 @@ var _ = Describe("Meta", func() {
 		httpClient.responseBody = []string{
 			metaResponseInfo(
 				"source-1",
-				"source-2",
-				"deadbeef-dead-dead-dead-deaddeafbeef",
+				"source-2",
+				"deadbeef-dead-dead-dead-deaddeafbeef",
+			),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+			{
+				capiServiceInstancesResponse(map[string]string{"source-2": "service-2"}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		args := []string{"--source-type", "service"}
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			args,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source     Source Type  Count   Expired  Cache Duration",
+			"service-2  service      100000  85008    11m45s",
+			"",
+		}))
+	})
+
+	It("prints meta scoped to platform", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo(
+				"source-1",
+				"source-2",
+				"deadbeef-dead-dead-dead-deaddeafbeef",
+			),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		args := []string{"--source-type", "PLATFORM"}
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			args,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source    Source Type  Count   Expired  Cache Duration",
+			"source-2  platform     100000  85008    11m45s",
+			"",
+		}))
+	})
+
+	It("returns unknown when sourceid is guid and not found in CAPI", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo(
+				"source-1",
+				"11111111-1111-1111-1111-111111111111",
 			),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+				capiAppsResponse(nil),
 			},
 			{
-				capiServiceInstancesResponse(map[string]string{"source-2": "service-2"}),
+				capiServiceInstancesResponse(nil),
 			},
 		}
 		cliConn.cliCommandErr = nil
 
-		args := []string{"--source-type", "service"}
+		args := []string{"--source-type", "all"}
 		cf.Meta(
 			context.Background(),
 			cliConn,
@@ -706,24 +1606,24 @@ var _ = Describe("Meta", func() {
 				cliConn.usernameResp,
 			),
 			"",
-			"Source     Source Type  Count   Expired  Cache Duration",
-			"service-2  service      100000  85008    11m45s",
+			"Source                                Source Type  Count   Expired  Cache Duration",
+			"source-1                              platform     100000  85008    1s",
+			"11111111-1111-1111-1111-111111111111  unknown      100000  85008    11m45s",
 			"",
 		}))
 	})
 
-	It("prints meta scoped to platform", func() {
+	It("can filter to uknown", func() {
 		httpClient.responseBody = []string{
 			metaResponseInfo(
 				"source-1",
-				"source-2",
-				"deadbeef-dead-dead-dead-deaddeafbeef",
+				"11111111-1111-1111-1111-111111111111",
 			),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+				capiAppsResponse(nil),
 			},
 			{
 				capiServiceInstancesResponse(nil),
@@ -731,7 +1631,7 @@ var _ = Describe("Meta", func() {
 		}
 		cliConn.cliCommandErr = nil
 
-		args := []string{"--source-type", "PLATFORM"}
+		args := []string{"--source-type", "unknown"}
 		cf.Meta(
 			context.Background(),
 			cliConn,
@@ -741,6 +1641,50 @@ var _ = Describe("Meta", func() {
 			tableWriter,
 		)
 
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source                                Source Type  Count   Expired  Cache Duration",
+			"11111111-1111-1111-1111-111111111111  unknown      100000  85008    11m45s",
+			"",
+		}))
+	})
+
+	It("does not return unknown source id's when all isn't provided", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo(
+				"source-1",
+				"11111111-1111-1111-1111-111111111111",
+			),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(nil),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
 		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
 			fmt.Sprintf(
 				"Retrieving log cache metadata as %s...",
@@ -753,12 +1697,12 @@ var _ = Describe("Meta", func() {
 			),
 			"",
 			"Source    Source Type  Count   Expired  Cache Duration",
-			"source-2  platform     100000  85008    11m45s",
+			"source-1  platform     100000  85008    1s",
 			"",
 		}))
 	})
 
-	It("returns unknown when sourceid is guid and not found in CAPI", func() {
+	It("prints unknown when guid is provided", func() {
 		httpClient.responseBody = []string{
 			metaResponseInfo(
 				"source-1",
@@ -768,101 +1712,281 @@ var _ = Describe("Meta", func() {
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(nil),
-			},
-			{
-				capiServiceInstancesResponse(nil),
+				capiAppsResponse(nil),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		args := []string{"--guid"}
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			args,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source ID                             Count   Expired  Cache Duration",
+			"source-1                              100000  85008    1s",
+			"11111111-1111-1111-1111-111111111111  100000  85008    11m45s",
+			"",
+		}))
+	})
+
+	It("prints meta scoped to platform with source GUIDs", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo(
+				"source-2",
+				"deadbeef-dead-dead-dead-deaddeafbeef",
+			),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(nil),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		args := []string{"--source-type", "PLATFORM", "--guid"}
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			args,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source ID  Count   Expired  Cache Duration",
+			"source-2   100000  85008    1s",
+			"",
+		}))
+	})
+
+	It("annotates duplicate source names with a short GUID suffix", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo(
+				"aaaaaaaa-1111-4aaa-8aaa-aaaaaaaaaaaa",
+				"bbbbbbbb-2222-4bbb-8bbb-bbbbbbbbbbbb",
+			),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{
+					"aaaaaaaa-1111-4aaa-8aaa-aaaaaaaaaaaa": "my-app",
+					"bbbbbbbb-2222-4bbb-8bbb-bbbbbbbbbbbb": "my-app",
+				}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			fmt.Sprintf(
+				"Retrieving log cache metadata as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			fmt.Sprintf(
+				"Retrieving app and service names as %s...",
+				cliConn.usernameResp,
+			),
+			"",
+			"Source             Source Type  Count   Expired  Cache Duration",
+			"my-app (aaaaaaaa)  application  100000  85008    1s",
+			"my-app (bbbbbbbb)  application  100000  85008    11m45s",
+			"",
+		}))
+	})
+
+	It("does not request more than 50 guids at a time", func() {
+		var guids []string
+		for i := 0; i < 51; i++ {
+			guids = append(guids, fmt.Sprintf("source-%d", i))
+		}
+
+		httpClient.responseBody = []string{
+			metaResponseInfo(guids...),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{}),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+			{
+				capiAppsResponse(map[string]string{}),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(cliConn.cliCommandArgs).To(HaveLen(4))
+
+		// batches are issued concurrently, so only assert on the set of
+		// requests, not their order
+		var appBatchSizes, serviceBatchSizes []int
+		for _, args := range cliConn.cliCommandArgs {
+			Expect(args).To(HaveLen(2))
+			Expect(args[0]).To(Equal("curl"))
+			uri, err := url.Parse(args[1])
+			Expect(err).ToNot(HaveOccurred())
+
+			size := len(strings.Split(uri.Query().Get("guids"), ","))
+			switch uri.Path {
+			case "/v3/apps":
+				appBatchSizes = append(appBatchSizes, size)
+			case "/v3/service_instances":
+				serviceBatchSizes = append(serviceBatchSizes, size)
+			default:
+				Fail("unexpected CAPI endpoint: " + uri.Path)
+			}
+		}
+		Expect(appBatchSizes).To(ConsistOf(50, 1))
+		Expect(serviceBatchSizes).To(ConsistOf(50, 1))
+
+		Expect(logger.printfMessages).To(ContainElement("51/51 sources processed"))
+
+		// 51 entries, 2 blank lines, "Retrieving..." preamble and table
+		// header comes to 55 lines.
+		Expect(strings.Split(tableWriter.String(), "\n")).To(HaveLen(57))
+	})
+
+	It("skips CAPI lookups when the name cache holds every source", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			metaResponseInfo("source-1"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
 			},
 		}
 		cliConn.cliCommandErr = nil
 
-		args := []string{"--source-type", "all"}
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			args,
+			nil,
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			fmt.Sprintf(
-				"Retrieving app and service names as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source                                Source Type  Count   Expired  Cache Duration",
-			"source-1                              platform     100000  85008    1s",
-			"11111111-1111-1111-1111-111111111111  unknown      100000  85008    11m45s",
-			"",
-		}))
+		freshCliConn := newStubCliConnection()
+		freshCliConn.usernameResp = "a-user"
+		tableWriter.Reset()
+
+		cf.Meta(
+			context.Background(),
+			freshCliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(freshCliConn.cliCommandArgs).To(BeEmpty())
+		Expect(tableWriter.String()).To(ContainSubstring("app-1"))
 	})
 
-	It("can filter to uknown", func() {
+	It("always consults CAPI with --no-name-cache", func() {
 		httpClient.responseBody = []string{
-			metaResponseInfo(
-				"source-1",
-				"11111111-1111-1111-1111-111111111111",
-			),
+			metaResponseInfo("source-1"),
+			metaResponseInfo("source-1"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(nil),
-			},
-			{
-				capiServiceInstancesResponse(nil),
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
 			},
 		}
 		cliConn.cliCommandErr = nil
 
-		args := []string{"--source-type", "unknown"}
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			args,
+			nil,
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			fmt.Sprintf(
-				"Retrieving app and service names as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source                                Source Type  Count   Expired  Cache Duration",
-			"11111111-1111-1111-1111-111111111111  unknown      100000  85008    11m45s",
-			"",
-		}))
+		freshCliConn := newStubCliConnection()
+		freshCliConn.usernameResp = "a-user"
+		freshCliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+		}
+		freshCliConn.cliCommandErr = nil
+		tableWriter.Reset()
+
+		cf.Meta(
+			context.Background(),
+			freshCliConn,
+			[]string{"--no-name-cache"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(freshCliConn.cliCommandArgs).ToNot(BeEmpty())
+		Expect(tableWriter.String()).To(ContainSubstring("app-1"))
 	})
 
-	It("does not return unknown source id's when all isn't provided", func() {
+	It("pushes --source-type filtering to the gateway when it is supported", func() {
+		httpClient.serverVersion = "2.12.0"
 		httpClient.responseBody = []string{
-			metaResponseInfo(
-				"source-1",
-				"11111111-1111-1111-1111-111111111111",
-			),
+			metaResponseInfo("source-1"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(nil),
-			},
-			{
-				capiServiceInstancesResponse(nil),
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
 			},
 		}
 		cliConn.cliCommandErr = nil
@@ -870,132 +1994,102 @@ var _ = Describe("Meta", func() {
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			nil,
+			[]string{"--source-type", "application"},
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			fmt.Sprintf(
-				"Retrieving app and service names as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source    Source Type  Count   Expired  Cache Duration",
-			"source-1  platform     100000  85008    1s",
-			"",
-		}))
+		Expect(httpClient.requestURLs).To(HaveLen(1))
+		Expect(httpClient.requestURLs[0]).To(ContainSubstring("/api/v1/meta?scope=application"))
+		Expect(tableWriter.String()).To(ContainSubstring("app-1"))
 	})
 
-	It("prints unknown when guid is provided", func() {
+	It("falls back to client-side --source-type filtering on older gateways", func() {
 		httpClient.responseBody = []string{
-			metaResponseInfo(
-				"source-1",
-				"11111111-1111-1111-1111-111111111111",
-			),
+			metaResponseInfo("source-1"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(nil),
-			},
-			{
-				capiServiceInstancesResponse(nil),
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
 			},
 		}
 		cliConn.cliCommandErr = nil
 
-		args := []string{"--guid"}
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			args,
+			[]string{"--source-type", "application"},
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source ID                             Count   Expired  Cache Duration",
-			"source-1                              100000  85008    1s",
-			"11111111-1111-1111-1111-111111111111  100000  85008    11m45s",
-			"",
-		}))
+		Expect(httpClient.requestURLs).To(HaveLen(1))
+		Expect(httpClient.requestURLs[0]).ToNot(ContainSubstring("scope="))
+		Expect(tableWriter.String()).To(ContainSubstring("app-1"))
 	})
 
-	It("prints meta scoped to platform with source GUIDs", func() {
-		httpClient.responseBody = []string{
-			metaResponseInfo(
-				"source-2",
-				"deadbeef-dead-dead-dead-deaddeafbeef",
-			),
-		}
+	It("reads meta from a snapshot file with --from-snapshot", func() {
+		dir, err := ioutil.TempDir("", "")
+		Expect(err).ToNot(HaveOccurred())
+		snapshot := filepath.Join(dir, "meta.json")
+		Expect(ioutil.WriteFile(snapshot, []byte(`{"meta":{"source-1":{
+			"count":100000,
+			"expired":85008,
+			"oldestTimestamp":1519256863100000000,
+			"newestTimestamp":1519256863110000000
+		}}}`), 0600)).To(Succeed())
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(nil),
-			},
-			{
-				capiServiceInstancesResponse(nil),
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
 			},
 		}
 		cliConn.cliCommandErr = nil
 
-		args := []string{"--source-type", "PLATFORM", "--guid"}
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			args,
+			[]string{"--from-snapshot", snapshot},
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
-		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
-			fmt.Sprintf(
-				"Retrieving log cache metadata as %s...",
-				cliConn.usernameResp,
-			),
-			"",
-			"Source ID  Count   Expired  Cache Duration",
-			"source-2   100000  85008    1s",
-			"",
-		}))
+		Expect(httpClient.requestCount()).To(Equal(0))
+		Expect(strings.Split(tableWriter.String(), "\n")).To(ContainElement(
+			"app-1   application  100000  85008    1s",
+		))
 	})
 
-	It("does not request more than 50 guids at a time", func() {
-		var guids []string
-		for i := 0; i < 51; i++ {
-			guids = append(guids, fmt.Sprintf("source-%d", i))
-		}
+	It("fatally logs when --from-snapshot is combined with --noise", func() {
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				[]string{"--from-snapshot", "meta.json", "--noise"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Cannot use --noise with --from-snapshot."))
+	})
+
+	It("uses the LOG_CACHE_ADDR environment variable", func() {
+		_ = os.Setenv("LOG_CACHE_ADDR", "https://different-log-cache:8080")
+		defer func() { _ = os.Unsetenv("LOG_CACHE_ADDR") }()
 
 		httpClient.responseBody = []string{
-			metaResponseInfo(guids...),
+			metaResponseInfo("source-1"),
 		}
 
 		cliConn.cliCommandResult = [][]string{
 			{
-				capiAppsResponse(map[string]string{}),
-			},
-			{
-				capiServiceInstancesResponse(nil),
-			},
-			{
-				capiAppsResponse(map[string]string{}),
-			},
-			{
-				capiServiceInstancesResponse(nil),
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
 			},
 		}
 		cliConn.cliCommandErr = nil
@@ -1009,43 +2103,35 @@ var _ = Describe("Meta", func() {
 			tableWriter,
 		)
 
-		Expect(cliConn.cliCommandArgs).To(HaveLen(4))
-
-		Expect(cliConn.cliCommandArgs[0]).To(HaveLen(2))
-		Expect(cliConn.cliCommandArgs[0][0]).To(Equal("curl"))
-		uri, err := url.Parse(cliConn.cliCommandArgs[0][1])
-		Expect(err).ToNot(HaveOccurred())
-		Expect(uri.Path).To(Equal("/v3/apps"))
-		Expect(strings.Split(uri.Query().Get("guids"), ",")).To(HaveLen(50))
-
-		Expect(cliConn.cliCommandArgs[1]).To(HaveLen(2))
-		Expect(cliConn.cliCommandArgs[1][0]).To(Equal("curl"))
-		uri, err = url.Parse(cliConn.cliCommandArgs[1][1])
+		Expect(httpClient.requestURLs).To(HaveLen(1))
+		u, err := url.Parse(httpClient.requestURLs[0])
 		Expect(err).ToNot(HaveOccurred())
-		Expect(uri.Path).To(Equal("/v3/apps"))
-		Expect(strings.Split(uri.Query().Get("guids"), ",")).To(HaveLen(1))
+		Expect(u.Scheme).To(Equal("https"))
+		Expect(u.Host).To(Equal("different-log-cache:8080"))
+	})
 
-		Expect(cliConn.cliCommandArgs[2]).To(HaveLen(2))
-		Expect(cliConn.cliCommandArgs[2][0]).To(Equal("curl"))
-		uri, err = url.Parse(cliConn.cliCommandArgs[2][1])
-		Expect(err).ToNot(HaveOccurred())
-		Expect(uri.Path).To(Equal("/v2/service_instances"))
-		Expect(strings.Split(uri.Query().Get("guids"), ",")).To(HaveLen(50))
+	It("fatally logs when the Log Cache address is plain HTTP", func() {
+		_ = os.Setenv("LOG_CACHE_ADDR", "http://localhost:8080")
+		defer func() { _ = os.Unsetenv("LOG_CACHE_ADDR") }()
 
-		Expect(cliConn.cliCommandArgs[3]).To(HaveLen(2))
-		Expect(cliConn.cliCommandArgs[3][0]).To(Equal("curl"))
-		uri, err = url.Parse(cliConn.cliCommandArgs[3][1])
-		Expect(err).ToNot(HaveOccurred())
-		Expect(uri.Path).To(Equal("/v2/service_instances"))
-		Expect(strings.Split(uri.Query().Get("guids"), ",")).To(HaveLen(1))
+		Expect(func() {
+			cf.Meta(
+				context.Background(),
+				cliConn,
+				nil,
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
 
-		// 51 entries, 2 blank lines, "Retrieving..." preamble and table
-		// header comes to 55 lines.
-		Expect(strings.Split(tableWriter.String(), "\n")).To(HaveLen(57))
+		Expect(logger.fatalfMessage).To(Equal(
+			"http://localhost:8080 is a plain HTTP address. Pass --insecure-http to send unencrypted traffic.",
+		))
 	})
 
-	It("uses the LOG_CACHE_ADDR environment variable", func() {
-		_ = os.Setenv("LOG_CACHE_ADDR", "https://different-log-cache:8080")
+	It("allows a plain HTTP Log Cache address with --insecure-http", func() {
+		_ = os.Setenv("LOG_CACHE_ADDR", "http://localhost:8080")
 		defer func() { _ = os.Unsetenv("LOG_CACHE_ADDR") }()
 
 		httpClient.responseBody = []string{
@@ -1062,17 +2148,21 @@ var _ = Describe("Meta", func() {
 		cf.Meta(
 			context.Background(),
 			cliConn,
-			nil,
+			[]string{"--insecure-http"},
 			httpClient,
 			logger,
 			tableWriter,
 		)
 
+		Expect(logger.printfMessages).To(ContainElement(
+			"WARNING: Sending unencrypted traffic to http://localhost:8080",
+		))
+
 		Expect(httpClient.requestURLs).To(HaveLen(1))
 		u, err := url.Parse(httpClient.requestURLs[0])
 		Expect(err).ToNot(HaveOccurred())
-		Expect(u.Scheme).To(Equal("https"))
-		Expect(u.Host).To(Equal("different-log-cache:8080"))
+		Expect(u.Scheme).To(Equal("http"))
+		Expect(u.Host).To(Equal("localhost:8080"))
 	})
 
 	It("does not send Authorization header with LOG_CACHE_SKIP_AUTH", func() {
@@ -1102,6 +2192,32 @@ var _ = Describe("Meta", func() {
 		Expect(httpClient.requestHeaders[0]).To(BeEmpty())
 	})
 
+	It("adds headers from WithMetaRequestHeader to each request", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Meta(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithMetaRequestHeader("X-Routing-Instance", "east"),
+		)
+
+		Expect(httpClient.requestHeaders).ToNot(BeEmpty())
+		Expect(httpClient.requestHeaders[0].Get("X-Routing-Instance")).To(Equal("east"))
+	})
+
 	It("fatally logs when it receives too many arguments", func() {
 		Expect(func() {
 			cf.Meta(
@@ -1345,6 +2461,15 @@ func capiAppsResponse(apps map[string]string) string {
 }
 
 func capiServiceInstancesResponse(services map[string]string) string {
+	var resources []string
+	for serviceID, serviceName := range services {
+		resource := fmt.Sprintf(`{"guid": "%s", "name": "%s"}`, serviceID, serviceName)
+		resources = append(resources, resource)
+	}
+	return fmt.Sprintf(`{ "resources": [%s] }`, strings.Join(resources, ","))
+}
+
+func capiV2ServiceInstancesResponse(services map[string]string) string {
 	var resources []string
 	for serviceID, serviceName := range services {
 		resource := fmt.Sprintf(`{"metadata": {"guid": "%s"}, "entity": {"name": "%s"}}`, serviceID, serviceName)
@@ -0,0 +1,76 @@
+package cf
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// nameCacheTTL bounds how long cached GUID to name mappings are trusted
+// before CAPI is consulted again.
+const nameCacheTTL = time.Hour
+
+// nameCache is the on-disk GUID to app/service name mapping used to skip
+// CAPI lookups on repeated meta invocations.
+type nameCache struct {
+	CreatedAt time.Time         `json:"created_at"`
+	Sources   map[string]source `json:"sources"`
+}
+
+func nameCachePath() string {
+	base := os.Getenv("CF_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = home
+	}
+
+	return filepath.Join(base, ".cf", "log-cache-cli", "name_cache.json")
+}
+
+// loadNameCache returns the cached mapping, or an empty cache when the file
+// is missing, unreadable, or older than the TTL.
+func loadNameCache() nameCache {
+	path := nameCachePath()
+	if path == "" {
+		return nameCache{}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nameCache{}
+	}
+
+	var c nameCache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nameCache{}
+	}
+
+	if time.Since(c.CreatedAt) > nameCacheTTL {
+		return nameCache{}
+	}
+
+	return c
+}
+
+func (c nameCache) save() error {
+	path := nameCachePath()
+	if path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,167 @@
+package cf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// otelEndpointEnv is the standard OTLP exporter endpoint variable. When it is
+// set, commands emit a trace describing their execution.
+const otelEndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+// CommandTracer records a span for a command invocation plus a child span per
+// HTTP request, and exports them as OTLP/HTTP JSON when the command finishes.
+type CommandTracer struct {
+	mu sync.Mutex
+
+	endpoint string
+	exporter HTTPClient
+
+	traceID     string
+	commandSpan otelSpan
+	spans       []otelSpan
+}
+
+type otelSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano int64           `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   int64           `json:"endTimeUnixNano,string"`
+	Attributes        []otelAttribute `json:"attributes,omitempty"`
+}
+
+type otelAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func stringAttribute(key, value string) otelAttribute {
+	a := otelAttribute{Key: key}
+	a.Value.StringValue = value
+	return a
+}
+
+// NewCommandTracer returns a tracer for the named command, or nil when
+// OTEL_EXPORTER_OTLP_ENDPOINT is not set.
+func NewCommandTracer(command string, exporter HTTPClient) *CommandTracer {
+	endpoint := os.Getenv(otelEndpointEnv)
+	if endpoint == "" {
+		return nil
+	}
+
+	return &CommandTracer{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		exporter: exporter,
+		traceID:  randomHex(16),
+		commandSpan: otelSpan{
+			SpanID:            randomHex(8),
+			Name:              command,
+			Kind:              1, // SPAN_KIND_INTERNAL
+			StartTimeUnixNano: time.Now().UnixNano(),
+		},
+	}
+}
+
+// Client wraps the given client so every request is recorded as a child span
+// of the command span.
+func (t *CommandTracer) Client(c HTTPClient) HTTPClient {
+	return &tracingHTTPClient{c: c, tracer: t}
+}
+
+// Finish ends the command span and exports the trace. Export failures are
+// deliberately swallowed; tracing must never break the command itself.
+func (t *CommandTracer) Finish() {
+	t.mu.Lock()
+	t.commandSpan.EndTimeUnixNano = time.Now().UnixNano()
+	t.commandSpan.TraceID = t.traceID
+	spans := append([]otelSpan{t.commandSpan}, t.spans...)
+	t.mu.Unlock()
+
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otelAttribute{
+						stringAttribute("service.name", "log-cache-cli"),
+					},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "log-cache-cli"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest("POST", t.endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if resp, err := t.exporter.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (t *CommandTracer) recordRequest(r *http.Request, statusCode int, start, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.spans = append(t.spans, otelSpan{
+		TraceID:           t.traceID,
+		SpanID:            randomHex(8),
+		ParentSpanID:      t.commandSpan.SpanID,
+		Name:              fmt.Sprintf("%s %s", r.Method, r.URL.Path),
+		Kind:              3, // SPAN_KIND_CLIENT
+		StartTimeUnixNano: start.UnixNano(),
+		EndTimeUnixNano:   end.UnixNano(),
+		Attributes: []otelAttribute{
+			stringAttribute("http.method", r.Method),
+			stringAttribute("http.url", r.URL.String()),
+			stringAttribute("http.status_code", fmt.Sprintf("%d", statusCode)),
+		},
+	})
+}
+
+type tracingHTTPClient struct {
+	c      HTTPClient
+	tracer *CommandTracer
+}
+
+func (c *tracingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.c.Do(req)
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	c.tracer.recordRequest(req, statusCode, start, time.Now())
+	return resp, err
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,64 @@
+package cf_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CommandTracer", func() {
+	AfterEach(func() {
+		_ = os.Unsetenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	})
+
+	It("is disabled when OTEL_EXPORTER_OTLP_ENDPOINT is not set", func() {
+		Expect(cf.NewCommandTracer("tail", newStubHTTPClient())).To(BeNil())
+	})
+
+	It("exports a command span with per-request child spans", func() {
+		_ = os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://collector.example.com")
+
+		exporter := newStubHTTPClient()
+		httpClient := newStubHTTPClient()
+
+		tracer := cf.NewCommandTracer("tail", exporter)
+		Expect(tracer).ToNot(BeNil())
+
+		traced := tracer.Client(httpClient)
+		req, err := http.NewRequest("GET", "https://log-cache.example.com/api/v1/meta", nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = traced.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		tracer.Finish()
+
+		Expect(exporter.requestURLs).To(HaveLen(1))
+		Expect(exporter.requestURLs[0]).To(Equal("https://collector.example.com/v1/traces"))
+
+		var payload struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID      string `json:"traceId"`
+						SpanID       string `json:"spanId"`
+						ParentSpanID string `json:"parentSpanId"`
+						Name         string `json:"name"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		Expect(json.Unmarshal([]byte(exporter.requestBodies[0]), &payload)).To(Succeed())
+
+		spans := payload.ResourceSpans[0].ScopeSpans[0].Spans
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("tail"))
+		Expect(spans[1].Name).To(Equal("GET /api/v1/meta"))
+		Expect(spans[1].ParentSpanID).To(Equal(spans[0].SpanID))
+		Expect(spans[1].TraceID).To(Equal(spans[0].TraceID))
+	})
+})
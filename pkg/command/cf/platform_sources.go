@@ -0,0 +1,31 @@
+package cf
+
+// platformSourceNames maps well-known non-GUID platform source IDs to the
+// component names operators actually recognize, so meta and tail output
+// does not have to be cross-referenced against deployment manifests.
+var platformSourceNames = map[string]string{
+	"auctioneer":         "Auctioneer (Diego)",
+	"bbs":                "BBS (Diego)",
+	"cc_uploader":        "CC Uploader (Diego)",
+	"cloud_controller":   "Cloud Controller",
+	"doppler":            "Doppler (Loggregator)",
+	"gorouter":           "Gorouter",
+	"locket":             "Locket (Diego)",
+	"log-cache":          "Log Cache",
+	"metron":             "Metron Agent (Loggregator)",
+	"rep":                "Rep (Diego Cell)",
+	"route_emitter":      "Route Emitter (Diego)",
+	"ssh-proxy":          "SSH Proxy (Diego)",
+	"syslog_agent":       "Syslog Agent (Loggregator)",
+	"traffic_controller": "Traffic Controller (Loggregator)",
+	"uaa":                "UAA",
+}
+
+// friendlySourceName returns the recognizable component name for a
+// well-known platform source, or the source ID unchanged.
+func friendlySourceName(sourceID string) string {
+	if name, ok := platformSourceNames[sourceID]; ok {
+		return name
+	}
+	return sourceID
+}
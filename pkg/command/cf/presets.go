@@ -0,0 +1,131 @@
+package cf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// presets are named argument lists saved by log-cache-save and recalled with
+// --preset, so gnarly runbook one-liners become one word.
+
+func presetsPath() string {
+	base := os.Getenv("CF_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = home
+	}
+
+	return filepath.Join(base, ".cf", "log-cache-cli", "presets.json")
+}
+
+// loadPresets returns the saved argument lists keyed by preset name.
+func loadPresets() (map[string][]string, error) {
+	path := presetsPath()
+	if path == "" {
+		return nil, fmt.Errorf("could not determine the presets path")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var presets map[string][]string
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, err
+	}
+
+	return presets, nil
+}
+
+// SavePreset stores the given arguments under a name for later recall with
+// --preset.
+func SavePreset(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+) {
+	if len(args) < 2 {
+		log.Fatalf("Usage: log-cache-save <name> <flags and arguments>...")
+	}
+
+	name := args[0]
+
+	presets, err := loadPresets()
+	if err != nil || presets == nil {
+		presets = make(map[string][]string)
+	}
+	presets[name] = args[1:]
+
+	path := presetsPath()
+	if path == "" {
+		log.Fatalf("Could not determine the presets path")
+	}
+
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		log.Fatalf("Could not save preset %s: %s", name, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatalf("Could not save preset %s: %s", name, err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		log.Fatalf("Could not save preset %s: %s", name, err)
+	}
+
+	lw := lineWriter{w: w}
+	lw.Write(fmt.Sprintf("Saved preset %s.", name))
+}
+
+// expandPreset replaces --preset <name> with the saved arguments so the rest
+// of flag parsing sees them as if they had been typed out. Arguments given
+// after --preset win over the saved ones.
+func expandPreset(args []string, log Logger) []string {
+	for i, arg := range args {
+		var name string
+		rest := args
+
+		switch {
+		case arg == "--preset":
+			if i+1 >= len(args) {
+				log.Fatalf("--preset requires a name")
+			}
+			name = args[i+1]
+			rest = append(append([]string{}, args[:i]...), args[i+2:]...)
+		case strings.HasPrefix(arg, "--preset="):
+			name = strings.TrimPrefix(arg, "--preset=")
+			rest = append(append([]string{}, args[:i]...), args[i+1:]...)
+		default:
+			continue
+		}
+
+		presets, err := loadPresets()
+		if err != nil {
+			log.Fatalf("Could not read presets: %s", err)
+		}
+
+		saved, ok := presets[name]
+		if !ok {
+			log.Fatalf("Unknown preset: %s", name)
+		}
+
+		return append(append([]string{}, saved...), rest...)
+	}
+
+	return args
+}
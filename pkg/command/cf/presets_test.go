@@ -0,0 +1,160 @@
+package cf_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Presets", func() {
+	var (
+		logger     *stubLogger
+		writer     *stubWriter
+		httpClient *stubHTTPClient
+		cliConn    *stubCliConnection
+		cfHome     string
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		writer = &stubWriter{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+
+		var err error
+		cfHome, err = ioutil.TempDir("", "presets")
+		Expect(err).ToNot(HaveOccurred())
+		os.Setenv("CF_HOME", cfHome)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("CF_HOME")
+	})
+
+	writePresets := func(content string) {
+		dir := filepath.Join(cfHome, ".cf", "log-cache-cli")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(
+			filepath.Join(dir, "presets.json"),
+			[]byte(content),
+			0644,
+		)).To(Succeed())
+	}
+
+	Describe("SavePreset", func() {
+		It("stores the arguments under the given name", func() {
+			cf.SavePreset(
+				context.Background(),
+				cliConn,
+				[]string{"errs", "--filter", "ERR"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			Expect(writer.lines()).To(Equal([]string{"Saved preset errs."}))
+
+			data, err := ioutil.ReadFile(filepath.Join(cfHome, ".cf", "log-cache-cli", "presets.json"))
+			Expect(err).ToNot(HaveOccurred())
+
+			var presets map[string][]string
+			Expect(json.Unmarshal(data, &presets)).To(Succeed())
+			Expect(presets).To(Equal(map[string][]string{
+				"errs": {"--filter", "ERR"},
+			}))
+		})
+
+		It("keeps other presets when saving a new one", func() {
+			writePresets(`{"old": ["--json"]}`)
+
+			cf.SavePreset(
+				context.Background(),
+				cliConn,
+				[]string{"errs", "--filter", "ERR"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			data, err := ioutil.ReadFile(filepath.Join(cfHome, ".cf", "log-cache-cli", "presets.json"))
+			Expect(err).ToNot(HaveOccurred())
+
+			var presets map[string][]string
+			Expect(json.Unmarshal(data, &presets)).To(Succeed())
+			Expect(presets).To(HaveLen(2))
+		})
+
+		It("fatally logs without a name and arguments", func() {
+			Expect(func() {
+				cf.SavePreset(
+					context.Background(),
+					cliConn,
+					[]string{"errs"},
+					httpClient,
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Usage: log-cache-save <name> <flags and arguments>..."))
+		})
+	})
+
+	Describe("--preset", func() {
+		var startTime time.Time
+
+		BeforeEach(func() {
+			startTime = time.Now().Truncate(time.Second).Add(-time.Minute)
+			httpClient.responseBody = []string{responseBody(startTime)}
+			cliConn.cliCommandResult = [][]string{{"app-guid"}}
+		})
+
+		It("inserts the saved arguments into a tail invocation", func() {
+			writePresets(`{"errs": ["--filter", "ERR"]}`)
+
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--preset", "errs"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailNoColor(),
+			)
+
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(
+					"   %s [APP/PROC/WEB/0] ERR log body",
+					startTime.Format("2006-01-02T15:04:05.00-0700"),
+				),
+			}))
+		})
+
+		It("fatally logs for an unknown preset", func() {
+			writePresets(`{}`)
+
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					[]string{"app-name", "--preset", "nope"},
+					httpClient,
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Unknown preset: nope"))
+		})
+	})
+})
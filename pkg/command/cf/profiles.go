@@ -0,0 +1,218 @@
+package cf
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+)
+
+// profile points the CLI at another foundation's Log Cache so the same query
+// can be run against several deployments, e.g. an active-active pair. A
+// profile selected with --target can additionally configure how to
+// authenticate ("token" uses the cf access token and is the default, "none"
+// skips the Authorization header) and a CA certificate for foundations with
+// their own certificate authority.
+type profile struct {
+	Addr   string `json:"addr"`
+	Auth   string `json:"auth,omitempty"`
+	CACert string `json:"ca_cert,omitempty"`
+}
+
+func profilesPath() string {
+	base := os.Getenv("CF_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = home
+	}
+
+	return filepath.Join(base, ".cf", "log-cache-cli", "profiles.json")
+}
+
+// loadProfiles returns the configured profiles keyed by name.
+func loadProfiles() (map[string]profile, error) {
+	path := profilesPath()
+	if path == "" {
+		return nil, fmt.Errorf("could not determine the profiles path")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// resolveTarget returns the profile selected with --target, independent of
+// the currently targeted cf API.
+func resolveTarget(name string, log Logger) profile {
+	profiles, err := loadProfiles()
+	if err != nil {
+		log.Fatalf("Could not load profiles: %s", err)
+	}
+
+	p, ok := profiles[name]
+	if !ok {
+		log.Fatalf("Unknown target: %s", name)
+	}
+
+	if p.Addr == "" {
+		log.Fatalf("Target %s does not configure an addr.", name)
+	}
+
+	return p
+}
+
+// targetHTTPClient returns a client that trusts the target's CA certificate.
+func targetHTTPClient(p profile, log Logger) HTTPClient {
+	pem, err := ioutil.ReadFile(p.CACert)
+	if err != nil {
+		log.Fatalf("Could not read the target's CA certificate: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Fatalf("Could not parse the target's CA certificate: %s", p.CACert)
+	}
+
+	transport := newPooledTransport()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}
+}
+
+// compareProfiles runs an instant query against each named profile and prints
+// a side-by-side table of the samples, one column per foundation.
+func compareProfiles(
+	ctx context.Context,
+	query string,
+	names []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	o queryOptions,
+) {
+	profiles, err := loadProfiles()
+	if err != nil {
+		log.Fatalf("Could not load profiles: %s", err)
+	}
+
+	values := make(map[string]map[string]string)
+	for _, name := range names {
+		p, ok := profiles[name]
+		if !ok {
+			log.Fatalf("Unknown profile: %s", name)
+		}
+
+		checkPlainHTTP(p.Addr, o.insecureHTTP, log)
+		client := logcache.NewClient(p.Addr, logcache.WithHTTPClient(c))
+
+		var options []logcache.PromQLOption
+		if o.timeProvided {
+			options = append(options, logcache.WithPromQLTime(o.time))
+		}
+
+		res, err := client.PromQLRaw(ctx, query, options...)
+		if err != nil {
+			log.Fatalf("Could not process query against profile %s: %s", name, err)
+		}
+
+		if res != nil && res.Status == "error" {
+			log.Fatalf("The PromQL API for profile %s returned an error (%s): %s", name, res.ErrorType, res.Error)
+		}
+
+		samples, err := vectorSamples(res.Data.Result)
+		if err != nil {
+			log.Fatalf("Could not decode results for profile %s: %s", name, err)
+		}
+
+		for metric, value := range samples {
+			if values[metric] == nil {
+				values[metric] = make(map[string]string)
+			}
+			values[metric][name] = value
+		}
+	}
+
+	var metrics []string
+	for metric := range values {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "Metric\t"+strings.Join(names, "\t"))
+	for _, metric := range metrics {
+		row := []string{metric}
+		for _, name := range names {
+			value, ok := values[metric][name]
+			if !ok {
+				value = "-"
+			}
+			row = append(row, value)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatalf("Error writing results")
+	}
+}
+
+// vectorSamples flattens an instant vector into metric to value.
+func vectorSamples(result json.RawMessage) (map[string]string, error) {
+	var series []struct {
+		Metric map[string]string `json:"metric"`
+		Value  []json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(result, &series); err != nil {
+		return nil, err
+	}
+
+	samples := make(map[string]string)
+	for _, s := range series {
+		name := s.Metric["__name__"]
+		if name == "" {
+			name = "value"
+		}
+
+		var labels []string
+		for k, v := range s.Metric {
+			if k == "__name__" {
+				continue
+			}
+			labels = append(labels, fmt.Sprintf("%s=%q", k, v))
+		}
+		sort.Strings(labels)
+
+		metric := name
+		if len(labels) > 0 {
+			metric = fmt.Sprintf("%s{%s}", name, strings.Join(labels, ","))
+		}
+
+		if len(s.Value) != 2 {
+			continue
+		}
+		samples[metric] = strings.Trim(string(s.Value[1]), `"`)
+	}
+
+	return samples, nil
+}
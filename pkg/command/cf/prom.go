@@ -0,0 +1,72 @@
+package cf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// promExposition renders a PromQL result in the Prometheus text exposition
+// format so it can be piped into promtool and friends.
+func promExposition(result json.RawMessage) ([]string, error) {
+	var series []struct {
+		Metric map[string]string   `json:"metric"`
+		Value  []json.RawMessage   `json:"value,omitempty"`
+		Values [][]json.RawMessage `json:"values,omitempty"`
+	}
+	if err := json.Unmarshal(result, &series); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, s := range series {
+		name := s.Metric["__name__"]
+		if name == "" {
+			name = "value"
+		}
+
+		var labels []string
+		for k, v := range s.Metric {
+			if k == "__name__" {
+				continue
+			}
+			// %q escaping matches the exposition format: \\, \", and \n
+			labels = append(labels, fmt.Sprintf("%s=%q", k, v))
+		}
+		sort.Strings(labels)
+
+		metric := name
+		if len(labels) > 0 {
+			metric = fmt.Sprintf("%s{%s}", name, strings.Join(labels, ","))
+		}
+
+		values := s.Values
+		if len(values) == 0 && len(s.Value) == 2 {
+			// instant vector, a single sample per series
+			values = [][]json.RawMessage{s.Value}
+		}
+
+		for _, v := range values {
+			if len(v) != 2 {
+				continue
+			}
+
+			value := strings.Trim(string(v[1]), `"`)
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+
+			ts, err := strconv.ParseFloat(string(v[0]), 64)
+			if err != nil {
+				continue
+			}
+
+			// exposition timestamps are milliseconds
+			lines = append(lines, fmt.Sprintf("%s %s %d", metric, value, int64(ts*1000)))
+		}
+	}
+
+	return lines, nil
+}
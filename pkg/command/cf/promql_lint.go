@@ -0,0 +1,269 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+)
+
+// lintIssue is a local PromQL syntax problem at a 1-based rune position.
+type lintIssue struct {
+	pos int
+	msg string
+}
+
+// selectorRegex pulls `metric{matchers}` selectors out of a query so the
+// names can be checked against what Log Cache actually holds.
+var selectorRegex = regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)?\{([^}]*)\}`)
+
+// sourceIDMatcherRegex finds exact source_id matchers inside a selector.
+var sourceIDMatcherRegex = regexp.MustCompile(`source_id\s*=\s*"([^"]*)"`)
+
+// braceRegex and bracketRegex pull out selector matcher lists and range
+// durations once the brackets are known to balance.
+var (
+	braceRegex   = regexp.MustCompile(`\{([^{}]*)\}`)
+	bracketRegex = regexp.MustCompile(`\[([^\[\]]*)\]`)
+)
+
+// matcherRegex matches one well-formed label matcher, e.g. `job!~"router.*"`.
+var matcherRegex = regexp.MustCompile(
+	`^[a-zA-Z_][a-zA-Z0-9_]*\s*(=~|!~|!=|=)\s*("(\\.|[^"\\])*"|'(\\.|[^'\\])*'|` + "`[^`]*`" + `)$`)
+
+// durationRegex matches one PromQL duration such as 250ms, 30s, or 5m.
+var durationRegex = regexp.MustCompile(`^[0-9]+(ms|[smhdwy])$`)
+
+// emptyGroupingRegex matches a grouping clause with no labels, e.g. `by ()`.
+var emptyGroupingRegex = regexp.MustCompile(`\b(by|without)\s*\(\s*\)`)
+
+// validateQueries lints each query locally for syntax problems and warns
+// about sources and metric names Log Cache has never seen, without sending
+// the query to the PromQL API. Passing the local checks does not guarantee
+// the server's parser will accept the query.
+func validateQueries(ctx context.Context, client *logcache.Client, queries []string, lw lineWriter, log Logger) {
+	meta, err := client.Meta(ctx)
+	if err != nil {
+		fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+	}
+
+	// metric names are looked up once per source and cached across queries
+	namesBySource := make(map[string]map[string]struct{})
+
+	var failed int
+	for _, query := range queries {
+		issues := lintPromQL(query)
+		if len(issues) > 0 {
+			for _, issue := range issues {
+				lw.Write(fmt.Sprintf("%s: syntax error at position %d: %s", query, issue.pos, issue.msg))
+			}
+			failed++
+			continue
+		}
+
+		lw.Write(fmt.Sprintf("%s: passed local checks", query))
+
+		for _, m := range selectorRegex.FindAllStringSubmatch(query, -1) {
+			metric := m[1]
+			for _, sm := range sourceIDMatcherRegex.FindAllStringSubmatch(m[2], -1) {
+				sourceID := sm[1]
+				if _, ok := meta[sourceID]; !ok {
+					lw.Write(fmt.Sprintf("Warning: source %q has no data in Log Cache.", sourceID))
+					continue
+				}
+
+				if metric == "" {
+					continue
+				}
+
+				names, ok := namesBySource[sourceID]
+				if !ok {
+					names = metricNames(ctx, client, sourceID, log)
+					namesBySource[sourceID] = names
+				}
+				if _, ok := names[metric]; !ok {
+					lw.Write(fmt.Sprintf("Warning: metric %q was not found for source %q.", metric, sourceID))
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		fatalf(log, ExitCodeUsage, "%d of %d queries failed validation.", failed, len(queries))
+	}
+}
+
+// metricNames collects the metric names in the source's most recent
+// envelopes, the same data an instant query would evaluate against.
+func metricNames(ctx context.Context, client *logcache.Client, sourceID string, log Logger) map[string]struct{} {
+	envelopes, err := client.Read(
+		ctx,
+		sourceID,
+		time.Unix(0, 0),
+		logcache.WithDescending(),
+		logcache.WithLimit(MaximumBatchSize),
+	)
+	if err != nil {
+		fatalf(log, ExitCodeNetwork, "Failed to read from Log Cache: %s", err)
+	}
+
+	names := make(map[string]struct{})
+	for _, e := range envelopes {
+		if c := e.GetCounter(); c != nil {
+			names[c.GetName()] = struct{}{}
+		}
+		if g := e.GetGauge(); g != nil {
+			for name := range g.GetMetrics() {
+				names[name] = struct{}{}
+			}
+		}
+		if t := e.GetTimer(); t != nil {
+			names[t.GetName()] = struct{}{}
+		}
+	}
+
+	return names
+}
+
+// lintPromQL runs a lightweight local syntax check: strings must terminate,
+// parentheses, braces, and brackets must balance, label matchers must be
+// well formed, grouping clauses must name labels, and range durations must
+// parse. It is not a full PromQL parser — a query can pass every check here
+// and still be rejected by the server — but it catches the mistakes that
+// otherwise come back as an opaque 400.
+func lintPromQL(query string) []lintIssue {
+	type open struct {
+		r   rune
+		pos int
+	}
+
+	pairs := map[rune]rune{')': '(', '}': '{', ']': '['}
+
+	var stack []open
+	var issues []lintIssue
+
+	var quote rune
+	quoteStart := 0
+	escaped := false
+
+	for i, r := range []rune(query) {
+		pos := i + 1
+
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch r {
+		case '"', '\'', '`':
+			quote = r
+			quoteStart = pos
+		case '(', '{', '[':
+			stack = append(stack, open{r: r, pos: pos})
+		case ')', '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1].r != pairs[r] {
+				issues = append(issues, lintIssue{pos: pos, msg: fmt.Sprintf("unexpected %q", string(r))})
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if quote != 0 {
+		issues = append(issues, lintIssue{pos: quoteStart, msg: "unterminated string"})
+	}
+
+	for _, o := range stack {
+		issues = append(issues, lintIssue{pos: o.pos, msg: fmt.Sprintf("unclosed %q", string(o.r))})
+	}
+
+	// the structural checks below slice the query up with regular
+	// expressions, which only works once the brackets are known to balance
+	if len(issues) > 0 {
+		return issues
+	}
+
+	runePos := func(byteOffset int) int {
+		return len([]rune(query[:byteOffset])) + 1
+	}
+
+	for _, m := range emptyGroupingRegex.FindAllStringIndex(query, -1) {
+		issues = append(issues, lintIssue{pos: runePos(m[0]), msg: "empty grouping clause"})
+	}
+
+	for _, m := range braceRegex.FindAllStringSubmatchIndex(query, -1) {
+		offset := m[2]
+		for _, matcher := range splitOutsideQuotes(query[m[2]:m[3]], ',') {
+			trimmed := strings.TrimSpace(matcher)
+			if trimmed != "" && !matcherRegex.MatchString(trimmed) {
+				issues = append(issues, lintIssue{pos: runePos(offset), msg: fmt.Sprintf("invalid label matcher %q", trimmed)})
+			}
+			offset += len(matcher) + 1
+		}
+	}
+
+	for _, m := range bracketRegex.FindAllStringSubmatchIndex(query, -1) {
+		pos := runePos(m[2])
+		content := strings.TrimSpace(query[m[2]:m[3]])
+		if content == "" {
+			issues = append(issues, lintIssue{pos: pos, msg: "empty range"})
+			continue
+		}
+
+		// a subquery may omit its resolution, e.g. `[5m:]`
+		for _, part := range strings.Split(content, ":") {
+			part = strings.TrimSpace(part)
+			if part != "" && !durationRegex.MatchString(part) {
+				issues = append(issues, lintIssue{pos: pos, msg: fmt.Sprintf("invalid duration %q", part)})
+			}
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool { return issues[i].pos < issues[j].pos })
+
+	return issues
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside quoted
+// strings so matcher values may contain commas.
+func splitOutsideQuotes(s string, sep rune) []string {
+	var parts []string
+	var quote rune
+	escaped := false
+	start := 0
+
+	for i, r := range s {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch r {
+		case '"', '\'', '`':
+			quote = r
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
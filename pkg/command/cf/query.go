@@ -1,23 +1,38 @@
 package cf
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"code.cloudfoundry.org/cli/plugin"
+	lc "code.cloudfoundry.org/log-cache-cli/v4/pkg/logcache"
 	logcache "code.cloudfoundry.org/log-cache/pkg/client"
 	flags "github.com/jessevdk/go-flags"
 )
 
+// expensiveQueryPoints is the estimated result size above which a range
+// query asks for confirmation before hitting the gateway.
+const expensiveQueryPoints = 10000
+
 type QueryOption func(*queryOptions)
 
+// WithQueryConfirmInput overrides where the expensive-query prompt reads its
+// answer from, used in tests.
+func WithQueryConfirmInput(r io.Reader) QueryOption {
+	return func(o *queryOptions) {
+		o.confirmInput = r
+	}
+}
+
 func Query(
 	ctx context.Context,
 	cli plugin.CliConnection,
@@ -27,14 +42,13 @@ func Query(
 	w io.Writer,
 	opts ...QueryOption,
 ) {
-	if len(args) < 1 {
-		log.Fatalf("Must specify a PromQL query")
-	}
-	query := args[0]
-
 	queryOptions, err := newQueryOptions(cli, args, log)
 	if err != nil {
-		log.Fatalf("%s", err)
+		fatalf(log, ExitCodeUsage, "%s", err)
+	}
+
+	if len(queryOptions.queries) == 0 {
+		fatalf(log, ExitCodeUsage, "Must specify a PromQL query")
 	}
 
 	for _, opt := range opts {
@@ -43,20 +57,34 @@ func Query(
 
 	lw := lineWriter{w: w}
 
-	if strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) != "true" {
-		c = &tokenHTTPClient{
-			c: c,
-			tokenFunc: func() string {
-				token, err := cli.AccessToken()
-				if err != nil {
-					log.Fatalf("Unable to get Access Token: %s", err)
-				}
-				return token
-			},
-		}
+	if queryOptions.target != nil && queryOptions.target.CACert != "" {
+		c = targetHTTPClient(*queryOptions.target, log)
+	}
+
+	v := newVerboseLogger(log, queryOptions.verbosity)
+	c = newVerboseHTTPClient(c, v)
+
+	skipAuth := strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) == "true" ||
+		(queryOptions.target != nil && queryOptions.target.Auth == "none")
+	if !skipAuth {
+		c = lc.NewTokenClient(c, func() string {
+			token, err := cli.AccessToken()
+			if err != nil {
+				fatalf(log, ExitCodeAuth, "Unable to get Access Token: %s", err)
+			}
+			return token
+		})
+	}
+
+	if len(queryOptions.profiles) > 0 {
+		compareProfiles(ctx, queryOptions.queries[0], queryOptions.profiles, c, log, w, queryOptions)
+		return
 	}
 
 	logCacheAddr := os.Getenv("LOG_CACHE_ADDR")
+	if queryOptions.target != nil {
+		logCacheAddr = queryOptions.target.Addr
+	}
 	if logCacheAddr == "" {
 		hasAPI, err := cli.HasAPIEndpoint()
 		if err != nil {
@@ -75,63 +103,270 @@ func Query(
 		logCacheAddr = strings.Replace(tokenURL, "api", "log-cache", 1)
 	}
 
+	checkPlainHTTP(logCacheAddr, queryOptions.insecureHTTP, log)
+
 	client := logcache.NewClient(logCacheAddr, logcache.WithHTTPClient(c))
 
-	var res *logcache.PromQLQueryResult
+	if queryOptions.versionCheck {
+		checkGatewayCompatibility(ctx, client, log, queryOptions.strictCompat)
+	}
+
+	if queryOptions.validate {
+		validateQueries(ctx, client, queryOptions.queries, lw, log)
+		return
+	}
+
+	if queryOptions.rangeQuery && !queryOptions.force {
+		points := estimateRangePoints(queryOptions) * int64(len(queryOptions.queries))
+		if points > expensiveQueryPoints {
+			lw.Write(fmt.Sprintf(
+				"This range query is estimated to return %d points per series (threshold %d). Run anyway? [y/N]",
+				points,
+				expensiveQueryPoints,
+			))
+
+			answer, _ := bufio.NewReader(queryOptions.confirmInput).ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer != "y" && answer != "yes" {
+				log.Fatalf("Query canceled. Use --force to skip this check.")
+			}
+		}
+	}
+
+	var assertFailures []string
+
+	multi := len(queryOptions.queries) > 1
+	for i, query := range queryOptions.queries {
+		if multi {
+			if i > 0 {
+				lw.Write("")
+			}
+			lw.Write(fmt.Sprintf("=== %s ===", query))
+		}
+
+		var res *logcache.PromQLQueryResult
+
+		queryStart := time.Now()
+
+		if !queryOptions.rangeQuery {
+			var options []logcache.PromQLOption
+
+			if queryOptions.timeProvided {
+				options = append(options, logcache.WithPromQLTime(queryOptions.time))
+			}
+
+			res, err = client.PromQLRaw(
+				ctx,
+				query,
+				options...,
+			)
+		} else {
+			res, err = client.PromQLRangeRaw(
+				ctx,
+				query,
+				logcache.WithPromQLStart(queryOptions.start),
+				logcache.WithPromQLEnd(queryOptions.end),
+				logcache.WithPromQLStep(queryOptions.step),
+			)
+		}
+
+		v.V(1, "Query completed in %s", time.Since(queryStart).Truncate(time.Millisecond))
+
+		if err != nil {
+			lw.Write(fmt.Sprintf("Could not process query: %s", err.Error()))
+			continue
+		}
+
+		if res != nil && res.Status == "error" {
+			lw.Write(fmt.Sprintf("The PromQL API returned an error (%s): %s", res.ErrorType, res.Error))
+			continue
+		}
 
-	if !queryOptions.rangeQuery {
-		var options []logcache.PromQLOption
+		if queryOptions.assertion != nil {
+			offending, err := queryOptions.assertion.check(res.Data.Result)
+			if err != nil {
+				log.Fatalf("Could not evaluate --assert: %s", err)
+			}
+			assertFailures = append(assertFailures, offending...)
+		}
+
+		if queryOptions.recordPath != "" {
+			if err := appendRecord(queryOptions.recordPath, query, res.Data.Result); err != nil {
+				log.Fatalf("Could not append to --record file: %s", err)
+			}
+		}
+
+		if queryOptions.grafanaOutput {
+			frames, err := grafanaFrames(res.Data.Result)
+			if err != nil {
+				log.Fatalf("Could not render Grafana dataframes: %s", err)
+			}
+			lw.Write(frames)
+			continue
+		}
+
+		if queryOptions.tableOutput {
+			if res.Data.ResultType != "vector" {
+				log.Fatalf("Can only use --output table with a vector result, got %s.", res.Data.ResultType)
+			}
+			lines, err := vectorTable(res.Data.Result)
+			if err != nil {
+				log.Fatalf("Could not render table output: %s", err)
+			}
+			for _, line := range lines {
+				lw.Write(line)
+			}
+			continue
+		}
 
-		if queryOptions.timeProvided {
-			options = append(options, logcache.WithPromQLTime(queryOptions.time))
+		if queryOptions.promOutput {
+			lines, err := promExposition(res.Data.Result)
+			if err != nil {
+				log.Fatalf("Could not render Prometheus exposition format: %s", err)
+			}
+			for _, line := range lines {
+				lw.Write(line)
+			}
+			continue
 		}
 
-		res, err = client.PromQLRaw(
-			context.Background(),
-			query,
-			options...,
-		)
-	} else {
-		res, err = client.PromQLRangeRaw(
-			context.Background(),
-			query,
-			logcache.WithPromQLStart(queryOptions.start),
-			logcache.WithPromQLEnd(queryOptions.end),
-			logcache.WithPromQLStep(queryOptions.step),
-		)
+		if queryOptions.sqlitePath != "" {
+			if err := writeSQLite(queryOptions.sqlitePath, res.Data.Result); err != nil {
+				log.Fatalf("Could not write SQLite output: %s", err)
+			}
+			lw.Write(fmt.Sprintf("Wrote query results to %s.", queryOptions.sqlitePath))
+			continue
+		}
+
+		if queryOptions.relativeTimes && res != nil {
+			result, err := relativeRangeResult(res.Data.Result, queryOptions.end)
+			if err != nil {
+				log.Fatalf("Could not render relative timestamps: %s", err)
+			}
+			res.Data.Result = result
+		}
+
+		body, _ := json.Marshal(res)
+		lw.Write(string(body))
 	}
 
-	if err != nil {
-		lw.Write(fmt.Sprintf("Could not process query: %s", err.Error()))
-		return
+	if len(assertFailures) > 0 {
+		for _, line := range assertFailures {
+			lw.Write(line)
+		}
+		log.Fatalf("Assertion '%s' failed for %d samples.", queryOptions.assertion.raw, len(assertFailures))
 	}
+}
 
-	if res != nil && res.Status == "error" {
-		lw.Write(fmt.Sprintf("The PromQL API returned an error (%s): %s", res.ErrorType, res.Error))
-		return
+// relativeRangeResult rewrites the timestamps of a matrix result as offsets
+// from the query's end time, e.g. -14m or -13m30s.
+func relativeRangeResult(result json.RawMessage, end time.Time) (json.RawMessage, error) {
+	var series []struct {
+		Metric map[string]string   `json:"metric"`
+		Values [][]json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(result, &series); err != nil {
+		return nil, err
 	}
 
-	body, _ := json.Marshal(res)
-	lw.Write(string(body))
+	type relativeSeries struct {
+		Metric map[string]string `json:"metric"`
+		Values [][]interface{}   `json:"values"`
+	}
+
+	relative := make([]relativeSeries, 0, len(series))
+	for _, s := range series {
+		rs := relativeSeries{Metric: s.Metric}
+		for _, v := range s.Values {
+			if len(v) != 2 {
+				continue
+			}
+			ts, err := strconv.ParseFloat(string(v[0]), 64)
+			if err != nil {
+				return nil, err
+			}
+			offset := end.Sub(time.Unix(int64(ts), 0))
+			rs.Values = append(rs.Values, []interface{}{formatRelativeTime(offset), v[1]})
+		}
+		relative = append(relative, rs)
+	}
+
+	return json.Marshal(relative)
+}
+
+func formatRelativeTime(offset time.Duration) string {
+	offset = offset.Truncate(time.Second)
+	if offset == 0 {
+		return "0s"
+	}
+
+	sign := "-"
+	if offset < 0 {
+		sign = "+"
+		offset = -offset
+	}
+
+	s := offset.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return sign + s
 }
 
 type queryOptions struct {
-	time         time.Time
-	start        time.Time
-	end          time.Time
-	step         string
-	rangeQuery   bool
-	timeProvided bool
+	queries       []string
+	time          time.Time
+	start         time.Time
+	end           time.Time
+	step          string
+	rangeQuery    bool
+	timeProvided  bool
+	force         bool
+	confirmInput  io.Reader
+	profiles      []string
+	relativeTimes bool
+	versionCheck  bool
+	strictCompat  bool
+	verbosity     int
+	insecureHTTP  bool
+	target        *profile
+	recordPath    string
+	sqlitePath    string
+	promOutput    bool
+	grafanaOutput bool
+	tableOutput   bool
+	validate      bool
+	assertion     *queryAssertion
 }
 
 type queryOptionFlags struct {
-	Time  string `long:"time"`
-	Start string `long:"start"`
-	End   string `long:"end"`
-	Step  string `long:"step"`
+	Queries       []string `short:"q" long:"query"`
+	QueryFile     string   `long:"query-file"`
+	Time          string   `long:"time"`
+	Start         string   `long:"start"`
+	End           string   `long:"end"`
+	Step          string   `long:"step"`
+	RelativeTimes bool     `long:"relative-times"`
+	Profiles      string   `long:"profiles"`
+	Assert        string   `long:"assert"`
+	Force         bool     `long:"force"`
+	Target        string   `long:"target"`
+	Record        string   `long:"record"`
+	Output        string   `long:"output"`
+	Validate      bool     `long:"validate"`
+	VersionCheck  bool     `long:"version-check"`
+	StrictCompat  bool     `long:"strict-compat"`
+	InsecureHTTP  bool     `long:"insecure-http"`
+	Verbose       []bool   `short:"v" long:"verbose"`
 }
 
 func newQueryOptions(cli plugin.CliConnection, args []string, log Logger) (queryOptions, error) {
+	args = expandPreset(args, log)
+
 	opts := queryOptionFlags{}
 
 	args, err := flags.ParseArgs(&opts, args)
@@ -139,10 +374,20 @@ func newQueryOptions(cli plugin.CliConnection, args []string, log Logger) (query
 		return queryOptions{}, err
 	}
 
-	if len(args) != 1 {
+	if len(args) > 1 {
 		return queryOptions{}, fmt.Errorf("Expected 1 argument, got %d.", len(args))
 	}
 
+	queries := append([]string{}, args...)
+	queries = append(queries, opts.Queries...)
+	if opts.QueryFile != "" {
+		fileQueries, err := readQueryFile(opts.QueryFile)
+		if err != nil {
+			return queryOptions{}, fmt.Errorf("Could not read --query-file: %s", err)
+		}
+		queries = append(queries, fileQueries...)
+	}
+
 	if isInstantQuery(opts) && !validInstantQueryArgs(opts) {
 		return queryOptions{}, errors.New("When issuing an instant query, you cannot specify --start, --end, or --step")
 	}
@@ -151,9 +396,72 @@ func newQueryOptions(cli plugin.CliConnection, args []string, log Logger) (query
 		return queryOptions{}, errors.New("When issuing a range query, you must specify all of --start, --end, and --step")
 	}
 
+	if opts.RelativeTimes && !isRangeQuery(opts) {
+		return queryOptions{}, errors.New("Can only use --relative-times with a range query")
+	}
+
+	var profiles []string
+	for _, name := range strings.Split(opts.Profiles, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			profiles = append(profiles, name)
+		}
+	}
+	if len(profiles) > 0 {
+		if isRangeQuery(opts) {
+			return queryOptions{}, errors.New("Can only use --profiles with an instant query")
+		}
+		if len(queries) > 1 {
+			return queryOptions{}, errors.New("Can only use --profiles with a single query")
+		}
+	}
+
+	o := queryOptions{
+		queries:      queries,
+		profiles:     profiles,
+		force:        opts.Force,
+		confirmInput: os.Stdin,
+		recordPath:   opts.Record,
+		validate:     opts.Validate,
+		versionCheck: opts.VersionCheck,
+		strictCompat: opts.StrictCompat,
+		verbosity:    len(opts.Verbose),
+		insecureHTTP: opts.InsecureHTTP,
+	}
+
+	if opts.Output != "" && opts.Output != "prom" && opts.Output != "grafana" && opts.Output != "table" {
+		path := strings.TrimPrefix(opts.Output, "sqlite:")
+		if path == opts.Output || path == "" {
+			return queryOptions{}, errors.New("--output must be 'prom', 'grafana', 'table', or of the form sqlite:<path>")
+		}
+		o.sqlitePath = path
+	}
+	o.promOutput = opts.Output == "prom"
+	o.grafanaOutput = opts.Output == "grafana"
+	o.tableOutput = opts.Output == "table"
+
+	if opts.Target != "" {
+		p := resolveTarget(opts.Target, log)
+		o.target = &p
+	}
+
+	if opts.Assert != "" {
+		o.assertion, err = parseQueryAssertion(opts.Assert)
+		if err != nil {
+			return queryOptions{}, err
+		}
+	}
+
+	if o.grafanaOutput && !isRangeQuery(opts) {
+		return queryOptions{}, errors.New("Can only use --output grafana with a range query")
+	}
+
+	if o.tableOutput && isRangeQuery(opts) {
+		return queryOptions{}, errors.New("Can only use --output table with an instant query")
+	}
+
 	if isInstantQuery(opts) {
 		if opts.Time == "" {
-			return queryOptions{}, nil
+			return o, nil
 		}
 
 		parsedTime, err := getParsedTime(opts.Time)
@@ -161,7 +469,9 @@ func newQueryOptions(cli plugin.CliConnection, args []string, log Logger) (query
 			return queryOptions{}, fmt.Errorf("Couldn't parse --time: %s", err.Error())
 		}
 
-		return queryOptions{timeProvided: true, time: parsedTime}, nil
+		o.timeProvided = true
+		o.time = parsedTime
+		return o, nil
 	}
 
 	if isRangeQuery(opts) {
@@ -174,10 +484,53 @@ func newQueryOptions(cli plugin.CliConnection, args []string, log Logger) (query
 			return queryOptions{}, fmt.Errorf("Couldn't parse --end: %s", err.Error())
 		}
 
-		return queryOptions{start: parsedStart, end: parsedEnd, step: opts.Step, rangeQuery: true}, nil
+		o.start = parsedStart
+		o.end = parsedEnd
+		o.step = opts.Step
+		o.rangeQuery = true
+		o.relativeTimes = opts.RelativeTimes
+		return o, nil
+	}
+
+	return o, nil
+}
+
+// estimateRangePoints approximates how many samples a range query returns
+// per series: one for each step between start and end.
+func estimateRangePoints(o queryOptions) int64 {
+	step, err := parseStep(o.step)
+	if err != nil || step <= 0 {
+		return 0
 	}
 
-	return queryOptions{}, nil
+	return int64(o.end.Sub(o.start)/step) + 1
+}
+
+// parseStep accepts both Prometheus-style durations and plain seconds.
+func parseStep(step string) (time.Duration, error) {
+	if s, err := strconv.Atoi(step); err == nil {
+		return time.Duration(s) * time.Second, nil
+	}
+	return time.ParseDuration(step)
+}
+
+// readQueryFile returns the queries in the given file, one per line. Blank
+// lines and lines starting with '#' are skipped.
+func readQueryFile(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		queries = append(queries, line)
+	}
+	return queries, nil
 }
 
 func getParsedTime(inputTime string) (time.Time, error) {
@@ -2,8 +2,14 @@ package cf_test
 
 import (
 	"context"
+	"encoding/csv"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
 	. "github.com/onsi/ginkgo"
@@ -163,7 +169,534 @@ var _ = Describe("LogCache", func() {
 			)
 		})
 
+		It("evaluates multiple -q queries in labeled sections", func() {
+			json1 := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"1"]}}`
+			json2 := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"2"]}}`
+			tc := setup(json1, 200)
+			tc.httpClient.responseBody = []string{json1, json2}
+
+			tc.query(
+				"-q", `egress{source_id="doppler"}`,
+				"-q", `ingress{source_id="doppler"}`,
+			)
+
+			Expect(tc.httpClient.requestURLs).To(HaveLen(2))
+
+			requestURL, err := url.Parse(tc.httpClient.requestURLs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requestURL.Query().Get("query")).To(Equal(`egress{source_id="doppler"}`))
+
+			requestURL, err = url.Parse(tc.httpClient.requestURLs[1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requestURL.Query().Get("query")).To(Equal(`ingress{source_id="doppler"}`))
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`=== egress{source_id="doppler"} ===`,
+				json1,
+				"",
+				`=== ingress{source_id="doppler"} ===`,
+				json2,
+			}))
+		})
+
+		It("reads queries from a --query-file", func() {
+			json1 := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"1"]}}`
+			json2 := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"2"]}}`
+			tc := setup(json1, 200)
+			tc.httpClient.responseBody = []string{json1, json2}
+
+			dir, err := ioutil.TempDir("", "")
+			Expect(err).ToNot(HaveOccurred())
+			queryFile := filepath.Join(dir, "queries.promql")
+			Expect(ioutil.WriteFile(queryFile, []byte(
+				"# dashboard queries\n\negress{source_id=\"doppler\"}\ningress{source_id=\"doppler\"}\n",
+			), 0600)).To(Succeed())
+
+			tc.query("--query-file", queryFile)
+
+			Expect(tc.httpClient.requestURLs).To(HaveLen(2))
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`=== egress{source_id="doppler"} ===`,
+				json1,
+				"",
+				`=== ingress{source_id="doppler"} ===`,
+				json2,
+			}))
+		})
+
+		It("fatally logs when the --query-file cannot be read", func() {
+			tc := setup("", 200)
+
+			Expect(func() {
+				tc.query("--query-file", "/does/not/exist")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(HavePrefix("Could not read --query-file"))
+		})
+
+		It("compares results across foundations with --profiles", func() {
+			east := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"egress","source_id":"doppler"},"value":[1.234,"2.5"]}]}}`
+			west := `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"egress","source_id":"doppler"},"value":[1.234,"1.5"]},
+				{"metric":{"__name__":"ingress","source_id":"doppler"},"value":[1.234,"7"]}
+			]}}`
+			tc := setup(east, 200)
+			tc.httpClient.responseBody = []string{east, west}
+
+			dir, err := ioutil.TempDir("", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(dir, ".cf", "log-cache-cli"), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(
+				filepath.Join(dir, ".cf", "log-cache-cli", "profiles.json"),
+				[]byte(`{
+					"prod-east": {"addr": "https://log-cache.east.example.com"},
+					"prod-west": {"addr": "https://log-cache.west.example.com"}
+				}`),
+				0600,
+			)).To(Succeed())
+			os.Setenv("CF_HOME", dir)
+			defer os.Unsetenv("CF_HOME")
+
+			tc.query(
+				`egress{source_id="doppler"}`,
+				"--profiles", "prod-east,prod-west",
+			)
+
+			Expect(tc.httpClient.requestURLs).To(HaveLen(2))
+			Expect(tc.httpClient.requestURLs[0]).To(HavePrefix("https://log-cache.east.example.com/api/v1/query"))
+			Expect(tc.httpClient.requestURLs[1]).To(HavePrefix("https://log-cache.west.example.com/api/v1/query"))
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				"Metric                        prod-east  prod-west",
+				`egress{source_id="doppler"}   2.5        1.5`,
+				`ingress{source_id="doppler"}  -          7`,
+			}))
+		})
+
+		It("fatally logs when a --profiles name is not configured", func() {
+			tc := setup("", 200)
+
+			dir, err := ioutil.TempDir("", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(dir, ".cf", "log-cache-cli"), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(
+				filepath.Join(dir, ".cf", "log-cache-cli", "profiles.json"),
+				[]byte(`{"prod-east": {"addr": "https://log-cache.east.example.com"}}`),
+				0600,
+			)).To(Succeed())
+			os.Setenv("CF_HOME", dir)
+			defer os.Unsetenv("CF_HOME")
+
+			Expect(func() {
+				tc.query(`egress{source_id="doppler"}`, "--profiles", "prod-south")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(Equal("Unknown profile: prod-south"))
+		})
+
+		It("runs against the named foundation with --target", func() {
+			json := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"2.5"]}}`
+			tc := setup(json, 200)
+
+			dir, err := ioutil.TempDir("", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(dir, ".cf", "log-cache-cli"), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(
+				filepath.Join(dir, ".cf", "log-cache-cli", "profiles.json"),
+				[]byte(`{"prod-east": {"addr": "https://log-cache.east.example.com", "auth": "none"}}`),
+				0600,
+			)).To(Succeed())
+			os.Setenv("CF_HOME", dir)
+			defer os.Unsetenv("CF_HOME")
+
+			tc.query(`egress{source_id="doppler"}`, "--target", "prod-east")
+
+			Expect(tc.httpClient.requestURLs).To(HaveLen(1))
+			Expect(tc.httpClient.requestURLs[0]).To(HavePrefix("https://log-cache.east.example.com/api/v1/query"))
+			Expect(tc.cliConnection.accessTokenCount).To(Equal(0))
+			Expect(tc.writer.lines()).To(Equal([]string{json}))
+		})
+
+		It("fatally logs when the --target name is not configured", func() {
+			tc := setup("", 200)
+
+			dir, err := ioutil.TempDir("", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(dir, ".cf", "log-cache-cli"), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(
+				filepath.Join(dir, ".cf", "log-cache-cli", "profiles.json"),
+				[]byte(`{"prod-east": {"addr": "https://log-cache.east.example.com"}}`),
+				0600,
+			)).To(Succeed())
+			os.Setenv("CF_HOME", dir)
+			defer os.Unsetenv("CF_HOME")
+
+			Expect(func() {
+				tc.query(`egress{source_id="doppler"}`, "--target", "prod-south")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(Equal("Unknown target: prod-south"))
+		})
+
+		It("gives you an error when --profiles is used with a range query", func() {
+			tc := setup("", 200)
+
+			Expect(func() {
+				tc.query(
+					`egress{source_id="doppler"}`,
+					"--start", "0",
+					"--end", "900",
+					"--step", "15s",
+					"--profiles", "prod-east",
+				)
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(HavePrefix(
+				"Can only use --profiles with an instant query",
+			))
+		})
+
+		It("rejects an --output that is not a known format", func() {
+			tc := setup("", 200)
+
+			Expect(func() {
+				tc.query(`egress{source_id="doppler"}`, "--output", "results.db")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(HavePrefix(
+				"--output must be 'prom', 'grafana', 'table', or of the form sqlite:<path>",
+			))
+		})
+
+		It("passes silently when all samples satisfy --assert", func() {
+			json := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"availability","source_id":"doppler"},"value":[1.234,"0.99"]}]}}`
+			tc := setup(json, 200)
+
+			tc.query(
+				`availability{source_id="doppler"}`,
+				"--assert", "> 0.95",
+			)
+
+			Expect(tc.writer.lines()).To(Equal([]string{json}))
+		})
+
+		It("fails and prints offending samples when --assert is violated", func() {
+			json := `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"availability","source_id":"doppler"},"value":[1.234,"0.5"]},
+				{"metric":{"__name__":"availability","source_id":"router"},"value":[1.234,"0.99"]}
+			]}}`
+			tc := setup(json, 200)
+
+			Expect(func() {
+				tc.query(
+					`availability`,
+					"--assert", "> 0.95",
+				)
+			}).To(Panic())
+
+			Expect(tc.writer.lines()).To(ContainElement(
+				`availability{source_id="doppler"} 0.5`,
+			))
+			Expect(tc.logger.fatalfMessage).To(Equal("Assertion '> 0.95' failed for 1 samples."))
+		})
+
+		It("rejects a malformed --assert", func() {
+			tc := setup("", 200)
+
+			Expect(func() {
+				tc.query(`availability`, "--assert", "0.95")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(HavePrefix(`--assert must be of the form "<op> <value>"`))
+		})
+
+		It("renders range results as Grafana dataframes with --output grafana", func() {
+			json := `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"egress","source_id":"doppler"},"values":[[60,"1"],[75,"2.5"]]}]}}`
+			tc := setup(json, 200)
+
+			tc.query(
+				`egress{source_id="doppler"}`,
+				"--start", "0",
+				"--end", "900",
+				"--step", "15s",
+				"--output", "grafana",
+			)
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`[{"schema":{"name":"egress","fields":[{"name":"time","type":"time"},{"name":"value","type":"number","labels":{"source_id":"doppler"}}]},"data":{"values":[[60000,75000],[1,2.5]]}}]`,
+			}))
+		})
+
+		It("gives you an error when --output grafana is used with an instant query", func() {
+			tc := setup("", 200)
+
+			Expect(func() {
+				tc.query(`egress{source_id="doppler"}`, "--output", "grafana")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(HavePrefix(
+				"Can only use --output grafana with a range query",
+			))
+		})
+
+		It("renders vector results in exposition format with --output prom", func() {
+			json := `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"egress","source_id":"doppler","deployment":"cf"},"value":[1.234,"2.5"]},
+				{"metric":{"__name__":"ingress","source_id":"doppler"},"value":[1.234,"7"]}
+			]}}`
+			tc := setup(json, 200)
+
+			tc.query(
+				`egress{source_id="doppler"}`,
+				"--output", "prom",
+			)
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`egress{deployment="cf",source_id="doppler"} 2.5 1234`,
+				`ingress{source_id="doppler"} 7 1234`,
+			}))
+		})
+
+		It("renders vector results as an aligned table with --output table", func() {
+			json := `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"egress","source_id":"doppler","deployment":"cf"},"value":[1.234,"2.5"]},
+				{"metric":{"__name__":"ingress","source_id":"doppler"},"value":[1.234,"7"]}
+			]}}`
+			tc := setup(json, 200)
+
+			tc.query(
+				`egress{source_id="doppler"}`,
+				"--output", "table",
+			)
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				"__name__  deployment  source_id  value",
+				"egress    cf          doppler    2.5",
+				"ingress               doppler    7",
+			}))
+		})
+
+		It("gives you an error when --output table is used with a range query", func() {
+			tc := setup("", 200)
+
+			Expect(func() {
+				tc.query(
+					`egress{source_id="doppler"}`,
+					"--start", "0",
+					"--end", "900",
+					"--step", "15s",
+					"--output", "table",
+				)
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(HavePrefix(
+				"Can only use --output table with an instant query",
+			))
+		})
+
+		It("fatally logs when --output table gets a non-vector result", func() {
+			json := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"2.5"]}}`
+			tc := setup(json, 200)
+
+			Expect(func() {
+				tc.query(`egress{source_id="doppler"}`, "--output", "table")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(Equal(
+				"Can only use --output table with a vector result, got scalar.",
+			))
+		})
+
+		It("checks queries locally with --validate without evaluating them", func() {
+			tc := setup("", 200)
+			tc.httpClient.responseBody = []string{
+				metaResponseInfo("doppler"),
+				`{"envelopes":{"batch":[{"timestamp":"300100000002","source_id":"doppler","counter":{"name":"egress","total":"5"}}]}}`,
+			}
+
+			tc.query(`egress{source_id="doppler"}`, "--validate")
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`egress{source_id="doppler"}: passed local checks`,
+			}))
+
+			for _, u := range tc.httpClient.requestURLs {
+				Expect(u).ToNot(ContainSubstring("/api/v1/query"))
+			}
+		})
+
+		It("reports syntax errors with their position with --validate", func() {
+			tc := setup(metaResponseInfo("doppler"), 200)
+
+			Expect(func() {
+				tc.query(`egress{source_id="doppler"`, "--validate")
+			}).To(Panic())
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`egress{source_id="doppler": syntax error at position 7: unclosed "{"`,
+			}))
+			Expect(tc.logger.fatalfMessage).To(Equal("1 of 1 queries failed validation."))
+		})
+
+		DescribeTable("flags malformed queries that balance with --validate",
+			func(query, expected string) {
+				tc := setup(metaResponseInfo("doppler"), 200)
+
+				Expect(func() {
+					tc.query(query, "--validate")
+				}).To(Panic())
+
+				Expect(tc.writer.lines()).To(Equal([]string{query + ": " + expected}))
+				Expect(tc.logger.fatalfMessage).To(Equal("1 of 1 queries failed validation."))
+			},
+			Entry("an empty matcher value", `egress{label=}`, `syntax error at position 8: invalid label matcher "label="`),
+			Entry("an empty grouping clause", `sum by() (egress)`, `syntax error at position 5: empty grouping clause`),
+			Entry("a bad range duration", `rate(egress[5z])`, `syntax error at position 13: invalid duration "5z"`),
+		)
+
+		It("warns about sources Log Cache has never seen with --validate", func() {
+			tc := setup(metaResponseInfo("rep"), 200)
+
+			tc.query(`egress{source_id="doppler"}`, "--validate")
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`egress{source_id="doppler"}: passed local checks`,
+				`Warning: source "doppler" has no data in Log Cache.`,
+			}))
+		})
+
+		It("warns about metric names the source has never emitted with --validate", func() {
+			tc := setup("", 200)
+			tc.httpClient.responseBody = []string{
+				metaResponseInfo("doppler"),
+				`{"envelopes":{"batch":[{"timestamp":"300100000002","source_id":"doppler","counter":{"name":"ingress","total":"5"}}]}}`,
+			}
+
+			tc.query(`egress{source_id="doppler"}`, "--validate")
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				`egress{source_id="doppler"}: passed local checks`,
+				`Warning: metric "egress" was not found for source "doppler".`,
+			}))
+		})
+
+		It("writes results into a SQLite file with --output sqlite:<path>", func() {
+			if _, err := exec.LookPath("sqlite3"); err != nil {
+				Skip("the sqlite3 binary is not available")
+			}
+
+			json := `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"egress"},"values":[[1,"2.5"]]}]}}`
+			tc := setup(json, 200)
+
+			dir, err := ioutil.TempDir("", "")
+			Expect(err).ToNot(HaveOccurred())
+			path := filepath.Join(dir, "results.db")
+
+			tc.query(
+				`egress{source_id="doppler"}`,
+				"--start", "0",
+				"--end", "900",
+				"--step", "15s",
+				"--output", "sqlite:"+path,
+			)
+
+			Expect(tc.writer.lines()).To(Equal([]string{
+				fmt.Sprintf("Wrote query results to %s.", path),
+			}))
+			Expect(path).To(BeAnExistingFile())
+		})
+
+		It("appends a timestamped CSV row per sample with --record", func() {
+			json := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"egress","source_id":"doppler"},"value":[1.234,"2.5"]}]}}`
+			tc := setup(json, 200)
+
+			dir, err := ioutil.TempDir("", "record")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "results.csv")
+
+			tc.query(`egress{source_id="doppler"}`, "--record", path)
+
+			Expect(tc.writer.lines()).To(Equal([]string{json}))
+
+			f, err := os.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+
+			records, err := csv.NewReader(f).ReadAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(HaveLen(2))
+			Expect(records[0]).To(Equal([]string{"recorded_at", "query", "labels_json", "ts", "value"}))
+			Expect(records[1][1]).To(Equal(`egress{source_id="doppler"}`))
+			Expect(records[1][2]).To(MatchJSON(`{"__name__":"egress","source_id":"doppler"}`))
+			Expect(records[1][3]).To(Equal("1.234"))
+			Expect(records[1][4]).To(Equal("2.5"))
+		})
+
+		It("appends to an existing --record file without repeating the header", func() {
+			json := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"egress"},"value":[1.234,"2.5"]}]}}`
+			tc := setup(json, 200)
+			tc.httpClient.responseBody = []string{json, json}
+
+			dir, err := ioutil.TempDir("", "record")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "results.csv")
+
+			tc.query(`egress`, "--record", path)
+			tc.query(`egress`, "--record", path)
+
+			f, err := os.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+
+			records, err := csv.NewReader(f).ReadAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(HaveLen(3))
+			Expect(records[0][0]).To(Equal("recorded_at"))
+		})
+
+		It("fatally logs when the --record file cannot be written", func() {
+			json := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"egress"},"value":[1.234,"2.5"]}]}}`
+			tc := setup(json, 200)
+
+			Expect(func() {
+				tc.query(`egress`, "--record", "/does/not/exist/results.csv")
+			}).To(Panic())
+
+			Expect(tc.logger.fatalfMessage).To(HavePrefix("Could not append to --record file:"))
+		})
+
 		Context("when issuing a range query", func() {
+			It("renders timestamps relative to --end with --relative-times", func() {
+				json := `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"egress"},"values":[[60,"1"],[630,"2.5"]]}]}}`
+				tc := setup(json, 200)
+
+				tc.query(
+					`egress{source_id="doppler"}`,
+					"--start", "0",
+					"--end", "900",
+					"--step", "15s",
+					"--relative-times",
+				)
+
+				Expect(tc.writer.lines()).To(Equal([]string{
+					`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"egress"},"values":[["-14m","1"],["-4m30s","2.5"]]}]}}`,
+				}))
+			})
+
+			It("gives you an error when --relative-times is used with an instant query", func() {
+				tc := setup("", 200)
+
+				Expect(func() {
+					tc.query(`egress{source_id="doppler"}`, "--relative-times")
+				}).To(Panic())
+
+				Expect(tc.logger.fatalfMessage).To(HavePrefix(
+					"Can only use --relative-times with a range query",
+				))
+			})
+
 			It("correctly uses the /api/v1/query_range endpoint when the --start, --end, and --step flags are provided", func() {
 				json := `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"egress"},"values":[[1.234,"2.5"]]}]}}`
 				tc := setup(json, 200)
@@ -201,6 +734,7 @@ var _ = Describe("LogCache", func() {
 							"--start", startArg,
 							"--end", endArg,
 							"--step", stepArg,
+							"--force",
 						)
 					}).NotTo(Panic())
 				},
@@ -209,6 +743,61 @@ var _ = Describe("LogCache", func() {
 				Entry("with mixed timestamps", "123456789", "2018-08-23T19:00:00Z", "1m"),
 			)
 
+			It("asks for confirmation before an expensive range query", func() {
+				json := `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+				tc := setup(json, 200)
+
+				tc.queryWithOptions(
+					[]string{
+						`egress{source_id="doppler"}`,
+						"--start", "0",
+						"--end", "9000000",
+						"--step", "1s",
+					},
+					cf.WithQueryConfirmInput(strings.NewReader("y\n")),
+				)
+
+				Expect(tc.writer.lines()).To(Equal([]string{
+					"This range query is estimated to return 9000001 points per series (threshold 10000). Run anyway? [y/N]",
+					json,
+				}))
+				Expect(tc.httpClient.requestURLs).To(HaveLen(1))
+			})
+
+			It("fatally logs when an expensive range query is declined", func() {
+				tc := setup("", 200)
+
+				Expect(func() {
+					tc.queryWithOptions(
+						[]string{
+							`egress{source_id="doppler"}`,
+							"--start", "0",
+							"--end", "9000000",
+							"--step", "1s",
+						},
+						cf.WithQueryConfirmInput(strings.NewReader("n\n")),
+					)
+				}).To(Panic())
+
+				Expect(tc.logger.fatalfMessage).To(Equal("Query canceled. Use --force to skip this check."))
+				Expect(tc.httpClient.requestURLs).To(HaveLen(0))
+			})
+
+			It("skips the confirmation prompt with --force", func() {
+				json := `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+				tc := setup(json, 200)
+
+				tc.query(
+					`egress{source_id="doppler"}`,
+					"--start", "0",
+					"--end", "9000000",
+					"--step", "1s",
+					"--force",
+				)
+
+				Expect(tc.writer.lines()).To(Equal([]string{json}))
+			})
+
 			DescribeTable("with invalid times",
 				func(invalidField, invalidArg string) {
 					tc := setup("", 200)
@@ -275,3 +864,15 @@ func (tc *testContext) query(args ...string) {
 		tc.writer,
 	)
 }
+
+func (tc *testContext) queryWithOptions(args []string, opts ...cf.QueryOption) {
+	cf.Query(
+		context.Background(),
+		tc.cliConnection,
+		args,
+		tc.httpClient,
+		tc.logger,
+		tc.writer,
+		opts...,
+	)
+}
@@ -0,0 +1,98 @@
+package cf
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appendRecord appends one timestamped CSV row per sample in a PromQL result
+// to the file at path, creating it with a header row first. Repeated runs,
+// e.g. from a cron job, build a simple longitudinal dataset without any
+// monitoring stack.
+func appendRecord(path, query string, result json.RawMessage) error {
+	rows, err := recordRows(time.Now(), query, result)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(f)
+	if info.Size() == 0 {
+		if err := cw.Write([]string{"recorded_at", "query", "labels_json", "ts", "value"}); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// recordRows renders a PromQL result as CSV rows, one per sample, each
+// stamped with when the query ran.
+func recordRows(ranAt time.Time, query string, result json.RawMessage) ([][]string, error) {
+	var series []struct {
+		Metric map[string]string   `json:"metric"`
+		Value  []json.RawMessage   `json:"value,omitempty"`
+		Values [][]json.RawMessage `json:"values,omitempty"`
+	}
+	if err := json.Unmarshal(result, &series); err != nil {
+		return nil, err
+	}
+
+	recordedAt := ranAt.Format(time.RFC3339)
+
+	var rows [][]string
+	for _, s := range series {
+		labels, err := json.Marshal(s.Metric)
+		if err != nil {
+			return nil, err
+		}
+
+		values := s.Values
+		if len(values) == 0 && len(s.Value) == 2 {
+			// instant vector, a single sample per series
+			values = [][]json.RawMessage{s.Value}
+		}
+
+		for _, v := range values {
+			if len(v) != 2 {
+				continue
+			}
+
+			value := strings.Trim(string(v[1]), `"`)
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+
+			rows = append(rows, []string{
+				recordedAt,
+				query,
+				string(labels),
+				string(v[0]),
+				value,
+			})
+		}
+	}
+
+	return rows, nil
+}
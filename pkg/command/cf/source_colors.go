@@ -0,0 +1,19 @@
+package cf
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// sourceColorPalette holds the ANSI foreground colors assigned to sources.
+// Red is excluded so source coloring does not collide with filter match
+// highlighting.
+var sourceColorPalette = []int{32, 33, 34, 35, 36, 92, 93, 94, 95, 96}
+
+// colorForSource deterministically picks a color for a source ID so
+// interleaved streams stay visually distinguishable across sessions.
+func colorForSource(sourceID string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(sourceID))
+	return fmt.Sprintf("\x1b[%dm", sourceColorPalette[h.Sum32()%uint32(len(sourceColorPalette))])
+}
@@ -0,0 +1,95 @@
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// sqliteStatements renders a PromQL result as SQL that loads it into a
+// samples(series, labels_json, ts, value) table.
+func sqliteStatements(result json.RawMessage) (string, error) {
+	var series []struct {
+		Metric map[string]string   `json:"metric"`
+		Value  []json.RawMessage   `json:"value,omitempty"`
+		Values [][]json.RawMessage `json:"values,omitempty"`
+	}
+	if err := json.Unmarshal(result, &series); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("CREATE TABLE IF NOT EXISTS samples (series TEXT, labels_json TEXT, ts REAL, value REAL);\n")
+
+	for _, s := range series {
+		labels, err := json.Marshal(s.Metric)
+		if err != nil {
+			return "", err
+		}
+
+		values := s.Values
+		if len(values) == 0 && len(s.Value) == 2 {
+			// instant vector, a single sample per series
+			values = [][]json.RawMessage{s.Value}
+		}
+
+		for _, v := range values {
+			if len(v) != 2 {
+				continue
+			}
+
+			// both fields go into the SQL verbatim, so only pass them
+			// through when they parse as plain numbers
+			ts := strings.Trim(string(v[0]), `"`)
+			if _, err := strconv.ParseFloat(ts, 64); err != nil {
+				continue
+			}
+
+			value := strings.Trim(string(v[1]), `"`)
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+
+			b.WriteString(fmt.Sprintf(
+				"INSERT INTO samples VALUES (%s, %s, %s, %s);\n",
+				sqliteQuote(s.Metric["__name__"]),
+				sqliteQuote(string(labels)),
+				ts,
+				value,
+			))
+		}
+	}
+
+	return b.String(), nil
+}
+
+// writeSQLite loads a PromQL result into a SQLite file at path via the
+// sqlite3 CLI, which keeps the plugin free of cgo SQLite bindings.
+func writeSQLite(path string, result json.RawMessage) error {
+	statements, err := sqliteStatements(result)
+	if err != nil {
+		return err
+	}
+
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return fmt.Errorf("the sqlite3 binary is required for sqlite output: %s", err)
+	}
+
+	cmd := exec.Command(bin, path)
+	cmd.Stdin = strings.NewReader(statements)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func sqliteQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,186 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+	flags "github.com/jessevdk/go-flags"
+)
+
+// statsReadLimit caps how many envelopes each read of the range requests.
+const statsReadLimit = 1000
+
+type StatsOption func(*statsOptionFlags)
+
+// Stats walks a time range for a source and prints a bucketed histogram of
+// envelope counts split by envelope type, making log bursts easy to locate.
+func Stats(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	sopts ...StatsOption,
+) {
+	opts := getStatsOptions(args, log, sopts...)
+	v := newVerboseLogger(log, len(opts.Verbose))
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+
+	sourceID, _ := getGUID(opts.source, cli, log)
+	if sourceID == "" {
+		// fall back to provided name
+		sourceID = opts.source
+	}
+
+	buckets := make(map[int64]envelopeTypeCounts)
+	readStart := time.Now()
+	var total, pages int
+	start := opts.start
+	for start.Before(opts.end) {
+		envelopes, err := client.Read(
+			ctx,
+			sourceID,
+			start,
+			logcache.WithEndTime(opts.end),
+			logcache.WithLimit(statsReadLimit),
+		)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read envelopes: %s", err)
+		}
+
+		if len(envelopes) == 0 {
+			break
+		}
+		total += len(envelopes)
+		pages++
+
+		for _, e := range envelopes {
+			bucket := e.GetTimestamp() - e.GetTimestamp()%int64(opts.bucket)
+			counts := buckets[bucket]
+			switch e.Message.(type) {
+			case *loggregator_v2.Envelope_Log:
+				counts.Logs++
+			case *loggregator_v2.Envelope_Counter:
+				counts.Counters++
+			case *loggregator_v2.Envelope_Gauge:
+				counts.Gauges++
+			case *loggregator_v2.Envelope_Timer:
+				counts.Timers++
+			case *loggregator_v2.Envelope_Event:
+				counts.Events++
+			}
+			buckets[bucket] = counts
+		}
+
+		next := time.Unix(0, envelopes[len(envelopes)-1].GetTimestamp()+1)
+		if !next.After(start) {
+			break
+		}
+		start = next
+	}
+
+	v.V(1, "Read %d envelopes in %s (%d pages, %s to %s)",
+		total,
+		time.Since(readStart).Truncate(time.Millisecond),
+		pages,
+		opts.start.UTC().Format(time.RFC3339),
+		opts.end.UTC().Format(time.RFC3339),
+	)
+
+	var keys []int64
+	for bucket := range buckets {
+		keys = append(keys, bucket)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "Bucket\tTotal\tLogs\tCounters\tGauges\tTimers\tEvents")
+	for _, bucket := range keys {
+		counts := buckets[bucket]
+		total := counts.Logs + counts.Counters + counts.Gauges + counts.Timers + counts.Events
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			time.Unix(0, bucket).UTC().Format(time.RFC3339),
+			total,
+			counts.Logs,
+			counts.Counters,
+			counts.Gauges,
+			counts.Timers,
+			counts.Events,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatalf("Error writing results")
+	}
+}
+
+type statsOptionFlags struct {
+	StartTime    string `long:"start-time"`
+	EndTime      string `long:"end-time"`
+	Bucket       string `long:"bucket" default:"1m"`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Verbose      []bool `short:"v" long:"verbose"`
+
+	source string
+	start  time.Time
+	end    time.Time
+	bucket time.Duration
+}
+
+func getStatsOptions(args []string, log Logger, sopts ...StatsOption) statsOptionFlags {
+	var opts statsOptionFlags
+
+	for _, o := range sopts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	if len(args) != 1 {
+		log.Fatalf("Expected 1 argument, got %d.", len(args))
+	}
+	opts.source = args[0]
+
+	opts.bucket, err = time.ParseDuration(opts.Bucket)
+	if err != nil {
+		log.Fatalf("--bucket must be a duration, e.g. '1m': %s", err)
+	}
+
+	if opts.bucket < time.Second {
+		log.Fatalf("--bucket must be at least 1 second.")
+	}
+
+	startTime := int64(0)
+	if opts.StartTime != "" {
+		startTime, err = parseTimeFlag(opts.StartTime, time.Now())
+		if err != nil {
+			log.Fatalf("Couldn't parse --start-time: %s", err)
+		}
+	}
+
+	endTime := time.Now().UnixNano()
+	if opts.EndTime != "" {
+		endTime, err = parseTimeFlag(opts.EndTime, time.Now())
+		if err != nil {
+			log.Fatalf("Couldn't parse --end-time: %s", err)
+		}
+	}
+
+	opts.start = time.Unix(0, startTime)
+	opts.end = time.Unix(0, endTime)
+	if !opts.start.Before(opts.end) {
+		log.Fatalf("--start-time must be before --end-time.")
+	}
+
+	return opts
+}
@@ -0,0 +1,112 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stats", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+		cliConn.usernameResp = "a-user"
+		tableWriter = bytes.NewBuffer(nil)
+	})
+
+	It("prints a bucketed histogram of envelope counts by type", func() {
+		httpClient.responseBody = []string{
+			`{"envelopes":{"batch":[
+				{"timestamp":"10000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}},
+				{"timestamp":"20000000000","source_id":"app-guid","counter":{"name":"requests","total":"5"}},
+				{"timestamp":"70000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}}
+			]}}`,
+			`{"envelopes":{"batch":[]}}`,
+		}
+
+		cf.Stats(
+			context.Background(),
+			cliConn,
+			[]string{"--start-time", "0", "--end-time", "180000000000", "app-name"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Bucket                Total  Logs  Counters  Gauges  Timers  Events",
+			"1970-01-01T00:00:00Z  2      1     1         0       0       0",
+			"1970-01-01T00:01:00Z  1      1     0         0       0       0",
+			"",
+		}))
+	})
+
+	It("respects the --bucket width", func() {
+		httpClient.responseBody = []string{
+			`{"envelopes":{"batch":[
+				{"timestamp":"10000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}},
+				{"timestamp":"70000000000","source_id":"app-guid","log":{"payload":"bG9nIGJvZHk="}}
+			]}}`,
+			`{"envelopes":{"batch":[]}}`,
+		}
+
+		cf.Stats(
+			context.Background(),
+			cliConn,
+			[]string{"--start-time", "0", "--end-time", "180000000000", "--bucket", "3m", "app-name"},
+			httpClient,
+			logger,
+			tableWriter,
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Bucket                Total  Logs  Counters  Gauges  Timers  Events",
+			"1970-01-01T00:00:00Z  2      2     0         0       0       0",
+			"",
+		}))
+	})
+
+	It("fatally logs when the bucket is not a duration", func() {
+		Expect(func() {
+			cf.Stats(
+				context.Background(),
+				cliConn,
+				[]string{"--bucket", "bogus", "app-name"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(HavePrefix("--bucket must be a duration"))
+	})
+
+	It("fatally logs when no source is provided", func() {
+		Expect(func() {
+			cf.Stats(
+				context.Background(),
+				cliConn,
+				nil,
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Expected 1 argument, got 0."))
+	})
+})
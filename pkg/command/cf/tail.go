@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -15,15 +18,17 @@ import (
 
 	"code.cloudfoundry.org/cli/plugin"
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	lc "code.cloudfoundry.org/log-cache-cli/v4/pkg/logcache"
 	logcache "code.cloudfoundry.org/log-cache/pkg/client"
 	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
 	"github.com/blang/semver"
+	"github.com/golang/protobuf/jsonpb"
 	flags "github.com/jessevdk/go-flags"
 )
 
-const (
-	timeFormat = "2006-01-02T15:04:05.00-0700"
-)
+// timeFormat is the timestamp layout for pretty output. It can be overridden
+// per user with timestamp_format in ~/.config/log-cache-cli/config.yml.
+var timeFormat = "2006-01-02T15:04:05.00-0700"
 
 // Command is the interface to implement plugin commands
 type Command func(ctx context.Context, cli plugin.CliConnection, args []string, c HTTPClient, log Logger, w io.Writer)
@@ -47,6 +52,44 @@ func WithTailNoHeaders() TailOption {
 	}
 }
 
+func WithTailNoColor() TailOption {
+	return func(o *tailOptions) {
+		o.noColor = true
+	}
+}
+
+// WithTailInteractiveInput overrides where --interactive reads key presses
+// from, used in tests.
+func WithTailInteractiveInput(r io.Reader) TailOption {
+	return func(o *tailOptions) {
+		o.interactiveInput = r
+	}
+}
+
+// WithTailTerminalWidth sets the detected terminal width used by --wrap and
+// --no-wrap.
+func WithTailTerminalWidth(width int) TailOption {
+	return func(o *tailOptions) {
+		o.terminalWidth = width
+	}
+}
+
+// WithTailRetries retries a failed initial read up to n more times before
+// giving up. It has no effect on the --follow stream, which already retries.
+func WithTailRetries(n int) TailOption {
+	return func(o *tailOptions) {
+		o.retries = n
+	}
+}
+
+// WithTailRequestHeader adds a header to every request the command sends,
+// e.g. for routing or tracing in front of the gateway.
+func WithTailRequestHeader(name, value string) TailOption {
+	return func(o *tailOptions) {
+		o.requestHeaders = append(o.requestHeaders, [2]string{name, value})
+	}
+}
+
 // Tail will fetch the logs for a given application guid and write them to
 // stdout.
 func Tail(
@@ -60,15 +103,58 @@ func Tail(
 ) {
 	o, err := newTailOptions(cli, args, log)
 	if err != nil {
-		log.Fatalf("%s", err)
+		fatalf(log, ExitCodeUsage, "%s", err)
 	}
 
 	for _, opt := range opts {
 		opt(&o)
 	}
 
+	if o.quietErrors != "" {
+		f, err := os.OpenFile(o.quietErrors, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Could not open --quiet-errors file: %s", err)
+		}
+		defer f.Close()
+		log = quietLogger{Logger: log, f: f}
+	}
+
+	if len(o.sources) == 0 && anyGlob(o.providedNames) {
+		o.sources = expandGlobSources(ctx, cli, o, c, log)
+		var names []string
+		for _, s := range o.sources {
+			names = append(names, s.name)
+		}
+		o.providedNames = names
+	}
+
+	if len(o.sources) > 0 || len(o.providedNames) > 1 {
+		// these flags hook into the single-source emit pipeline and have no
+		// multi-source equivalent yet; refuse them rather than drop them
+		unsupported := []struct {
+			flag string
+			set  bool
+		}{
+			{"--audit", o.audit},
+			{"--counter-rates", o.counterRates},
+			{"--dedup", o.dedup},
+			{"--forward", o.forwardAddr != ""},
+			{"--interactive", o.interactive},
+			{"--wrap", o.wrap},
+			{"--no-wrap", o.noWrap},
+		}
+		for _, u := range unsupported {
+			if u.set {
+				fatalf(log, ExitCodeUsage, "Cannot use %s when tailing multiple sources.", u.flag)
+			}
+		}
+
+		multiTail(ctx, cli, o, c, log, w)
+		return
+	}
+
 	sourceID := o.guid
-	formatter := newFormatter(o.providedName, o.follow, formatterKindFromOptions(o), log, o.outputTemplate, o.newLineReplacer)
+	formatter := newFormatter(o.providedName, o.follow, formatterKindFromOptions(o), log, o.outputTemplate, o.newLineReplacer, o.prettyJSON, o.fields, o.showTags, o.promqlNames)
 	lw := lineWriter{w: w}
 
 	defer func() {
@@ -78,6 +164,9 @@ func Tail(
 	}()
 
 	logCacheAddr := os.Getenv("LOG_CACHE_ADDR")
+	if len(o.endpoints) > 0 {
+		logCacheAddr = o.endpoints[0]
+	}
 	if logCacheAddr == "" {
 		hasAPI, err := cli.HasAPIEndpoint()
 		if err != nil {
@@ -128,60 +217,207 @@ func Tail(
 		}
 	}
 
+	checkPlainHTTP(logCacheAddr, o.insecureHTTP, log)
+	if len(o.endpoints) > 1 {
+		for _, addr := range o.endpoints[1:] {
+			checkPlainHTTP(addr, o.insecureHTTP, log)
+		}
+	}
+
+	forward := func(e *loggregator_v2.Envelope) {}
+	if o.forwardAddr != "" {
+		conn, err := net.Dial(o.forwardNetwork, o.forwardAddr)
+		if err != nil {
+			log.Fatalf("Failed to connect to forward address: %s", err)
+		}
+		defer conn.Close()
+
+		marshaler := jsonpb.Marshaler{}
+		forward = func(e *loggregator_v2.Envelope) {
+			line, err := marshaler.MarshalToString(e)
+			if err != nil {
+				log.Fatalf("Failed to marshal envelope: %s", err)
+			}
+			if _, err := fmt.Fprintln(conn, line); err != nil {
+				log.Fatalf("Failed to forward envelope: %s", err)
+			}
+		}
+	}
+
+	var audit *envelopeAudit
+	if o.audit {
+		audit = newEnvelopeAudit()
+	}
+
+	var rates *counterRateTracker
+	if o.counterRates {
+		rates = newCounterRateTracker()
+	}
+
+	var interactive *interactiveTail
+	writeLine := lw.Write
+	if o.interactive {
+		interactive = newInteractiveTail(w)
+		writeLine = func(line string) error {
+			interactive.write(line)
+			return nil
+		}
+		go interactive.handleInput(o.interactiveInput)
+	}
+
+	if o.wrap || o.noWrap {
+		inner := writeLine
+		writeLine = func(line string) error {
+			for _, l := range fitLines(line, o.terminalWidth, o.wrapIndent, o.noWrap) {
+				if err := inner(l); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	var dedup *deduper
+	if o.dedup {
+		dedup = newDeduper(writeLine, o.dedupeWindow)
+		defer func() {
+			dedup.flush()
+			for _, line := range dedup.summary() {
+				log.Printf("%s", line)
+			}
+		}()
+	}
+
+	emit := func(e *loggregator_v2.Envelope, formatted string) {
+		if dedup != nil {
+			dedup.observe(e.GetSourceId(), dedupKey(e), formatted)
+			return
+		}
+		writeLine(formatted)
+	}
+
+	loss := newLossMonitor(log)
+
 	filterAndFormat := func(e *loggregator_v2.Envelope) (string, bool) {
+		forward(e)
+
+		if audit != nil {
+			audit.observe(e.GetSourceId(), e.GetTimestamp())
+		}
+
+		loss.observe(e)
+
 		if !typeFilter(e, o) {
 			return "", false
 		}
 
-		return formatter.formatEnvelope(e)
+		if !tagFilter(e, o) {
+			return "", false
+		}
+
+		formatted, ok := formatter.formatEnvelope(e)
+		if !ok {
+			return "", false
+		}
+
+		if rates != nil {
+			if suffix, ok := rates.observe(e); ok {
+				formatted += suffix
+			}
+		}
+
+		if o.filterPattern != nil {
+			if !o.filterPattern.MatchString(formatted) {
+				return "", false
+			}
+
+			if !o.noColor {
+				formatted = o.filterPattern.ReplaceAllString(formatted, "\x1b[1;31m${0}\x1b[0m")
+			}
+		}
+
+		if o.sourceColors && !o.noColor {
+			formatted = colorForSource(e.GetSourceId()) + formatted + "\x1b[0m"
+		}
+
+		if !o.noColor {
+			formatted = highlightEvent(e, formatted)
+		}
+
+		return formatted, true
 	}
 
-	tokenClient := &tokenHTTPClient{
-		c:         c,
-		tokenFunc: func() string { return "" },
+	v := newVerboseLogger(log, o.verbosity)
+
+	if o.target != nil && o.target.CACert != "" {
+		c = targetHTTPClient(*o.target, log)
 	}
 
-	if strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) != "true" {
-		tokenClient.tokenFunc = func() string {
+	if len(o.requestHeaders) > 0 {
+		c = &headerHTTPClient{c: c, headers: o.requestHeaders}
+	}
+
+	tokenFunc := func() string { return "" }
+
+	skipAuth := strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) == "true" ||
+		(o.target != nil && o.target.Auth == "none")
+	if !skipAuth {
+		tokenFunc = func() string {
 			token, err := cli.AccessToken()
 			if err != nil {
-				log.Fatalf("Unable to get Access Token: %s", err)
+				fatalf(log, ExitCodeAuth, "Unable to get Access Token: %s", err)
 			}
 			return token
 		}
 	}
 
+	tokenClient := lc.NewTokenClient(newVerboseHTTPClient(c, v), tokenFunc)
+
 	client := logcache.NewClient(logCacheAddr, logcache.WithHTTPClient(tokenClient))
 
-	checkFeatureVersioning(client, ctx, log, o.nameFilter)
+	reader := logcache.Reader(client.Read)
+	if len(o.endpoints) > 1 {
+		reader = newFallbackReader(o.endpoints, tokenClient, log).Read
+	}
 
 	if sourceID == "" {
 		// fall back to provided name
 		sourceID = o.providedName
 	}
 
+	if o.dryRun {
+		lw.Write(fmt.Sprintf("Source ID: %s", sourceID))
+		lw.Write(readURL(logCacheAddr, sourceID, o))
+		return
+	}
+
+	if o.versionCheck {
+		checkGatewayCompatibility(ctx, client, log, o.strictCompat)
+	}
+
+	checkFeatureVersioning(client, ctx, log, o.nameFilter)
+
 	walkStartTime := time.Now().Add(-5 * time.Second).UnixNano()
 	if o.lines > 0 {
-		envelopes, err := client.Read(
-			context.Background(),
-			sourceID,
-			o.startTime,
-			logcache.WithEndTime(o.endTime),
-			logcache.WithEnvelopeTypes(o.envelopeType),
-			logcache.WithLimit(o.lines),
-			logcache.WithDescending(),
-			logcache.WithNameFilter(o.nameFilter),
-		)
-
+		readStart := time.Now()
+		envelopes, pages, err := readLinesPaged(ctx, reader, sourceID, o, v)
 		if err != nil && !o.follow {
-			log.Fatalf("%s", err)
+			fatalf(log, ExitCodeNetwork, "%s", err)
 		}
 
+		v.V(1, "Received %d envelopes in %s (%d pages, %s to %s)",
+			len(envelopes),
+			time.Since(readStart).Truncate(time.Millisecond),
+			pages,
+			o.startTime.UTC().Format(time.RFC3339),
+			o.endTime.UTC().Format(time.RFC3339),
+		)
+
 		// we get envelopes in descending order but want to print them ascending
 		for i := len(envelopes) - 1; i >= 0; i-- {
 			walkStartTime = envelopes[i].Timestamp + 1
 			if formatted, ok := filterAndFormat(envelopes[i]); ok {
-				lw.Write(formatted)
+				emit(envelopes[i], formatted)
 			}
 		}
 	}
@@ -193,18 +429,109 @@ func Tail(
 			logcache.Visitor(func(envelopes []*loggregator_v2.Envelope) bool {
 				for _, e := range envelopes {
 					if formatted, ok := filterAndFormat(e); ok {
-						lw.Write(formatted)
+						emit(e, formatted)
 					}
 				}
-				return true
+				return interactive == nil || !interactive.done()
 			}),
-			client.Read,
+			reader,
 			logcache.WithWalkStartTime(time.Unix(0, walkStartTime)),
 			logcache.WithWalkEnvelopeTypes(o.envelopeType),
 			logcache.WithWalkBackoff(logcache.NewAlwaysRetryBackoff(250*time.Millisecond)),
 			logcache.WithWalkNameFilter(o.nameFilter),
 		)
 	}
+
+	if audit != nil {
+		lw.Write("")
+		for _, line := range audit.report() {
+			lw.Write(line)
+		}
+	}
+}
+
+// readURL renders the request a read would perform, so --dry-run can hand
+// the flag-to-URL translation to curl-based automation.
+func readURL(logCacheAddr, sourceID string, o tailOptions) string {
+	q := url.Values{}
+	q.Set("start_time", strconv.FormatInt(o.startTime.UnixNano(), 10))
+	q.Set("end_time", strconv.FormatInt(o.endTime.UnixNano(), 10))
+	q.Set("limit", strconv.Itoa(o.lines))
+	q.Set("descending", "true")
+	if o.envelopeType != logcache_v1.EnvelopeType_ANY {
+		q.Set("envelope_types", o.envelopeType.String())
+	}
+	if o.nameFilter != "" {
+		q.Set("name_filter", o.nameFilter)
+	}
+
+	return fmt.Sprintf("%s/api/v1/read/%s?%s", logCacheAddr, sourceID, q.Encode())
+}
+
+// readLinesPaged reads the most recent o.lines envelopes for a source in
+// descending order. Limits beyond a single batch are paged transparently,
+// walking backwards from the end time with the oldest timestamp of each page
+// as the next end, so large limits do not need shell-side pagination.
+// Envelopes returned by two adjacent pages are de-duplicated.
+func readLinesPaged(ctx context.Context, reader logcache.Reader, sourceID string, o tailOptions, v verboseLogger) ([]*loggregator_v2.Envelope, int, error) {
+	read := func(end time.Time, limit int) ([]*loggregator_v2.Envelope, error) {
+		return reader(
+			ctx,
+			sourceID,
+			o.startTime,
+			logcache.WithEndTime(end),
+			logcache.WithEnvelopeTypes(o.envelopeType),
+			logcache.WithLimit(limit),
+			logcache.WithDescending(),
+			logcache.WithNameFilter(o.nameFilter),
+		)
+	}
+
+	var envelopes []*loggregator_v2.Envelope
+	var pages int
+	end := o.endTime
+	for remaining := o.lines; remaining > 0; {
+		limit := remaining
+		if limit > MaximumBatchSize {
+			limit = MaximumBatchSize
+		}
+
+		page, err := read(end, limit)
+		for attempt := 0; attempt < o.retries && err != nil; attempt++ {
+			v.V(1, "Read failed (%s), retrying", err)
+			page, err = read(end, limit)
+		}
+		if err != nil {
+			return envelopes, pages, err
+		}
+		pages++
+		if len(page) == 0 {
+			break
+		}
+
+		// drop boundary envelopes the previous page already returned
+		pageSize := len(page)
+		if len(envelopes) > 0 {
+			oldest := envelopes[len(envelopes)-1].Timestamp
+			for len(page) > 0 && page[0].Timestamp >= oldest {
+				page = page[1:]
+			}
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		envelopes = append(envelopes, page...)
+		remaining -= len(page)
+		end = time.Unix(0, page[len(page)-1].Timestamp+1)
+
+		if pageSize < limit {
+			// the cache has no older envelopes
+			break
+		}
+	}
+
+	return envelopes, pages, nil
 }
 
 type lineWriter struct {
@@ -236,32 +563,113 @@ type tailOptions struct {
 	guid                 string
 	isService            bool
 	providedName         string
+	providedNames        []string
 	outputTemplate       *template.Template
 	jsonOutput           bool
 	tokenRefreshInterval time.Duration
 
-	nameFilter string
+	nameFilter    string
+	filterPattern *regexp.Regexp
+
+	forwardNetwork string
+	forwardAddr    string
+
+	versionCheck bool
+	strictCompat bool
+	verbosity    int
+	insecureHTTP bool
+	audit        bool
+
+	interactive      bool
+	interactiveInput io.Reader
+	sourceColors     bool
+	counterRates     bool
+	dedup            bool
+	dedupeWindow     int
+	quietErrors      string
+	dryRun           bool
+	rawSourceNames   bool
+	jsonLines        bool
+	target           *profile
+	sources          []sourceStream
+	endpoints        []string
+	retries          int
+	requestHeaders   [][2]string
+
+	wrap          bool
+	noWrap        bool
+	wrapIndent    int
+	terminalWidth int
 
 	noHeaders       bool
+	noColor         bool
 	newLineReplacer rune
+	prettyJSON      bool
+	fields          []string
+	tagFilters      map[string]string
+	showTags        bool
+	promqlNames     bool
 }
 
 type tailOptionFlags struct {
-	StartTime     int64  `long:"start-time"`
-	EndTime       int64  `long:"end-time"`
-	EnvelopeType  string `long:"envelope-type" short:"t"`
-	Lines         uint   `long:"lines" short:"n" default:"10"`
-	Follow        bool   `long:"follow" short:"f"`
-	OutputFormat  string `long:"output-format" short:"o"`
-	JSONOutput    bool   `long:"json"`
-	EnvelopeClass string `long:"envelope-class" short:"c"`
-	NewLine       string `long:"new-line" optional:"true" optional-value:"\\u2028"`
-	NameFilter    string `long:"name-filter"`
+	StartTime     string   `long:"start-time"`
+	EndTime       string   `long:"end-time"`
+	EnvelopeType  string   `long:"envelope-type" short:"t"`
+	Lines         uint     `long:"lines" short:"n"`
+	Follow        bool     `long:"follow" short:"f"`
+	OutputFormat  string   `long:"output-format" short:"o"`
+	JSONOutput    bool     `long:"json"`
+	EnvelopeClass string   `long:"envelope-class" short:"c"`
+	NewLine       string   `long:"new-line" optional:"true" optional-value:"\\u2028"`
+	NameFilter    string   `long:"name-filter"`
+	Forward       string   `long:"forward"`
+	Filter        string   `long:"filter"`
+	FilterRegex   string   `long:"filter-regex"`
+	VersionCheck  bool     `long:"version-check"`
+	StrictCompat  bool     `long:"strict-compat"`
+	InsecureHTTP  bool     `long:"insecure-http"`
+	Audit         bool     `long:"audit"`
+	Interactive   bool     `long:"interactive"`
+	SourceColors  bool     `long:"source-colors"`
+	CounterRates  bool     `long:"counter-rates"`
+	Endpoints     string   `long:"endpoints"`
+	Dedup         bool     `long:"dedup"`
+	DedupeWindow  int      `long:"dedupe-window" default:"1"`
+	QuietErrors   string   `long:"quiet-errors"`
+	Target        string   `long:"target"`
+	Space         bool     `long:"space"`
+	Wrap          bool     `long:"wrap"`
+	NoWrap        bool     `long:"no-wrap"`
+	WrapIndent    int      `long:"wrap-indent" default:"4"`
+	Quiet         bool     `long:"quiet" short:"q"`
+	PrettyJSON    bool     `long:"pretty-json"`
+	Fields        []string `long:"field"`
+	Tags          []string `long:"tag"`
+	ShowTags      bool     `long:"show-tags"`
+	PromQLNames   bool     `long:"promql-names"`
+	DryRun        bool     `long:"dry-run"`
+	RawNames      bool     `long:"raw-source-names"`
+	TraceID       string   `long:"trace-id"`
+	Output        string   `long:"output"`
+	Verbose       []bool   `short:"v" long:"verbose"`
 }
 
 func newTailOptions(cli plugin.CliConnection, args []string, log Logger) (tailOptions, error) {
+	args = expandPreset(args, log)
+
 	opts := tailOptionFlags{
-		EndTime: time.Now().UnixNano(),
+		Lines: 10,
+	}
+
+	defaults := loadUserDefaults()
+	if defaults.Lines > 0 {
+		opts.Lines = uint(defaults.Lines)
+	}
+	if defaults.EnvelopeType != "" {
+		opts.EnvelopeType = defaults.EnvelopeType
+	}
+	if defaults.TimestampFormat != "" {
+		timeFormat = defaults.TimestampFormat
 	}
 
 	args, err := flags.ParseArgs(&opts, args)
@@ -269,14 +677,48 @@ func newTailOptions(cli plugin.CliConnection, args []string, log Logger) (tailOp
 		return tailOptions{}, err
 	}
 
-	if len(args) != 1 {
-		return tailOptions{}, fmt.Errorf("Expected 1 argument, got %d.", len(args))
+	startTime := int64(0)
+	if opts.StartTime != "" {
+		startTime, err = parseTimeFlag(opts.StartTime, time.Now())
+		if err != nil {
+			return tailOptions{}, fmt.Errorf("Couldn't parse --start-time: %s", err)
+		}
+	}
+
+	endTime := time.Now().UnixNano()
+	if opts.EndTime != "" {
+		endTime, err = parseTimeFlag(opts.EndTime, time.Now())
+		if err != nil {
+			return tailOptions{}, fmt.Errorf("Couldn't parse --end-time: %s", err)
+		}
+	}
+
+	if opts.Space {
+		if len(args) > 1 {
+			return tailOptions{}, fmt.Errorf("Expected at most 1 argument with --space, got %d.", len(args))
+		}
+	} else if len(args) < 1 {
+		if opts.TraceID == "" {
+			return tailOptions{}, fmt.Errorf("Expected at least 1 argument, got %d.", len(args))
+		}
+		// searching for a trace ID with no sources named fans out over
+		// every source discovered from meta
+		args = []string{"*"}
 	}
 
 	if opts.JSONOutput && opts.OutputFormat != "" {
 		return tailOptions{}, errors.New("Cannot use output-format and json flags together")
 	}
 
+	if opts.Output != "" {
+		if opts.Output != "json-lines" {
+			return tailOptions{}, fmt.Errorf("Unknown --output %q. Available: 'json-lines'.", opts.Output)
+		}
+		if opts.JSONOutput || opts.OutputFormat != "" {
+			return tailOptions{}, errors.New("Cannot use --output with --json or --output-format")
+		}
+	}
+
 	if opts.EnvelopeType != "" && opts.EnvelopeClass != "" {
 		return tailOptions{}, errors.New("--envelope-type cannot be used with --envelope-class")
 	}
@@ -285,6 +727,46 @@ func newTailOptions(cli plugin.CliConnection, args []string, log Logger) (tailOp
 		opts.EnvelopeType = "ANY"
 	}
 
+	if opts.Filter != "" && opts.FilterRegex != "" {
+		return tailOptions{}, errors.New("Cannot use --filter and --filter-regex together")
+	}
+
+	if opts.Wrap && opts.NoWrap {
+		return tailOptions{}, errors.New("Cannot use --wrap and --no-wrap together")
+	}
+
+	if opts.WrapIndent < 0 {
+		return tailOptions{}, errors.New("--wrap-indent cannot be negative")
+	}
+
+	if opts.DedupeWindow < 1 {
+		return tailOptions{}, errors.New("--dedupe-window must be greater than 0")
+	}
+
+	var tagFilters map[string]string
+	if len(opts.Tags) > 0 {
+		tagFilters = make(map[string]string)
+		for _, tag := range opts.Tags {
+			parts := strings.SplitN(tag, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return tailOptions{}, fmt.Errorf("--tag must be key=value, got %q", tag)
+			}
+			tagFilters[parts[0]] = parts[1]
+		}
+	}
+
+	filterPattern := opts.FilterRegex
+	if opts.Filter != "" {
+		filterPattern = regexp.QuoteMeta(opts.Filter)
+	}
+
+	if opts.TraceID != "" {
+		if filterPattern != "" {
+			return tailOptions{}, errors.New("Cannot use --trace-id with --filter or --filter-regex")
+		}
+		filterPattern = regexp.QuoteMeta(opts.TraceID)
+	}
+
 	var outputTemplate *template.Template
 	if opts.OutputFormat != "" {
 		outputTemplate, err = parseOutputFormat(opts.OutputFormat)
@@ -293,21 +775,104 @@ func newTailOptions(cli plugin.CliConnection, args []string, log Logger) (tailOp
 		}
 	}
 
-	id, isService := getGUID(args[0], cli, log)
+	var spaceSources []sourceStream
+	if opts.Space {
+		spaceSources = spaceAppSources(args, cli, log)
+		args = nil
+		for _, s := range spaceSources {
+			args = append(args, s.name)
+		}
+	}
+
+	var id string
+	var isService bool
+	if len(args) == 1 && !opts.Space && !containsGlob(args[0]) {
+		// multiple sources resolve their GUIDs lazily in multiTail
+		id, isService = getGUID(args[0], cli, log)
+	}
 	o := tailOptions{
-		startTime:            time.Unix(0, opts.StartTime),
-		endTime:              time.Unix(0, opts.EndTime),
+		startTime:            time.Unix(0, startTime),
+		endTime:              time.Unix(0, endTime),
 		envelopeType:         translateEnvelopeType(opts.EnvelopeType, log),
 		lines:                int(opts.Lines),
 		guid:                 id,
 		isService:            isService,
 		providedName:         args[0],
+		providedNames:        args,
 		follow:               opts.Follow,
 		outputTemplate:       outputTemplate,
 		jsonOutput:           opts.JSONOutput,
 		tokenRefreshInterval: 5 * time.Minute,
 		nameFilter:           opts.NameFilter,
 		envelopeClass:        toEnvelopeClass(opts.EnvelopeClass),
+		versionCheck:         opts.VersionCheck,
+		strictCompat:         opts.StrictCompat,
+		verbosity:            len(opts.Verbose),
+		insecureHTTP:         opts.InsecureHTTP,
+		audit:                opts.Audit,
+		interactive:          opts.Interactive,
+		interactiveInput:     os.Stdin,
+		sourceColors:         opts.SourceColors,
+		counterRates:         opts.CounterRates,
+		dedup:                opts.Dedup,
+		dedupeWindow:         opts.DedupeWindow,
+		quietErrors:          opts.QuietErrors,
+		sources:              spaceSources,
+		wrap:                 opts.Wrap,
+		noWrap:               opts.NoWrap,
+		wrapIndent:           opts.WrapIndent,
+		terminalWidth:        80,
+		noHeaders:            opts.Quiet || opts.DryRun,
+		dryRun:               opts.DryRun,
+		rawSourceNames:       opts.RawNames,
+		jsonLines:            opts.Output == "json-lines",
+		prettyJSON:           opts.PrettyJSON,
+		fields:               opts.Fields,
+		tagFilters:           tagFilters,
+		showTags:             opts.ShowTags,
+		promqlNames:          opts.PromQLNames,
+	}
+
+	// the interactive session only makes sense on a live stream
+	if o.interactive {
+		o.follow = true
+	}
+
+	// a widened window is only meaningful when deduplicating
+	if o.dedupeWindow > 1 {
+		o.dedup = true
+	}
+
+	if defaults.NoColor {
+		o.noColor = true
+	}
+
+	for _, addr := range strings.Split(opts.Endpoints, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			o.endpoints = append(o.endpoints, addr)
+		}
+	}
+
+	if opts.Target != "" {
+		p := resolveTarget(opts.Target, log)
+		o.target = &p
+		o.endpoints = append([]string{p.Addr}, o.endpoints...)
+	}
+
+	if filterPattern != "" {
+		o.filterPattern, err = regexp.Compile(filterPattern)
+		if err != nil {
+			return tailOptions{}, fmt.Errorf("Invalid filter regex '%s'. Ensure your filter-regex is a valid regex.", opts.FilterRegex)
+		}
+	}
+
+	if opts.Forward != "" {
+		u, err := url.Parse(opts.Forward)
+		if err != nil || (u.Scheme != "tcp" && u.Scheme != "udp") || u.Host == "" {
+			return tailOptions{}, errors.New("--forward address must be in the form tcp://host:port or udp://host:port")
+		}
+		o.forwardNetwork = u.Scheme
+		o.forwardAddr = u.Host
 	}
 
 	if opts.NewLine != "" {
@@ -334,6 +899,10 @@ func toEnvelopeClass(class string) envelopeClass {
 }
 
 func formatterKindFromOptions(o tailOptions) formatterKind {
+	if o.jsonLines {
+		return jsonLinesFormat
+	}
+
 	if o.jsonOutput {
 		return jsonFormat
 	}
@@ -345,6 +914,42 @@ func formatterKindFromOptions(o tailOptions) formatterKind {
 	return prettyFormat
 }
 
+// highlightEvent wraps crash and audit event lines in a bold red escape so
+// they stand out from the surrounding stream on a terminal.
+func highlightEvent(e *loggregator_v2.Envelope, formatted string) string {
+	ev := e.GetEvent()
+	if ev == nil {
+		return formatted
+	}
+
+	title := strings.ToLower(ev.GetTitle())
+	if strings.Contains(title, "crash") || strings.Contains(title, "audit") {
+		return "\x1b[1;31m" + formatted + "\x1b[0m"
+	}
+
+	return formatted
+}
+
+// tagFilter reports whether the envelope carries every --tag key=value
+// pair. Deprecated tags are consulted as a fallback, the same way the
+// formatter reads source_type.
+func tagFilter(e *loggregator_v2.Envelope, o tailOptions) bool {
+	for k, want := range o.tagFilters {
+		got, ok := e.GetTags()[k]
+		if !ok {
+			if t, dok := e.GetDeprecatedTags()[k]; dok {
+				got = t.GetText()
+				ok = true
+			}
+		}
+		if !ok || got != want {
+			return false
+		}
+	}
+
+	return true
+}
+
 func typeFilter(e *loggregator_v2.Envelope, o tailOptions) bool {
 	if o.envelopeClass == envelopeClassAny {
 		return true
@@ -365,8 +970,8 @@ func (o tailOptions) validate() error {
 		return errors.New("Invalid date/time range. Ensure your start time is prior or equal the end time.")
 	}
 
-	if o.lines > 1000 || o.lines < 0 {
-		return errors.New("Lines cannot be greater than 1000.")
+	if o.lines < 0 {
+		return errors.New("Lines cannot be negative.")
 	}
 
 	_, err := regexp.Compile(o.nameFilter)
@@ -420,6 +1025,12 @@ func getGUID(name string, cli plugin.CliConnection, log Logger) (string, bool) {
 }
 
 func getAppGUID(appName string, cli plugin.CliConnection, log Logger) string {
+	// resolve through the targeted org/space first; within a space app names
+	// are unique, so this lookup is never ambiguous
+	if app, err := cli.GetApp(appName); err == nil && app.Guid != "" {
+		return app.Guid
+	}
+
 	r, err := cli.CliCommandWithoutTerminalOutput(
 		"app",
 		appName,
@@ -503,17 +1114,87 @@ func (b backoff) OnErr(err error) bool {
 	return b.AlwaysDoneBackoff.OnErr(err)
 }
 
-type tokenHTTPClient struct {
-	c         HTTPClient
-	tokenFunc func() string
+// headerHTTPClient adds fixed headers to every outgoing request on behalf of
+// the WithMetaRequestHeader and WithTailRequestHeader options and the global
+// --header flag.
+type headerHTTPClient struct {
+	c       HTTPClient
+	headers [][2]string
+}
+
+// NewHeaderClient wraps the client so the given headers are set on every
+// request, e.g. routing headers required by a fronting proxy.
+func NewHeaderClient(c HTTPClient, headers [][2]string) HTTPClient {
+	return &headerHTTPClient{c: c, headers: headers}
 }
 
-func (c *tokenHTTPClient) Do(req *http.Request) (*http.Response, error) {
-	accessToken := c.tokenFunc()
-	if len(accessToken) > 0 {
-		req.Header.Set("Authorization", accessToken)
+func (c *headerHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	for _, h := range c.headers {
+		req.Header.Set(h[0], h[1])
 	}
 
 	return c.c.Do(req)
+}
+
+// maxRateLimitRetries bounds how often a rate limited request is retried
+// before the response is handed back to the command.
+const maxRateLimitRetries = 3
+
+// retryAfterHTTPClient retries requests the gateway rate limits with a 429,
+// honoring the Retry-After header instead of aborting the whole run. Noise
+// mode's burst of requests regularly trips rate limits.
+type retryAfterHTTPClient struct {
+	c     HTTPClient
+	sleep func(time.Duration)
+}
+
+// NewRetryAfterClient wraps an HTTPClient so 429 responses wait out the
+// server's Retry-After and try again.
+func NewRetryAfterClient(c HTTPClient) HTTPClient {
+	return &retryAfterHTTPClient{c: c, sleep: time.Sleep}
+}
+
+func (c *retryAfterHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.c.Do(req)
+	for attempt := 0; attempt < maxRateLimitRetries && err == nil && resp.StatusCode == http.StatusTooManyRequests; attempt++ {
+		// a request with a consumed body cannot be replayed
+		if req.Body != nil && req.GetBody == nil {
+			break
+		}
+
+		delay := retryAfterDelay(resp)
+		resp.Body.Close()
+		c.sleep(delay)
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err = c.c.Do(req)
+	}
+
+	return resp, err
+}
+
+// retryAfterDelay parses a Retry-After header, which holds either delay
+// seconds or an HTTP date. A missing or malformed header falls back to one
+// second.
+func retryAfterDelay(resp *http.Response) time.Duration {
+	h := resp.Header.Get("Retry-After")
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
 
+	return time.Second
 }
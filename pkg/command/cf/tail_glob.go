@@ -0,0 +1,82 @@
+package cf
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// containsGlob reports whether a provided source name is a pattern rather
+// than a literal name or GUID.
+func containsGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// anyGlob reports whether any of the provided source names is a pattern.
+func anyGlob(names []string) bool {
+	for _, name := range names {
+		if containsGlob(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandGlobSources resolves glob patterns (e.g. 'doppler*') against the
+// source IDs discovered from meta, so platform components with rotating
+// GUIDs can be tailed without finding the exact IDs first. Literal names
+// are resolved the usual way and passed through.
+func expandGlobSources(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	o tailOptions,
+	c HTTPClient,
+	log Logger,
+) []sourceStream {
+	client := createLogCacheClient(c, log, cli, o.insecureHTTP)
+
+	var sources []sourceStream
+	var sourceIDs []string
+	for _, name := range o.providedNames {
+		if !containsGlob(name) {
+			id, _ := getGUID(name, cli, log)
+			if id == "" {
+				// fall back to provided name
+				id = name
+			}
+			sources = append(sources, sourceStream{name: name, id: id})
+			continue
+		}
+
+		if sourceIDs == nil {
+			meta, err := client.Meta(ctx)
+			if err != nil {
+				fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+			}
+			for sourceID := range meta {
+				sourceIDs = append(sourceIDs, sourceID)
+			}
+			sort.Strings(sourceIDs)
+		}
+
+		var matched bool
+		for _, sourceID := range sourceIDs {
+			ok, err := path.Match(name, sourceID)
+			if err != nil {
+				fatalf(log, ExitCodeUsage, "Invalid source pattern %q: %s", name, err)
+			}
+			if ok {
+				sources = append(sources, sourceStream{name: sourceID, id: sourceID})
+				matched = true
+			}
+		}
+		if !matched {
+			fatalf(log, ExitCodeNotFound, "No sources match %q.", name)
+		}
+	}
+
+	return sources
+}
@@ -0,0 +1,250 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	lc "code.cloudfoundry.org/log-cache-cli/v4/pkg/logcache"
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+)
+
+// sourceStream pairs a display name with the source ID read from Log Cache.
+type sourceStream struct {
+	name string
+	id   string
+}
+
+// spaceAppSources lists every app in the targeted space, or in the named
+// space within the targeted org, as tailable sources.
+func spaceAppSources(args []string, cli plugin.CliConnection, log Logger) []sourceStream {
+	var sources []sourceStream
+	if len(args) == 1 {
+		space, err := cli.GetSpace(args[0])
+		if err != nil {
+			fatalf(log, ExitCodeNotFound, "Failed to resolve space %s: %s", args[0], err)
+		}
+		for _, a := range space.Applications {
+			sources = append(sources, sourceStream{name: a.Name, id: a.Guid})
+		}
+	} else {
+		apps, err := cli.GetApps()
+		if err != nil {
+			log.Fatalf("Failed to list apps in the targeted space: %s", err)
+		}
+		for _, a := range apps {
+			sources = append(sources, sourceStream{name: a.Name, id: a.Guid})
+		}
+	}
+
+	if len(sources) == 0 {
+		fatalf(log, ExitCodeNotFound, "No apps found in space.")
+	}
+	return sources
+}
+
+// multiTail streams several sources at once, interleaving their envelopes by
+// timestamp and prefixing every line with its source name, so a microservice
+// interaction can be followed in a single terminal.
+func multiTail(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	o tailOptions,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+) {
+	lw := lineWriter{w: w}
+	formatter := newFormatter(o.providedNames[0], o.follow, formatterKindFromOptions(o), log, o.outputTemplate, o.newLineReplacer, o.prettyJSON, o.fields, o.showTags, o.promqlNames)
+
+	logCacheAddr := os.Getenv("LOG_CACHE_ADDR")
+	if len(o.endpoints) > 0 {
+		logCacheAddr = o.endpoints[0]
+	}
+	if logCacheAddr == "" {
+		hasAPI, err := cli.HasAPIEndpoint()
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		if !hasAPI {
+			log.Fatalf("No API endpoint targeted.")
+		}
+
+		tokenURL, err := cli.ApiEndpoint()
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		logCacheAddr = strings.Replace(tokenURL, "api", "log-cache", 1)
+
+		if !o.noHeaders {
+			user, err := cli.Username()
+			if err != nil {
+				log.Fatalf("%s", err)
+			}
+
+			lw.Write(fmt.Sprintf(
+				"Retrieving logs for %s as %s...",
+				strings.Join(o.providedNames, ", "),
+				user,
+			))
+			lw.Write("")
+		}
+	}
+
+	checkPlainHTTP(logCacheAddr, o.insecureHTTP, log)
+
+	v := newVerboseLogger(log, o.verbosity)
+
+	if o.target != nil && o.target.CACert != "" {
+		c = targetHTTPClient(*o.target, log)
+	}
+
+	tokenFunc := func() string { return "" }
+
+	skipAuth := strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) == "true" ||
+		(o.target != nil && o.target.Auth == "none")
+	if !skipAuth {
+		tokenFunc = func() string {
+			token, err := cli.AccessToken()
+			if err != nil {
+				fatalf(log, ExitCodeAuth, "Unable to get Access Token: %s", err)
+			}
+			return token
+		}
+	}
+
+	tokenClient := lc.NewTokenClient(newVerboseHTTPClient(c, v), tokenFunc)
+
+	client := logcache.NewClient(logCacheAddr, logcache.WithHTTPClient(tokenClient))
+
+	sources := o.sources
+	if len(sources) == 0 {
+		for _, name := range o.providedNames {
+			id, _ := getGUID(name, cli, log)
+			if id == "" {
+				// fall back to provided name
+				id = name
+			}
+			sources = append(sources, sourceStream{name: name, id: id})
+		}
+	}
+
+	names := make(map[string]string)
+	for _, s := range sources {
+		names[s.id] = s.name
+		// unresolved non-GUID sources are platform components; show the
+		// recognizable component name unless the raw ID was asked for
+		if s.name == s.id && !o.rawSourceNames {
+			names[s.id] = friendlySourceName(s.id)
+		}
+	}
+
+	var mu sync.Mutex
+	loss := newLossMonitor(log)
+	emit := func(e *loggregator_v2.Envelope) {
+		loss.observe(e)
+
+		if !typeFilter(e, o) {
+			return
+		}
+
+		if !tagFilter(e, o) {
+			return
+		}
+
+		formatted, ok := formatter.formatEnvelope(e)
+		if !ok {
+			return
+		}
+
+		if o.filterPattern != nil {
+			if !o.filterPattern.MatchString(formatted) {
+				return
+			}
+
+			if !o.noColor {
+				formatted = o.filterPattern.ReplaceAllString(formatted, "\x1b[1;31m${0}\x1b[0m")
+			}
+		}
+
+		if !o.noColor {
+			formatted = highlightEvent(e, formatted)
+		}
+
+		line := fmt.Sprintf("[%s]%s", names[e.GetSourceId()], formatted)
+		if o.sourceColors && !o.noColor {
+			line = colorForSource(e.GetSourceId()) + line + "\x1b[0m"
+		}
+		lw.Write(line)
+	}
+
+	walkStartTimes := make(map[string]int64)
+	for _, s := range sources {
+		walkStartTimes[s.id] = time.Now().Add(-5 * time.Second).UnixNano()
+	}
+
+	if o.lines > 0 {
+		var all []*loggregator_v2.Envelope
+		for _, s := range sources {
+			readStart := time.Now()
+			envelopes, pages, err := readLinesPaged(ctx, client.Read, s.id, o, v)
+			if err != nil && !o.follow {
+				fatalf(log, ExitCodeNetwork, "Failed to read %s: %s", s.name, err)
+			}
+
+			v.V(1, "Received %d envelopes for %s in %s (%d pages)",
+				len(envelopes),
+				s.name,
+				time.Since(readStart).Truncate(time.Millisecond),
+				pages,
+			)
+
+			if len(envelopes) > 0 {
+				walkStartTimes[s.id] = envelopes[0].Timestamp + 1
+			}
+			all = append(all, envelopes...)
+		}
+
+		sort.Slice(all, func(i, j int) bool { return all[i].Timestamp < all[j].Timestamp })
+		for _, e := range all {
+			emit(e)
+		}
+	}
+
+	if o.follow {
+		var wg sync.WaitGroup
+		for _, s := range sources {
+			wg.Add(1)
+			go func(s sourceStream) {
+				defer wg.Done()
+				logcache.Walk(
+					ctx,
+					s.id,
+					logcache.Visitor(func(envelopes []*loggregator_v2.Envelope) bool {
+						mu.Lock()
+						defer mu.Unlock()
+						for _, e := range envelopes {
+							emit(e)
+						}
+						return true
+					}),
+					client.Read,
+					logcache.WithWalkStartTime(time.Unix(0, walkStartTimes[s.id])),
+					logcache.WithWalkEnvelopeTypes(o.envelopeType),
+					logcache.WithWalkBackoff(logcache.NewAlwaysRetryBackoff(250*time.Millisecond)),
+					logcache.WithWalkNameFilter(o.nameFilter),
+				)
+			}(s)
+		}
+		wg.Wait()
+	}
+}
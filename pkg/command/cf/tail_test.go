@@ -1,17 +1,25 @@
 package cf_test
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	plugin_models "code.cloudfoundry.org/cli/plugin/models"
 	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -37,6 +45,25 @@ var _ = Describe("LogCache", func() {
 		cliConn = newStubCliConnection()
 	})
 
+	It("prints the computed read URL without a request with --dry-run", func() {
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--dry-run", "--start-time", "0", "--end-time", "100", "app-name"},
+			httpClient,
+			logger,
+			writer,
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			"Source ID: app-guid",
+			"https://log-cache.some-system.com/api/v1/read/app-guid?descending=true&end_time=100&limit=10&start_time=0",
+		}))
+		Expect(httpClient.requestURLs).To(BeEmpty())
+	})
+
 	It("removes headers when not printing to a tty", func() {
 		cf.Tail(
 			context.Background(),
@@ -48,54 +75,1143 @@ var _ = Describe("LogCache", func() {
 			cf.WithTailNoHeaders(),
 		)
 
-		logFormat := "   %s [APP/PROC/WEB/0] %s log body"
-		Expect(writer.lines()).To(Equal([]string{
-			fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
-			fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT"),
-			fmt.Sprintf(logFormat, startTime.Add(2*time.Second).Format(timeFormat), "OUT"),
-		}))
-	})
+		logFormat := "   %s [APP/PROC/WEB/0] %s log body"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
+			fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT"),
+			fmt.Sprintf(logFormat, startTime.Add(2*time.Second).Format(timeFormat), "OUT"),
+		}))
+	})
+
+	It("re-indents JSON object payloads with --pretty-json", func() {
+		payload := base64.StdEncoding.EncodeToString([]byte(`{"level":"info","msg":"started"}`))
+		httpClient.responseBody = []string{fmt.Sprintf(`{
+			"envelopes": {
+				"batch": [
+					{
+						"timestamp": %d,
+						"instance_id": "0",
+						"tags": {"source_type": "APP/PROC/WEB"},
+						"log": {"payload": %q}
+					}
+				]
+			}
+		}`, startTime.UnixNano(), payload)}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--pretty-json", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf("   %s [APP/PROC/WEB/0] OUT {", startTime.Format(timeFormat)),
+			`  "level": "info",`,
+			`  "msg": "started"`,
+			"}",
+		}))
+	})
+
+	It("extracts JSON fields from structured payloads with --field", func() {
+		payload := base64.StdEncoding.EncodeToString([]byte(`{"level":"info","msg":"started","request":{"path":"/v2/apps"}}`))
+		httpClient.responseBody = []string{fmt.Sprintf(`{
+			"envelopes": {
+				"batch": [
+					{
+						"timestamp": %d,
+						"instance_id": "0",
+						"tags": {"source_type": "APP/PROC/WEB"},
+						"log": {"payload": %q}
+					}
+				]
+			}
+		}`, startTime.UnixNano(), payload)}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--field", "level", "--field", "request.path", "--field", "missing", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf("   %s [APP/PROC/WEB/0] OUT info /v2/apps -", startTime.Format(timeFormat)),
+		}))
+	})
+
+	It("filters envelopes by their tags with --tag", func() {
+		httpClient.responseBody = []string{
+			responseBody(startTime),
+			responseBody(startTime),
+		}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--tag", "source_type=APP/PROC/WEB", "--tag", "deployment=cf", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		// nothing carries a deployment tag, so the first read prints nothing
+		Expect(string(writer.bytes)).To(BeEmpty())
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--tag", "source_type=APP/PROC/WEB", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(HaveLen(3))
+	})
+
+	It("warns when the stream reports dropped envelopes", func() {
+		httpClient.responseBody = []string{fmt.Sprintf(`{
+			"envelopes": {
+				"batch": [
+					{"timestamp":"%d","source_id":"doppler","counter":{"name":"dropped","total":"8"}},
+					{"timestamp":"%d","source_id":"doppler","counter":{"name":"dropped","total":"5"}}
+				]
+			}
+		}`, startTime.Add(1*time.Second).UnixNano(), startTime.UnixNano())}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(logger.printfMessages).To(ContainElement(
+			"WARNING: doppler reports 3 dropped envelopes (dropped); the stream is likely missing logs.",
+		))
+	})
+
+	It("highlights crash and audit events", func() {
+		httpClient.responseBody = []string{fmt.Sprintf(`{
+			"envelopes": {
+				"batch": [
+					{
+						"source_id": "app-name",
+						"instance_id": "0",
+						"timestamp": "%d",
+						"event": {"title": "app.crash", "body": "index 0 exited"}
+					}
+				]
+			}
+		}`, startTime.UnixNano())}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf("\x1b[1;31m   %s [app-name/0] EVENT app.crash:index 0 exited\x1b[0m", startTime.Format(timeFormat)),
+		}))
+	})
+
+	It("does not highlight events when color is disabled", func() {
+		httpClient.responseBody = []string{fmt.Sprintf(`{
+			"envelopes": {
+				"batch": [
+					{
+						"source_id": "app-name",
+						"instance_id": "0",
+						"timestamp": "%d",
+						"event": {"title": "app.crash", "body": "index 0 exited"}
+					}
+				]
+			}
+		}`, startTime.UnixNano())}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+			cf.WithTailNoColor(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf("   %s [app-name/0] EVENT app.crash:index 0 exited", startTime.Format(timeFormat)),
+		}))
+	})
+
+	It("renders sub-millisecond timers as readable durations", func() {
+		httpClient.responseBody = []string{fmt.Sprintf(
+			timerResponseTemplate,
+			startTime.UnixNano(),
+			startTime.UnixNano(),
+			startTime.Add(1500*time.Microsecond).UnixNano(),
+		)}
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf("   %s [app-name/0] TIMER http 1.5ms", startTime.Format(timeFormat)),
+		}))
+	})
+
+	It("sanitizes metric names like the PromQL layer with --promql-names", func() {
+		httpClient.responseBody = []string{counterResponseBody(startTime)}
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--promql-names", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf("   %s [app-name/0] COUNTER some_name:99", startTime.Format(timeFormat)),
+		}))
+	})
+
+	It("appends envelope tags to each line with --show-tags", func() {
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--show-tags", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		logFormat := "   %s [APP/PROC/WEB/0] %s log body [source_type=APP/PROC/WEB]"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
+			fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT"),
+			fmt.Sprintf(logFormat, startTime.Add(2*time.Second).Format(timeFormat), "OUT"),
+		}))
+	})
+
+	It("fatally logs for a --tag without a value", func() {
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--tag", "deployment", "app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal(`--tag must be key=value, got "deployment"`))
+	})
+
+	It("leaves non-JSON payloads alone with --pretty-json", func() {
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--pretty-json", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		logFormat := "   %s [APP/PROC/WEB/0] %s log body"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
+			fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT"),
+			fmt.Sprintf(logFormat, startTime.Add(2*time.Second).Format(timeFormat), "OUT"),
+		}))
+	})
+
+	It("adds headers from WithTailRequestHeader to each request", func() {
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+			cf.WithTailRequestHeader("X-Routing-Instance", "east"),
+		)
+
+		Expect(httpClient.requestHeaders).To(HaveLen(1))
+		Expect(httpClient.requestHeaders[0].Get("X-Routing-Instance")).To(Equal("east"))
+	})
+
+	It("collapses consecutive identical log messages with --dedup", func() {
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name", "--dedup"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		logFormat := "   %s [APP/PROC/WEB/0] %s log body"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
+			fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT") + " (x2)",
+		}))
+	})
+
+	It("drops interleaved duplicates within --dedupe-window and reports removals", func() {
+		httpClient.responseBody = []string{interleavedDupResponseBody(startTime)}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name", "--dedupe-window", "2"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		logFormat := "   %s [APP/PROC/WEB/0] %s log body"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, startTime.Format(timeFormat), "OUT"),
+			fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "ERR"),
+		}))
+
+		Expect(logger.printfMessages).To(ContainElement("Removed 1 duplicate lines for app-name."))
+	})
+
+	It("routes non-fatal diagnostics to a file with --quiet-errors", func() {
+		httpClient.responseBody = []string{interleavedDupResponseBody(startTime)}
+
+		dir, err := ioutil.TempDir("", "quiet-errors")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "diagnostics.log")
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name", "--dedupe-window", "2", "--quiet-errors", path},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(logger.printfMessages).ToNot(ContainElement("Removed 1 duplicate lines for app-name."))
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("Removed 1 duplicate lines for app-name."))
+	})
+
+	It("fatally logs when the --quiet-errors file cannot be opened", func() {
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--quiet-errors", "/does/not/exist/diagnostics.log"},
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(HavePrefix("Could not open --quiet-errors file:"))
+	})
+
+	It("fatally logs when --dedupe-window is not positive", func() {
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--dedupe-window", "0"},
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--dedupe-window must be greater than 0"))
+	})
+
+	It("interleaves multiple sources by timestamp with a source prefix", func() {
+		httpClient.responseBody = []string{
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"guid-1","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}},
+				{"timestamp":"%d","source_id":"guid-1","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.Add(2*time.Second).UnixNano(), startTime.UnixNano()),
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"guid-2","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.Add(1*time.Second).UnixNano()),
+		}
+		cliConn.cliCommandResult = [][]string{{"guid-1"}, {"guid-2"}}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-1", "app-2"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		logFormat := "[%s]   %s [APP/PROC/WEB/0] OUT log body"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, "app-1", startTime.Format(timeFormat)),
+			fmt.Sprintf(logFormat, "app-2", startTime.Add(1*time.Second).Format(timeFormat)),
+			fmt.Sprintf(logFormat, "app-1", startTime.Add(2*time.Second).Format(timeFormat)),
+		}))
+	})
+
+	It("colors interleaved multi-source lines by source with --source-colors", func() {
+		httpClient.responseBody = []string{
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"guid-1","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.UnixNano()),
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"guid-2","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.Add(1*time.Second).UnixNano()),
+		}
+		cliConn.cliCommandResult = [][]string{{"guid-1"}, {"guid-2"}}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--source-colors", "app-1", "app-2"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		lines := writer.lines()
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(MatchRegexp(`^\x1b\[\d+m\[app-1\].*\x1b\[0m$`))
+		Expect(lines[1]).To(MatchRegexp(`^\x1b\[\d+m\[app-2\].*\x1b\[0m$`))
+	})
+
+	DescribeTable("fatally logs when a single-source-only flag is used with multiple sources",
+		func(flag string, args []string) {
+			cliConn.cliCommandResult = [][]string{{"guid-1"}, {"guid-2"}}
+
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					append(args, "app-1", "app-2"),
+					httpClient,
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal(
+				fmt.Sprintf("Cannot use %s when tailing multiple sources.", flag),
+			))
+		},
+		Entry("--audit", "--audit", []string{"--audit"}),
+		Entry("--counter-rates", "--counter-rates", []string{"--counter-rates"}),
+		Entry("--dedup", "--dedup", []string{"--dedup"}),
+		Entry("--forward", "--forward", []string{"--forward", "tcp://localhost:9999"}),
+		Entry("--interactive", "--interactive", []string{"--interactive"}),
+		Entry("--wrap", "--wrap", []string{"--wrap"}),
+		Entry("--no-wrap", "--no-wrap", []string{"--no-wrap"}),
+	)
+
+	It("expands a glob source against the IDs discovered from meta", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("doppler-abc", "doppler-def", "router-1"),
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"doppler-abc","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.UnixNano()),
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"doppler-def","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.Add(1*time.Second).UnixNano()),
+		}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"doppler-*"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(httpClient.requestURLs[0]).To(ContainSubstring("/v1/meta"))
+
+		logFormat := "[%s]   %s [APP/PROC/WEB/0] OUT log body"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, "doppler-abc", startTime.Format(timeFormat)),
+			fmt.Sprintf(logFormat, "doppler-def", startTime.Add(1*time.Second).Format(timeFormat)),
+		}))
+	})
+
+	It("emits one compact JSON document per envelope with --output json-lines", func() {
+		httpClient.responseBody = []string{
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"app-guid","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}},
+				{"timestamp":"%d","source_id":"app-guid","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.UnixNano(), startTime.Add(1*time.Second).UnixNano()),
+		}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--output", "json-lines", "app-name"},
+			httpClient,
+			logger,
+			writer,
+		)
+
+		Expect(writer.lines()).To(HaveLen(2))
+		Expect(writer.lines()[0]).To(MatchJSON(fmt.Sprintf(
+			`{"timestamp":"%d","source_id":"app-guid","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}`,
+			startTime.Add(1*time.Second).UnixNano(),
+		)))
+		Expect(writer.lines()[1]).To(MatchJSON(fmt.Sprintf(
+			`{"timestamp":"%d","source_id":"app-guid","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}`,
+			startTime.UnixNano(),
+		)))
+	})
+
+	It("fatally logs for an unknown --output", func() {
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--output", "xml", "app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal(`Unknown --output "xml". Available: 'json-lines'.`))
+	})
+
+	It("only prints lines containing --trace-id", func() {
+		httpClient.responseBody = []string{
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"app-guid","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"dmNhcF9yZXF1ZXN0X2lkPWFiYy0xMjMgaGFuZGxlZA=="}},
+				{"timestamp":"%d","source_id":"app-guid","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"dW5yZWxhdGVkIGxpbmU="}}
+			]}}`, startTime.UnixNano(), startTime.Add(1*time.Second).UnixNano()),
+		}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"app-name", "--trace-id", "abc-123"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(
+				"   %s [APP/PROC/WEB/0] OUT vcap_request_id=\x1b[1;31mabc-123\x1b[0m handled",
+				startTime.Format(timeFormat),
+			),
+		}))
+	})
+
+	It("searches every source from meta when --trace-id is given without a source", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-a", "source-b"),
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"source-a","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"dmNhcF9yZXF1ZXN0X2lkPWFiYy0xMjMgaGFuZGxlZA=="}}
+			]}}`, startTime.UnixNano()),
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"source-b","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"dW5yZWxhdGVkIGxpbmU="}}
+			]}}`, startTime.Add(1*time.Second).UnixNano()),
+		}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--trace-id", "abc-123"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(
+				"[source-a]   %s [APP/PROC/WEB/0] OUT vcap_request_id=\x1b[1;31mabc-123\x1b[0m handled",
+				startTime.Format(timeFormat),
+			),
+		}))
+	})
+
+	It("fatally logs when --trace-id is combined with --filter", func() {
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--trace-id", "abc-123", "--filter", "ERR"},
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Cannot use --trace-id with --filter or --filter-regex"))
+	})
+
+	It("fatally logs when a glob source matches nothing", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("router-1"),
+		}
+
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"doppler-*"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal(`No sources match "doppler-*".`))
+	})
+
+	It("tails every app in the targeted space with --space", func() {
+		httpClient.responseBody = []string{
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"guid-1","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.UnixNano()),
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"guid-2","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.Add(1*time.Second).UnixNano()),
+		}
+		cliConn.getAppsResult = []plugin_models.GetAppsModel{
+			{Name: "app-1", Guid: "guid-1"},
+			{Name: "app-2", Guid: "guid-2"},
+		}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"--space"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		logFormat := "[%s]   %s [APP/PROC/WEB/0] OUT log body"
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf(logFormat, "app-1", startTime.Format(timeFormat)),
+			fmt.Sprintf(logFormat, "app-2", startTime.Add(1*time.Second).Format(timeFormat)),
+		}))
+	})
+
+	It("tails a named space's apps with --space", func() {
+		httpClient.responseBody = []string{
+			fmt.Sprintf(`{"envelopes":{"batch":[
+				{"timestamp":"%d","source_id":"guid-1","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}
+			]}}`, startTime.UnixNano()),
+		}
+		cliConn.getSpaceResult = plugin_models.GetSpace_Model{
+			Applications: []plugin_models.GetSpace_Apps{
+				{Name: "app-1", Guid: "guid-1"},
+			},
+		}
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"my-space", "--space"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(cliConn.getSpaceName).To(Equal("my-space"))
+		Expect(writer.lines()).To(Equal([]string{
+			fmt.Sprintf("[app-1]   %s [APP/PROC/WEB/0] OUT log body", startTime.Format(timeFormat)),
+		}))
+	})
+
+	It("fatally logs when --space finds no apps", func() {
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--space"},
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("No apps found in space."))
+	})
+
+	It("falls back to the next --endpoints entry after repeated read failures", func() {
+		httpClient.responseCode = 500
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		cf.Tail(
+			ctx,
+			cliConn,
+			[]string{
+				"app-name",
+				"--follow",
+				"--lines", "0",
+				"--endpoints", "https://primary.example.com,https://secondary.example.com",
+			},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(logger.printfMessages).To(ContainElement(
+			"Switching to Log Cache endpoint https://secondary.example.com after repeated read failures.",
+		))
+
+		var hitSecondary bool
+		for _, u := range httpClient.requestURLs {
+			if strings.HasPrefix(u, "https://secondary.example.com") {
+				hitSecondary = true
+			}
+		}
+		Expect(hitSecondary).To(BeTrue())
+	})
+
+	It("retries a failed read when WithTailRetries is provided", func() {
+		httpClient.responseCode = 500
+
+		Expect(func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailRetries(2),
+			)
+		}).To(Panic())
+
+		Expect(httpClient.requestURLs).To(HaveLen(3))
+	})
+
+	Context("when the source is an app", func() {
+		BeforeEach(func() {
+			cliConn.cliCommandResult = [][]string{
+				{"app-guid"},
+			}
+			cliConn.usernameResp = "a-user"
+			cliConn.orgName = "organization"
+			cliConn.spaceName = "space"
+		})
+
+		It("reports successful results", func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+			Expect(httpClient.requestURLs).To(HaveLen(1))
+
+			requestURL, err := url.Parse(httpClient.requestURLs[0])
+			end, err := strconv.ParseInt(requestURL.Query().Get("end_time"), 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(end).To(BeNumerically("~", time.Now().UnixNano(), 10000000))
+
+			logFormat := "   %s [APP/PROC/WEB/0] %s log body"
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(
+					"Retrieving logs for app %s in org %s / space %s as %s...",
+					"app-name",
+					cliConn.orgName,
+					cliConn.spaceName,
+					cliConn.usernameResp,
+				),
+				"",
+				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
+				fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT"),
+				fmt.Sprintf(logFormat, startTime.Add(2*time.Second).Format(timeFormat), "OUT"),
+			}))
+		})
+
+		It("resolves the app name through GetApp before shelling out", func() {
+			cliConn.cliCommandResult = nil
+			cliConn.getAppResult = plugin_models.GetAppModel{
+				Guid: "plugin-app-guid",
+				Name: "app-name",
+			}
+
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			Expect(cliConn.getAppName).To(Equal("app-name"))
+			Expect(cliConn.cliCommandArgs).To(BeEmpty())
+
+			Expect(httpClient.requestURLs).To(HaveLen(1))
+			Expect(httpClient.requestURLs[0]).To(ContainSubstring("/v1/read/plugin-app-guid"))
+		})
+
+		It("highlights matches when --filter is provided", func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--filter", "ERR"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(
+					"Retrieving logs for app %s in org %s / space %s as %s...",
+					"app-name",
+					cliConn.orgName,
+					cliConn.spaceName,
+					cliConn.usernameResp,
+				),
+				"",
+				fmt.Sprintf(
+					"   %s [APP/PROC/WEB/0] \x1b[1;31mERR\x1b[0m log body",
+					startTime.Format(timeFormat),
+				),
+			}))
+		})
+
+		It("filters without color codes when color is disabled", func() {
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--filter-regex", "E.R"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailNoColor(),
+			)
+
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(
+					"   %s [APP/PROC/WEB/0] ERR log body",
+					startTime.Format(timeFormat),
+				),
+			}))
+		})
+
+		It("prints an audit report with --audit", func() {
+			httpClient.responseBody = []string{`{"envelopes":{"batch":[
+				{"source_id":"app-guid","timestamp":"200000000000"},
+				{"source_id":"app-guid","timestamp":"500000000"},
+				{"source_id":"app-guid","timestamp":"1000000000"},
+				{"source_id":"app-guid","timestamp":"1000000000"}
+			]}}`}
+
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--audit"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+			)
+
+			Expect(writer.lines()).To(ContainElement("Audit report:"))
+			Expect(writer.lines()).To(ContainElement("  Envelopes:               4"))
+			Expect(writer.lines()).To(ContainElement("  Monotonicity violations: 1"))
+			Expect(writer.lines()).To(ContainElement("  Duplicate timestamps:    1"))
+			Expect(writer.lines()).To(ContainElement("  Gaps over 1m0s:          1"))
+		})
+
+		It("wraps long lines to the terminal width with --wrap", func() {
+			httpClient.responseBody = []string{
+				responseBody(startTime),
+			}
+
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--wrap", "app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailTerminalWidth(30),
+			)
+
+			var reassembled []string
+			for _, line := range writer.lines() {
+				Expect(len(line)).To(BeNumerically("<=", 30))
+				if strings.HasPrefix(line, "    ") {
+					Expect(reassembled).ToNot(BeEmpty())
+					reassembled[len(reassembled)-1] += strings.TrimPrefix(line, "    ")
+				} else {
+					reassembled = append(reassembled, line)
+				}
+			}
+
+			Expect(reassembled).To(HaveLen(3))
+			for _, line := range reassembled {
+				Expect(line).To(HaveSuffix("log body"))
+			}
+		})
+
+		It("truncates long lines to the terminal width with --no-wrap", func() {
+			httpClient.responseBody = []string{
+				responseBody(startTime),
+			}
+
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--no-wrap", "app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailTerminalWidth(30),
+			)
+
+			lines := writer.lines()
+			Expect(lines).To(HaveLen(3))
+			for _, line := range lines {
+				Expect(line).To(HaveLen(30))
+			}
+		})
+
+		It("returns an error when --wrap is used with --no-wrap", func() {
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					[]string{"--wrap", "--no-wrap", "app-name"},
+					httpClient,
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Cannot use --wrap and --no-wrap together"))
+		})
+
+		It("colors lines by source with --source-colors", func() {
+			httpClient.responseBody = []string{
+				responseBody(startTime),
+			}
+
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--source-colors", "app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+			)
+
+			lines := writer.lines()
+			Expect(lines).ToNot(BeEmpty())
+
+			var prefix string
+			for _, line := range lines {
+				Expect(line).To(MatchRegexp(`^\x1b\[\d+m.*\x1b\[0m$`))
+				if prefix == "" {
+					prefix = line[:strings.Index(line, "m")+1]
+				}
+				// all envelopes share a source, so they share a color
+				Expect(line).To(HavePrefix(prefix))
+			}
+		})
+
+		It("does not color lines by source when color is disabled", func() {
+			httpClient.responseBody = []string{
+				responseBody(startTime),
+			}
+
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--source-colors", "app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailNoColor(),
+			)
+
+			for _, line := range writer.lines() {
+				Expect(line).ToNot(ContainSubstring("\x1b["))
+			}
+		})
+
+		It("responds to key presses with --interactive", func() {
+			httpClient.responseBody = []string{
+				// Lines mode requests WithDescending
+				responseBody(startTime.Add(-30 * time.Second)),
+				// Walk uses ascending order
+				responseBodyAsc(startTime),
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+			defer cancel()
+			cf.Tail(
+				ctx,
+				cliConn,
+				[]string{"--interactive", "app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailInteractiveInput(strings.NewReader("/ERR\n q")),
+			)
+
+			Expect(writer.lines()).To(ContainElement(`-- filter: "ERR" --`))
+			Expect(writer.lines()).To(ContainElement("-- paused --"))
+		})
 
-	Context("when the source is an app", func() {
-		BeforeEach(func() {
-			cliConn.cliCommandResult = [][]string{
-				{"app-guid"},
+		It("reports how many lines were buffered while paused", func() {
+			httpClient.responseBody = []string{
+				// Lines mode requests WithDescending
+				responseBody(startTime.Add(-30 * time.Second)),
+				// Walk uses ascending order
+				responseBodyAsc(startTime),
 			}
-			cliConn.usernameResp = "a-user"
-			cliConn.orgName = "organization"
-			cliConn.spaceName = "space"
+
+			ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+			defer cancel()
+			cf.Tail(
+				ctx,
+				cliConn,
+				[]string{"--interactive", "app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+				cf.WithTailInteractiveInput(strings.NewReader("  q")),
+			)
+
+			Expect(writer.lines()).To(ContainElement("-- paused --"))
+			Expect(writer.lines()).To(ContainElement(MatchRegexp(`^-- resumed, \d+ buffered --$`)))
 		})
 
-		It("reports successful results", func() {
+		It("forwards the stream as NDJSON to a local socket with --forward", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+
+			lines := make(chan string, 10)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+
 			cf.Tail(
 				context.Background(),
 				cliConn,
-				[]string{"app-name"},
+				[]string{"app-name", "--forward", "tcp://" + listener.Addr().String()},
 				httpClient,
 				logger,
 				writer,
+				cf.WithTailNoHeaders(),
 			)
-			Expect(httpClient.requestURLs).To(HaveLen(1))
 
-			requestURL, err := url.Parse(httpClient.requestURLs[0])
-			end, err := strconv.ParseInt(requestURL.Query().Get("end_time"), 10, 64)
-			Expect(err).ToNot(HaveOccurred())
-			Expect(end).To(BeNumerically("~", time.Now().UnixNano(), 10000000))
+			for i := 0; i < 3; i++ {
+				var line string
+				Eventually(lines).Should(Receive(&line))
+				Expect(line).To(ContainSubstring(`"log":`))
+			}
+		})
 
-			logFormat := "   %s [APP/PROC/WEB/0] %s log body"
-			Expect(writer.lines()).To(Equal([]string{
-				fmt.Sprintf(
-					"Retrieving logs for app %s in org %s / space %s as %s...",
-					"app-name",
-					cliConn.orgName,
-					cliConn.spaceName,
-					cliConn.usernameResp,
-				),
-				"",
-				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
-				fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT"),
-				fmt.Sprintf(logFormat, startTime.Add(2*time.Second).Format(timeFormat), "OUT"),
-			}))
+		It("fatally logs when the --forward address is invalid", func() {
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					[]string{"app-name", "--forward", "http://localhost:1234"},
+					httpClient,
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal(
+				"--forward address must be in the form tcp://host:port or udp://host:port",
+			))
+		})
+
+		It("fatally logs when --filter and --filter-regex are combined", func() {
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					[]string{"app-name", "--filter", "a", "--filter-regex", "b"},
+					httpClient,
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Cannot use --filter and --filter-regex together"))
 		})
 
 		It("reports successful results with deprecated tags", func() {
@@ -164,6 +1280,34 @@ var _ = Describe("LogCache", func() {
 			}))
 		})
 
+		It("shows counter deltas and rates with --counter-rates", func() {
+			httpClient.responseBody = []string{
+				counterRatesResponseBody(startTime),
+			}
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name", "--counter-rates"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			logFormat := "   %s [%s/%s] COUNTER %s:%d"
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(
+					"Retrieving logs for app %s in org %s / space %s as %s...",
+					"app-name",
+					cliConn.orgName,
+					cliConn.spaceName,
+					cliConn.usernameResp,
+				),
+				"",
+				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "app-name", "0", "some-name", 99),
+				fmt.Sprintf(logFormat+" (delta: 10, rate: 5.00/s)", startTime.Add(2*time.Second).Format(timeFormat), "app-name", "0", "some-name", 109),
+			}))
+		})
+
 		It("reports successful results with gauge envelopes", func() {
 			httpClient.responseBody = []string{
 				gaugeResponseBody(startTime),
@@ -196,6 +1340,33 @@ var _ = Describe("LogCache", func() {
 			}))
 		})
 
+		It("scales byte and nanosecond gauges into readable units", func() {
+			httpClient.responseBody = []string{
+				memoryGaugeResponseBody(startTime),
+			}
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			logFormat := "   %s [%s/%s] GAUGE memory:1.5 GiB response_time:2.50 ms"
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(
+					"Retrieving logs for app %s in org %s / space %s as %s...",
+					"app-name",
+					cliConn.orgName,
+					cliConn.spaceName,
+					cliConn.usernameResp,
+				),
+				"",
+				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "app-name", "0"),
+			}))
+		})
+
 		It("reports successful results with timer envelopes", func() {
 			httpClient.responseBody = []string{
 				timerResponseBody(startTime),
@@ -217,7 +1388,7 @@ var _ = Describe("LogCache", func() {
 			end, err := strconv.ParseInt(requestURL.Query().Get("end_time"), 10, 64)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(end).To(BeNumerically("~", time.Now().UnixNano(), 10000000))
-			logFormat := "   %s [%s/%s] TIMER %s %f ms"
+			logFormat := "   %s [%s/%s] TIMER %s %s"
 			Expect(writer.lines()).To(Equal([]string{
 				fmt.Sprintf(
 					"Retrieving logs for app %s in org %s / space %s as %s...",
@@ -227,7 +1398,7 @@ var _ = Describe("LogCache", func() {
 					cliConn.usernameResp,
 				),
 				"",
-				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "app-name", "0", "http", float64(time.Second)/1000000.0),
+				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "app-name", "0", "http", time.Second),
 			}))
 		})
 
@@ -428,6 +1599,45 @@ var _ = Describe("LogCache", func() {
 			))
 		})
 
+		It("backfills the most recent --lines envelopes before following", func() {
+			httpClient.responseBody = []string{
+				// Lines mode requests WithDescending
+				responseBody(startTime.Add(-30 * time.Second)),
+				// Walk uses ascending order
+				responseBodyAsc(startTime),
+			}
+			logFormat := "   %s [APP/PROC/WEB/0] %s log body"
+
+			ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+			defer cancel()
+			cf.Tail(
+				ctx,
+				cliConn,
+				[]string{"--lines", "5", "--follow", "app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			Expect(httpClient.requestURLs).ToNot(BeEmpty())
+			requestURL, err := url.Parse(httpClient.requestURLs[0])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requestURL.Query().Get("limit")).To(Equal("5"))
+			Expect(requestURL.Query().Get("descending")).To(Equal("true"))
+
+			// the walk picks up right after the backfilled envelopes
+			requestURL, err = url.Parse(httpClient.requestURLs[1])
+			Expect(err).ToNot(HaveOccurred())
+			start, err := strconv.ParseInt(requestURL.Query().Get("start_time"), 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(start).To(Equal(startTime.Add(-28*time.Second).UnixNano() + 1))
+
+			Expect(writer.lines()).To(ContainElement(
+				fmt.Sprintf(logFormat, startTime.Add(-30*time.Second).Format(timeFormat), "ERR"),
+			))
+		})
+
 		It("respects short flag for following", func() {
 			httpClient.responseBody = []string{
 				// Lines mode requests WithDescending
@@ -850,39 +2060,157 @@ var _ = Describe("LogCache", func() {
 			go cf.Tail(
 				context.Background(),
 				cliConn,
-				[]string{"--follow", "app-name"},
+				[]string{"--follow", "app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			Eventually(httpClient.requestCount).Should(BeNumerically(">", 3))
+		})
+
+		It("follow retries for an error", func() {
+			httpClient.responseBody = nil
+			httpClient.responseErr = errors.New("some-error")
+
+			go cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--follow", "app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			Eventually(httpClient.requestCount).Should(BeNumerically(">", 2))
+		})
+
+		It("reports successful results with event envelopes", func() {
+			httpClient.responseBody = []string{
+				eventResponseBody(startTime),
+			}
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"app-name"},
+				httpClient,
+				logger,
+				writer,
+			)
+
+			Expect(httpClient.requestURLs).To(HaveLen(1))
+			requestURL, err := url.Parse(httpClient.requestURLs[0])
+			end, err := strconv.ParseInt(requestURL.Query().Get("end_time"), 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(end).To(BeNumerically("~", time.Now().UnixNano(), 10000000))
+			logFormat := "   %s [%s/%s] EVENT %s:%s"
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(
+					"Retrieving logs for app %s in org %s / space %s as %s...",
+					"app-name",
+					cliConn.orgName,
+					cliConn.spaceName,
+					cliConn.usernameResp,
+				),
+				"",
+				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "app-name", "0", "some-title", "some-body"),
+			}))
+		})
+
+		It("exits with the usage code for invalid arguments", func() {
+			codeLogger := &stubCodeLogger{}
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					nil,
+					httpClient,
+					codeLogger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(codeLogger.fatalfCode).To(Equal(cf.ExitCodeUsage))
+		})
+
+		It("exits with the auth code when the access token cannot be fetched", func() {
+			codeLogger := &stubCodeLogger{}
+			cliConn.accessTokenErr = errors.New("some-error")
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					[]string{"app-name"},
+					httpClient,
+					codeLogger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(codeLogger.fatalfCode).To(Equal(cf.ExitCodeAuth))
+		})
+
+		It("suppresses the preamble with --quiet", func() {
+			httpClient.responseBody = []string{
+				responseBody(startTime),
+			}
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--quiet", "app-name"},
 				httpClient,
 				logger,
 				writer,
 			)
 
-			Eventually(httpClient.requestCount).Should(BeNumerically(">", 3))
+			logFormat := "   %s [APP/PROC/WEB/0] %s log body"
+			Expect(writer.lines()).To(Equal([]string{
+				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "ERR"),
+				fmt.Sprintf(logFormat, startTime.Add(1*time.Second).Format(timeFormat), "OUT"),
+				fmt.Sprintf(logFormat, startTime.Add(2*time.Second).Format(timeFormat), "OUT"),
+			}))
 		})
 
-		It("follow retries for an error", func() {
-			httpClient.responseBody = nil
-			httpClient.responseErr = errors.New("some-error")
-
-			go cf.Tail(
+		It("accepts start-time, end-time, envelope-type, and lines flags", func() {
+			args := []string{
+				"--start-time", "100",
+				"--end-time", "123",
+				"--envelope-type", "gauge", // deliberately lowercase
+				"--lines", "99",
+				"app-name",
+			}
+			cf.Tail(
 				context.Background(),
 				cliConn,
-				[]string{"--follow", "app-name"},
+				args,
 				httpClient,
 				logger,
 				writer,
 			)
 
-			Eventually(httpClient.requestCount).Should(BeNumerically(">", 2))
+			Expect(httpClient.requestURLs).To(HaveLen(1))
+			requestURL, err := url.Parse(httpClient.requestURLs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requestURL.Scheme).To(Equal("https"))
+			Expect(requestURL.Host).To(Equal("log-cache.some-system.com"))
+			Expect(requestURL.Path).To(Equal("/v1/read/app-guid"))
+			Expect(requestURL.Query().Get("start_time")).To(Equal("100"))
+			Expect(requestURL.Query().Get("end_time")).To(Equal("123"))
+			Expect(requestURL.Query().Get("envelope_types")).To(Equal("GAUGE"))
+			Expect(requestURL.Query().Get("descending")).To(Equal("true"))
+			Expect(requestURL.Query().Get("limit")).To(Equal("99"))
 		})
 
-		It("reports successful results with event envelopes", func() {
-			httpClient.responseBody = []string{
-				eventResponseBody(startTime),
+		It("accepts RFC3339 and calendar values for start-time and end-time", func() {
+			args := []string{
+				"--start-time", "2026-08-30T10:00:00Z",
+				"--end-time", "2026-08-30 11:30",
+				"app-name",
 			}
 			cf.Tail(
 				context.Background(),
 				cliConn,
-				[]string{"app-name"},
+				args,
 				httpClient,
 				logger,
 				writer,
@@ -890,29 +2218,17 @@ var _ = Describe("LogCache", func() {
 
 			Expect(httpClient.requestURLs).To(HaveLen(1))
 			requestURL, err := url.Parse(httpClient.requestURLs[0])
-			end, err := strconv.ParseInt(requestURL.Query().Get("end_time"), 10, 64)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(end).To(BeNumerically("~", time.Now().UnixNano(), 10000000))
-			logFormat := "   %s [%s/%s] EVENT %s:%s"
-			Expect(writer.lines()).To(Equal([]string{
-				fmt.Sprintf(
-					"Retrieving logs for app %s in org %s / space %s as %s...",
-					"app-name",
-					cliConn.orgName,
-					cliConn.spaceName,
-					cliConn.usernameResp,
-				),
-				"",
-				fmt.Sprintf(logFormat, startTime.Format(timeFormat), "app-name", "0", "some-title", "some-body"),
-			}))
+
+			start := time.Date(2026, 8, 30, 10, 0, 0, 0, time.UTC)
+			end := time.Date(2026, 8, 30, 11, 30, 0, 0, time.UTC)
+			Expect(requestURL.Query().Get("start_time")).To(Equal(strconv.FormatInt(start.UnixNano(), 10)))
+			Expect(requestURL.Query().Get("end_time")).To(Equal(strconv.FormatInt(end.UnixNano(), 10)))
 		})
 
-		It("accepts start-time, end-time, envelope-type, and lines flags", func() {
+		It("accepts relative durations for start-time", func() {
 			args := []string{
-				"--start-time", "100",
-				"--end-time", "123",
-				"--envelope-type", "gauge", // deliberately lowercase
-				"--lines", "99",
+				"--start-time=-15m",
 				"app-name",
 			}
 			cf.Tail(
@@ -927,14 +2243,26 @@ var _ = Describe("LogCache", func() {
 			Expect(httpClient.requestURLs).To(HaveLen(1))
 			requestURL, err := url.Parse(httpClient.requestURLs[0])
 			Expect(err).ToNot(HaveOccurred())
-			Expect(requestURL.Scheme).To(Equal("https"))
-			Expect(requestURL.Host).To(Equal("log-cache.some-system.com"))
-			Expect(requestURL.Path).To(Equal("/v1/read/app-guid"))
-			Expect(requestURL.Query().Get("start_time")).To(Equal("100"))
-			Expect(requestURL.Query().Get("end_time")).To(Equal("123"))
-			Expect(requestURL.Query().Get("envelope_types")).To(Equal("GAUGE"))
-			Expect(requestURL.Query().Get("descending")).To(Equal("true"))
-			Expect(requestURL.Query().Get("limit")).To(Equal("99"))
+
+			start, err := strconv.ParseInt(requestURL.Query().Get("start_time"), 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(start).To(BeNumerically("~", time.Now().Add(-15*time.Minute).UnixNano(), int64(5*time.Second)))
+		})
+
+		It("fatally logs when start-time cannot be parsed", func() {
+			args := []string{"--start-time", "half past nine", "app-name"}
+			Expect(func() {
+				cf.Tail(
+					context.Background(),
+					cliConn,
+					args,
+					httpClient,
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(HavePrefix("Couldn't parse --start-time:"))
 		})
 
 		It("accepts lines flags (short)", func() {
@@ -1160,23 +2488,41 @@ var _ = Describe("LogCache", func() {
 			Expect(logger.fatalfMessage).To(Equal(`Output template parsed, but failed to execute: template: OutputFormat:1:2: executing "OutputFormat" at <.invalid>: can't evaluate field invalid in type *loggregator_v2.Envelope`))
 		})
 
-		It("fatally logs if lines is greater than 1000", func() {
-			args := []string{
-				"--lines", "1001",
-				"some-app",
+		It("pages transparently when --lines exceeds a single batch", func() {
+			oldest := startTime.Add(-999 * time.Second)
+			httpClient.responseBody = []string{
+				descendingResponseBody(startTime, 1000),
+				// the second page repeats the boundary envelope before older
+				// ones
+				descendingResponseBody(oldest, 2),
 			}
-			Expect(func() {
-				cf.Tail(
-					context.Background(),
-					cliConn,
-					args,
-					httpClient,
-					logger,
-					writer,
-				)
-			}).To(Panic())
 
-			Expect(logger.fatalfMessage).To(Equal("Lines cannot be greater than 1000."))
+			cf.Tail(
+				context.Background(),
+				cliConn,
+				[]string{"--lines", "1500", "app-name"},
+				httpClient,
+				logger,
+				writer,
+				cf.WithTailNoHeaders(),
+			)
+
+			Expect(httpClient.requestURLs).To(HaveLen(2))
+
+			requestURL, err := url.Parse(httpClient.requestURLs[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requestURL.Query().Get("limit")).To(Equal("1000"))
+
+			requestURL, err = url.Parse(httpClient.requestURLs[1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requestURL.Query().Get("limit")).To(Equal("500"))
+			Expect(requestURL.Query().Get("end_time")).To(Equal(
+				strconv.FormatInt(oldest.UnixNano()+1, 10),
+			))
+
+			// 1000 envelopes from the first page plus one new from the
+			// second; the boundary duplicate is dropped
+			Expect(writer.lines()).To(HaveLen(1001))
 		})
 
 		It("accepts 0 for --lines", func() {
@@ -1282,21 +2628,6 @@ var _ = Describe("LogCache", func() {
 			Expect(logger.fatalfMessage).To(Equal("Invalid name filter '*foo'. Ensure your name-filter is a valid regex."))
 		})
 
-		It("fatally logs if too many arguments are given", func() {
-			Expect(func() {
-				cf.Tail(
-					context.Background(),
-					cliConn,
-					[]string{"one", "two"},
-					httpClient,
-					logger,
-					writer,
-				)
-			}).To(Panic())
-
-			Expect(logger.fatalfMessage).To(Equal("Expected 1 argument, got 2."))
-		})
-
 		It("fatally logs if not enough arguments are given", func() {
 			Expect(func() {
 				cf.Tail(
@@ -1309,7 +2640,7 @@ var _ = Describe("LogCache", func() {
 				)
 			}).To(Panic())
 
-			Expect(logger.fatalfMessage).To(Equal("Expected 1 argument, got 0."))
+			Expect(logger.fatalfMessage).To(Equal("Expected at least 1 argument, got 0."))
 		})
 
 		Context("when name-filter argument is not supplied", func() {
@@ -1629,6 +2960,126 @@ var _ = Describe("LogCache", func() {
 	})
 })
 
+var _ = Describe("NewRetryAfterClient", func() {
+	It("retries a rate limited request after the Retry-After delay", func() {
+		underlying := &sequencedHTTPClient{
+			responses: []*http.Response{
+				{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": []string{"0"}},
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				},
+				{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				},
+			},
+		}
+		client := cf.NewRetryAfterClient(underlying)
+
+		req, err := http.NewRequest("GET", "http://some.url/v1/read/app-guid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(underlying.requests).To(Equal(2))
+	})
+
+	It("replays the request body when retrying a POST", func() {
+		underlying := &bodyRecordingHTTPClient{
+			responses: []*http.Response{
+				{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": []string{"0"}},
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				},
+				{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				},
+			},
+		}
+		client := cf.NewRetryAfterClient(underlying)
+
+		req, err := http.NewRequest("POST", "http://some.url/v2/envelopes", strings.NewReader("payload"))
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(underlying.bodies).To(Equal([]string{"payload", "payload"}))
+	})
+
+	It("does not retry when the request body cannot be replayed", func() {
+		underlying := &sequencedHTTPClient{}
+		client := cf.NewRetryAfterClient(underlying)
+
+		req, err := http.NewRequest("POST", "http://some.url/v2/envelopes", strings.NewReader("payload"))
+		Expect(err).ToNot(HaveOccurred())
+		req.GetBody = nil
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(underlying.requests).To(Equal(1))
+	})
+
+	It("gives up after repeated 429 responses", func() {
+		underlying := &sequencedHTTPClient{}
+		client := cf.NewRetryAfterClient(underlying)
+
+		req, err := http.NewRequest("GET", "http://some.url/v1/read/app-guid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(underlying.requests).To(Equal(4))
+	})
+})
+
+// sequencedHTTPClient serves canned responses in order, then 429s with an
+// immediate Retry-After so retry tests don't sleep.
+type sequencedHTTPClient struct {
+	responses []*http.Response
+	requests  int
+}
+
+func (s *sequencedHTTPClient) Do(*http.Request) (*http.Response, error) {
+	s.requests++
+	if len(s.responses) == 0 {
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"0"}},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil
+	}
+
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+// bodyRecordingHTTPClient serves canned responses in order and records the
+// body sent with each request.
+type bodyRecordingHTTPClient struct {
+	responses []*http.Response
+	bodies    []string
+}
+
+func (b *bodyRecordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	b.bodies = append(b.bodies, string(body))
+
+	resp := b.responses[0]
+	b.responses = b.responses[1:]
+	return resp, nil
+}
+
 func responseBody(startTime time.Time) string {
 	// NOTE: These are in descending order.
 	return fmt.Sprintf(responseTemplate,
@@ -1647,6 +3098,29 @@ func responseBodyAsc(startTime time.Time) string {
 	)
 }
 
+func interleavedDupResponseBody(startTime time.Time) string {
+	// NOTE: These are in descending order. The OUT payloads are identical so
+	// the second one falls inside a dedupe window of two.
+	return fmt.Sprintf(interleavedDupResponseTemplate,
+		startTime.Add(2*time.Second).UnixNano(),
+		startTime.Add(1*time.Second).UnixNano(),
+		startTime.UnixNano(),
+	)
+}
+
+func descendingResponseBody(newest time.Time, count int) string {
+	// NOTE: These are in descending order, one second apart, starting at
+	// newest.
+	var envelopes []string
+	for i := 0; i < count; i++ {
+		envelopes = append(envelopes, fmt.Sprintf(
+			`{"timestamp":"%d","source_id":"app-name","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"bG9nIGJvZHk="}}`,
+			newest.Add(-time.Duration(i)*time.Second).UnixNano(),
+		))
+	}
+	return fmt.Sprintf(`{"envelopes":{"batch":[%s]}}`, strings.Join(envelopes, ","))
+}
+
 func responseBodyWithNewLine(startTime time.Time, newLine rune) string {
 	// NOTE: These are in descending order.
 	payload := fmt.Sprintf("log%sbody", string(newLine))
@@ -1686,12 +3160,26 @@ func counterResponseBody(startTime time.Time) string {
 	)
 }
 
+func counterRatesResponseBody(startTime time.Time) string {
+	// NOTE: These are in descending order.
+	return fmt.Sprintf(counterRatesResponseTemplate,
+		startTime.Add(2*time.Second).UnixNano(),
+		startTime.UnixNano(),
+	)
+}
+
 func gaugeResponseBody(startTime time.Time) string {
 	return fmt.Sprintf(gaugeResponseTemplate,
 		startTime.UnixNano(),
 	)
 }
 
+func memoryGaugeResponseBody(startTime time.Time) string {
+	return fmt.Sprintf(memoryGaugeResponseTemplate,
+		startTime.UnixNano(),
+	)
+}
+
 func timerResponseBody(startTime time.Time) string {
 	return fmt.Sprintf(timerResponseTemplate,
 		startTime.UnixNano(),
@@ -1765,6 +3253,47 @@ var responseTemplate = `{
 	}
 }`
 
+var interleavedDupResponseTemplate = `{
+	"envelopes": {
+		"batch": [
+			{
+				"timestamp":"%d",
+				"source_id": "app-name",
+				"instance_id":"0",
+				"tags":{
+					"source_type":"APP/PROC/WEB"
+				},
+				"log":{
+					"payload":"bG9nIGJvZHk="
+				}
+			},
+			{
+				"timestamp":"%d",
+				"source_id": "app-name",
+				"instance_id":"0",
+				"tags":{
+					"source_type":"APP/PROC/WEB"
+				},
+				"log":{
+					"payload":"bG9nIGJvZHk=",
+					"type": "ERR"
+				}
+			},
+			{
+				"timestamp":"%d",
+				"source_id": "app-name",
+				"instance_id":"0",
+				"tags":{
+					"source_type":"APP/PROC/WEB"
+				},
+				"log":{
+					"payload":"bG9nIGJvZHk="
+				}
+			}
+		]
+	}
+}`
+
 var responseTemplateWithNewLine = `{
 	"envelopes": {
 		"batch": [
@@ -1850,6 +3379,49 @@ var counterResponseTemplate = `{
 	}
 }`
 
+var memoryGaugeResponseTemplate = `{
+	"envelopes": {
+		"batch": [
+			{
+				"source_id": "app-name",
+				"instance_id":"0",
+				"timestamp": "%d",
+				"gauge": {
+				  "metrics": {
+					"memory": {
+					  "value": 1610612736,
+					  "unit":"bytes"
+					},
+					"response_time": {
+					  "value": 2500000,
+					  "unit":"nanoseconds"
+					}
+				  }
+				}
+			  }
+		]
+	}
+}`
+
+var counterRatesResponseTemplate = `{
+	"envelopes": {
+		"batch": [
+			{
+				"source_id": "app-name",
+				"instance_id":"0",
+				"timestamp":"%d",
+				"counter":{"name":"some-name","total":109}
+			},
+			{
+				"source_id": "app-name",
+				"instance_id":"0",
+				"timestamp":"%d",
+				"counter":{"name":"some-name","total":99}
+			}
+		]
+	}
+}`
+
 var gaugeResponseTemplate = `{
 	"envelopes": {
 		"batch": [
@@ -0,0 +1,235 @@
+package cf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// Telemetry is strictly opt-in and strictly local: with "telemetry": true in
+// ~/.cf/log-cache-cli/config.json, each command invocation updates on-disk
+// usage counters that `cf usage-report` prints. Nothing is ever sent over
+// the network.
+
+type cliConfig struct {
+	Telemetry bool `json:"telemetry"`
+}
+
+func configPath() string {
+	base := os.Getenv("CF_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = home
+	}
+
+	return filepath.Join(base, ".cf", "log-cache-cli", "config.json")
+}
+
+func usagePath() string {
+	path := configPath()
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Join(filepath.Dir(path), "usage.json")
+}
+
+func telemetryEnabled() bool {
+	path := configPath()
+	if path == "" {
+		return false
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var c cliConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return false
+	}
+
+	return c.Telemetry
+}
+
+type usageEntry struct {
+	Count           int64 `json:"count"`
+	TotalDurationNs int64 `json:"total_duration_ns"`
+	ResultBytes     int64 `json:"result_bytes"`
+}
+
+func loadUsage() (map[string]usageEntry, error) {
+	path := usagePath()
+	if path == "" {
+		return nil, fmt.Errorf("could not determine the usage path")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage map[string]usageEntry
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// TelemetryRecorder accumulates local usage counters for one command
+// invocation.
+type TelemetryRecorder struct {
+	mu sync.Mutex
+
+	command     string
+	start       time.Time
+	resultBytes int64
+}
+
+// NewTelemetryRecorder returns a recorder for the named command, or nil when
+// telemetry has not been opted into.
+func NewTelemetryRecorder(command string) *TelemetryRecorder {
+	if !telemetryEnabled() {
+		return nil
+	}
+
+	return &TelemetryRecorder{
+		command: command,
+		start:   time.Now(),
+	}
+}
+
+// Client wraps the given client so response sizes count towards the
+// invocation's result bytes.
+func (t *TelemetryRecorder) Client(c HTTPClient) HTTPClient {
+	return &meteredHTTPClient{c: c, recorder: t}
+}
+
+// Finish folds the invocation into the usage file. Failures are deliberately
+// swallowed; telemetry must never break the command itself.
+func (t *TelemetryRecorder) Finish() {
+	t.mu.Lock()
+	duration := time.Since(t.start)
+	resultBytes := t.resultBytes
+	t.mu.Unlock()
+
+	usage, _ := loadUsage()
+	if usage == nil {
+		usage = make(map[string]usageEntry)
+	}
+
+	e := usage[t.command]
+	e.Count++
+	e.TotalDurationNs += int64(duration)
+	e.ResultBytes += resultBytes
+	usage[t.command] = e
+
+	path := usagePath()
+	if path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return
+	}
+
+	_ = os.MkdirAll(filepath.Dir(path), 0755)
+	_ = ioutil.WriteFile(path, data, 0644)
+}
+
+func (t *TelemetryRecorder) addResultBytes(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.resultBytes += n
+}
+
+type meteredHTTPClient struct {
+	c        HTTPClient
+	recorder *TelemetryRecorder
+}
+
+func (c *meteredHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.c.Do(req)
+	if resp != nil && resp.Body != nil {
+		resp.Body = &countingReadCloser{rc: resp.Body, recorder: c.recorder}
+	}
+	return resp, err
+}
+
+type countingReadCloser struct {
+	rc       io.ReadCloser
+	recorder *TelemetryRecorder
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.recorder.addResultBytes(int64(n))
+	return n, err
+}
+
+func (r *countingReadCloser) Close() error {
+	return r.rc.Close()
+}
+
+// UsageReport prints the locally recorded usage counters.
+func UsageReport(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+) {
+	if len(args) != 0 {
+		log.Fatalf("Expected 0 arguments, got %d.", len(args))
+	}
+
+	usage, err := loadUsage()
+	if err != nil || len(usage) == 0 {
+		lw := lineWriter{w: w}
+		lw.Write(fmt.Sprintf(
+			"No usage recorded. Opt in with \"telemetry\": true in %s.",
+			configPath(),
+		))
+		return
+	}
+
+	var names []string
+	for name := range usage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "Command\tCount\tTotal Duration\tResult Bytes")
+	for _, name := range names {
+		e := usage[name]
+		fmt.Fprintf(
+			tw,
+			"%s\t%d\t%s\t%d\n",
+			name,
+			e.Count,
+			time.Duration(e.TotalDurationNs).Truncate(time.Millisecond),
+			e.ResultBytes,
+		)
+	}
+	tw.Flush()
+}
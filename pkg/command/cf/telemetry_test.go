@@ -0,0 +1,149 @@
+package cf_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Telemetry", func() {
+	var cfHome string
+
+	BeforeEach(func() {
+		var err error
+		cfHome, err = ioutil.TempDir("", "telemetry")
+		Expect(err).ToNot(HaveOccurred())
+		os.Setenv("CF_HOME", cfHome)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("CF_HOME")
+	})
+
+	writeConfig := func() {
+		dir := filepath.Join(cfHome, ".cf", "log-cache-cli")
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(
+			filepath.Join(dir, "config.json"),
+			[]byte(`{"telemetry":true}`),
+			0644,
+		)).To(Succeed())
+	}
+
+	It("is disabled without the config opt-in", func() {
+		Expect(cf.NewTelemetryRecorder("tail")).To(BeNil())
+	})
+
+	It("records command, duration, and result size to the usage file", func() {
+		writeConfig()
+
+		recorder := cf.NewTelemetryRecorder("tail")
+		Expect(recorder).ToNot(BeNil())
+
+		httpClient := newStubHTTPClient()
+		httpClient.responseBody = []string{`{"envelopes":{"batch":[]}}`}
+
+		metered := recorder.Client(httpClient)
+		req, err := http.NewRequest("GET", "https://log-cache.example.com/api/v1/meta", nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := metered.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		recorder.Finish()
+		recorder = cf.NewTelemetryRecorder("tail")
+		recorder.Finish()
+
+		data, err := ioutil.ReadFile(filepath.Join(cfHome, ".cf", "log-cache-cli", "usage.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var usage map[string]struct {
+			Count           int64 `json:"count"`
+			TotalDurationNs int64 `json:"total_duration_ns"`
+			ResultBytes     int64 `json:"result_bytes"`
+		}
+		Expect(json.Unmarshal(data, &usage)).To(Succeed())
+
+		Expect(usage["tail"].Count).To(Equal(int64(2)))
+		Expect(usage["tail"].TotalDurationNs).To(BeNumerically(">=", 0))
+		Expect(usage["tail"].ResultBytes).To(Equal(int64(len(`{"envelopes":{"batch":[]}}`))))
+	})
+
+	Describe("UsageReport", func() {
+		var (
+			logger *stubLogger
+			writer *stubWriter
+		)
+
+		BeforeEach(func() {
+			logger = &stubLogger{}
+			writer = &stubWriter{}
+		})
+
+		It("prints the recorded counters as a table", func() {
+			writeConfig()
+			dir := filepath.Join(cfHome, ".cf", "log-cache-cli")
+			Expect(ioutil.WriteFile(
+				filepath.Join(dir, "usage.json"),
+				[]byte(`{
+					"tail": {"count": 3, "total_duration_ns": 1500000000, "result_bytes": 2048},
+					"query": {"count": 1, "total_duration_ns": 250000000, "result_bytes": 64}
+				}`),
+				0644,
+			)).To(Succeed())
+
+			cf.UsageReport(
+				context.Background(),
+				newStubCliConnection(),
+				nil,
+				newStubHTTPClient(),
+				logger,
+				writer,
+			)
+
+			Expect(writer.lines()).To(Equal([]string{
+				"Command  Count  Total Duration  Result Bytes",
+				"query    1      250ms           64",
+				"tail     3      1.5s            2048",
+			}))
+		})
+
+		It("explains how to opt in when nothing is recorded", func() {
+			cf.UsageReport(
+				context.Background(),
+				newStubCliConnection(),
+				nil,
+				newStubHTTPClient(),
+				logger,
+				writer,
+			)
+
+			Expect(writer.lines()).To(HaveLen(1))
+			Expect(writer.lines()[0]).To(HavePrefix("No usage recorded."))
+		})
+
+		It("fatally logs when arguments are given", func() {
+			Expect(func() {
+				cf.UsageReport(
+					context.Background(),
+					newStubCliConnection(),
+					[]string{"extra"},
+					newStubHTTPClient(),
+					logger,
+					writer,
+				)
+			}).To(Panic())
+
+			Expect(logger.fatalfMessage).To(Equal("Expected 0 arguments, got 1."))
+		})
+	})
+})
@@ -0,0 +1,34 @@
+package cf
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// parseTimeFlag converts a --start-time/--end-time value to epoch
+// nanoseconds. Raw epoch nanoseconds are accepted as before, along with
+// RFC3339 timestamps, "YYYY-MM-DD HH:MM" (read as UTC), and durations such
+// as "-15m", which are relative to now.
+func parseTimeFlag(value string, now time.Time) (int64, error) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UnixNano(), nil
+	}
+
+	if t, err := time.Parse("2006-01-02 15:04", value); err == nil {
+		return t.UnixNano(), nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		if d > 0 {
+			d = -d
+		}
+		return now.Add(d).UnixNano(), nil
+	}
+
+	return 0, fmt.Errorf("invalid time format: %s", value)
+}
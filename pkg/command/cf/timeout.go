@@ -0,0 +1,45 @@
+package cf
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// timeoutHTTPClient bounds every request with a context deadline so a hung
+// log-cache node cannot block the CLI indefinitely.
+type timeoutHTTPClient struct {
+	c       HTTPClient
+	timeout time.Duration
+}
+
+// NewTimeoutClient wraps the client so each request is canceled after the
+// given duration.
+func NewTimeoutClient(c HTTPClient, timeout time.Duration) HTTPClient {
+	return &timeoutHTTPClient{c: c, timeout: timeout}
+}
+
+func (t *timeoutHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	resp, err := t.c.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the deadline has to survive until the body is consumed
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,54 @@
+package cf_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// blockingHTTPClient hangs until the request's context is canceled.
+type blockingHTTPClient struct{}
+
+func (b *blockingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+var _ = Describe("NewTimeoutClient", func() {
+	It("cancels a hung request after the timeout", func() {
+		req, err := http.NewRequest("GET", "https://log-cache.some-system.com/api/v1/read/app-guid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		start := time.Now()
+		_, err = cf.NewTimeoutClient(&blockingHTTPClient{}, time.Millisecond).Do(req)
+
+		Expect(err).To(MatchError(ContainSubstring("deadline exceeded")))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("leaves a fast response readable", func() {
+		stub := &sequencedHTTPClient{responses: []*http.Response{
+			{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"envelopes":{"batch":[]}}`)),
+			},
+		}}
+
+		req, err := http.NewRequest("GET", "https://log-cache.some-system.com/api/v1/read/app-guid", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := cf.NewTimeoutClient(stub, time.Minute).Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(`{"envelopes":{"batch":[]}}`))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+})
@@ -0,0 +1,297 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
+	flags "github.com/jessevdk/go-flags"
+)
+
+const (
+	topSortByRate   = "rate"
+	topSortByCount  = "count"
+	topSortBySource = "source"
+)
+
+type TopOption func(*topOptionFlags)
+
+// WithTopSleepDuration overrides the sampling interval sleep, used in tests.
+func WithTopSleepDuration(d time.Duration) TopOption {
+	return func(o *topOptionFlags) {
+		o.sleepDuration = &d
+	}
+}
+
+// WithTopClearScreen redraws each refresh over the previous one instead of
+// appending, used when writing to a terminal.
+func WithTopClearScreen() TopOption {
+	return func(o *topOptionFlags) {
+		o.clearScreen = true
+	}
+}
+
+// WithTopInput overrides where the key bindings read key presses from, used
+// in tests.
+func WithTopInput(r io.Reader) TopOption {
+	return func(o *topOptionFlags) {
+		o.input = r
+	}
+}
+
+// topControls holds the view state the key bindings mutate while top runs:
+//
+//	s  cycle the sort column: rate, count, source
+//	a  cycle the source type scope: all, application, service, platform
+//	q  quit
+type topControls struct {
+	mu     sync.Mutex
+	sortBy string
+	scope  sourceType
+	quit   bool
+}
+
+// view returns the sort column and scope to use for the next refresh. An
+// empty scope means every source type is shown.
+func (t *topControls) view() (string, sourceType) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.sortBy, t.scope
+}
+
+// done reports whether the session has been quit.
+func (t *topControls) done() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.quit
+}
+
+// handleInput consumes key presses until the reader is exhausted or the
+// session quits. It is meant to run on its own goroutine.
+func (t *topControls) handleInput(r io.Reader) {
+	buf := make([]byte, 1)
+
+	for {
+		n, err := r.Read(buf)
+		if n == 0 {
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		switch buf[0] {
+		case 's':
+			t.mu.Lock()
+			switch t.sortBy {
+			case topSortByRate:
+				t.sortBy = topSortByCount
+			case topSortByCount:
+				t.sortBy = topSortBySource
+			default:
+				t.sortBy = topSortByRate
+			}
+			t.mu.Unlock()
+		case 'a':
+			t.mu.Lock()
+			switch t.scope {
+			case "":
+				t.scope = sourceTypeApplication
+			case sourceTypeApplication:
+				t.scope = sourceTypeService
+			case sourceTypeService:
+				t.scope = sourceTypePlatform
+			default:
+				t.scope = ""
+			}
+			t.mu.Unlock()
+		case 'q':
+			t.mu.Lock()
+			t.quit = true
+			t.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Top continuously samples envelope rates per source and renders a sorted,
+// auto-refreshing table, like `top` for the firehose. While it runs, key
+// presses adjust the view: 's' cycles the sort column, 'a' cycles the source
+// type scope, and 'q' quits.
+func Top(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+	topts ...TopOption,
+) {
+	opts := getTopOptions(args, log, topts...)
+	v := newVerboseLogger(log, len(opts.Verbose))
+	client := createLogCacheClient(newVerboseHTTPClient(c, v), log, cli, opts.InsecureHTTP)
+
+	interval := time.Duration(opts.Interval) * time.Second
+	sleep := interval
+	if opts.sleepDuration != nil {
+		sleep = *opts.sleepDuration
+	}
+
+	prev, err := client.Meta(ctx)
+	if err != nil {
+		fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+	}
+
+	controls := &topControls{sortBy: opts.SortBy}
+	go controls.handleInput(opts.input)
+
+	for i := 0; opts.Iterations == 0 || i < opts.Iterations; i++ {
+		time.Sleep(sleep)
+
+		if controls.done() {
+			return
+		}
+
+		current, err := client.Meta(ctx)
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read Meta information: %s", err)
+		}
+
+		resources, err := getSourceInfoWithCache(current, cli, false, newProgressReporter(log))
+		if err != nil {
+			fatalf(log, ExitCodeNetwork, "Failed to read application information: %s", err)
+		}
+
+		sortBy, scope := controls.view()
+		renderTop(w, opts, interval, sortBy, scope, prev, current, resources, log)
+		prev = current
+	}
+}
+
+type topRow struct {
+	source     string
+	sourceType sourceType
+	count      int64
+	rate       int64
+}
+
+func renderTop(
+	w io.Writer,
+	opts topOptionFlags,
+	interval time.Duration,
+	sortBy string,
+	scope sourceType,
+	prev, current map[string]*logcache_v1.MetaInfo,
+	resources map[string]source,
+	log Logger,
+) {
+	var rows []topRow
+	for sourceID, m := range current {
+		row := topRow{source: sourceID, sourceType: sourceTypePlatform, count: m.Count}
+		if s, ok := resources[sourceID]; ok {
+			row.source = s.Name
+			row.sourceType = s.Type
+		} else if appOrServiceRegex.MatchString(sourceID) {
+			row.sourceType = sourceTypeUnknown
+		}
+
+		if scope != "" && row.sourceType != scope {
+			continue
+		}
+
+		if p, ok := prev[sourceID]; ok {
+			diff := (m.Count + m.Expired) - (p.Count + p.Expired)
+			row.rate = diff * int64(time.Minute) / int64(interval)
+		}
+
+		rows = append(rows, row)
+	}
+
+	switch sortBy {
+	case topSortByCount:
+		sort.Slice(rows, func(i, j int) bool { return rows[i].count > rows[j].count })
+	case topSortBySource:
+		sort.Slice(rows, func(i, j int) bool { return rows[i].source < rows[j].source })
+	default:
+		sort.Slice(rows, func(i, j int) bool { return rows[i].rate > rows[j].rate })
+	}
+
+	if len(rows) > opts.Lines {
+		rows = rows[:opts.Lines]
+	}
+
+	if opts.clearScreen {
+		fmt.Fprint(w, "\x1b[2J\x1b[H")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "Source\tSource Type\tCount\tRate/minute")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", r.source, r.sourceType, r.count, r.rate)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatalf("Error writing results")
+	}
+	fmt.Fprintln(w)
+}
+
+type topOptionFlags struct {
+	Interval     int64  `long:"interval" default:"5"`
+	Lines        int    `long:"lines" short:"n" default:"25"`
+	SortBy       string `long:"sort-by" default:"rate"`
+	Iterations   int    `long:"iterations"`
+	InsecureHTTP bool   `long:"insecure-http"`
+	Verbose      []bool `short:"v" long:"verbose"`
+
+	sleepDuration *time.Duration
+	clearScreen   bool
+	input         io.Reader
+}
+
+func getTopOptions(args []string, log Logger, topts ...TopOption) topOptionFlags {
+	opts := topOptionFlags{
+		input: os.Stdin,
+	}
+
+	for _, o := range topts {
+		o(&opts)
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		fatalf(log, ExitCodeUsage, "Could not parse flags: %s", err)
+	}
+
+	if len(args) > 0 {
+		log.Fatalf("Invalid arguments, expected 0, got %d.", len(args))
+	}
+
+	if opts.Interval < 1 {
+		log.Fatalf("--interval must be at least 1 second.")
+	}
+
+	if opts.Lines < 1 {
+		log.Fatalf("--lines must be at least 1.")
+	}
+
+	opts.SortBy = strings.ToLower(opts.SortBy)
+	if opts.SortBy != topSortByRate && opts.SortBy != topSortByCount && opts.SortBy != topSortBySource {
+		log.Fatalf("Sort by must be 'rate', 'count', or 'source'.")
+	}
+
+	if opts.Iterations < 0 {
+		log.Fatalf("--iterations cannot be negative.")
+	}
+
+	return opts
+}
@@ -0,0 +1,219 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Top", func() {
+	var (
+		logger      *stubLogger
+		httpClient  *stubHTTPClient
+		cliConn     *stubCliConnection
+		tableWriter *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		cliConn.usernameResp = "a-user"
+		tableWriter = bytes.NewBuffer(nil)
+
+		cfHome, err := ioutil.TempDir("", "")
+		Expect(err).ToNot(HaveOccurred())
+		_ = os.Setenv("CF_HOME", cfHome)
+	})
+
+	AfterEach(func() {
+		_ = os.Unsetenv("CF_HOME")
+	})
+
+	It("renders a rate-sorted table for each refresh", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			metaResponseInfoButHigher("source-1"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Top(
+			context.Background(),
+			cliConn,
+			[]string{"--iterations", "1"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithTopSleepDuration(0),
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Source  Source Type  Count   Rate/minute",
+			"app-1   application  100004  60",
+			"",
+			"",
+		}))
+
+		Expect(httpClient.requestCount()).To(Equal(2))
+	})
+
+	It("clears the screen between refreshes when requested", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+			metaResponseInfoButHigher("source-1"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Top(
+			context.Background(),
+			cliConn,
+			[]string{"--iterations", "1"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithTopSleepDuration(0),
+			cf.WithTopClearScreen(),
+		)
+
+		Expect(tableWriter.String()).To(HavePrefix("\x1b[2J\x1b[H"))
+	})
+
+	It("restricts the table to a source type scope with the 'a' key", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1", "doppler"),
+			metaResponseInfoButHigher("source-1", "doppler"),
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{"source-1": "app-1"}),
+			},
+			{
+				capiServiceInstancesResponse(nil),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Top(
+			context.Background(),
+			cliConn,
+			[]string{"--iterations", "1"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithTopSleepDuration(50*time.Millisecond),
+			cf.WithTopInput(strings.NewReader("a")),
+		)
+
+		Expect(strings.Split(tableWriter.String(), "\n")).To(Equal([]string{
+			"Source  Source Type  Count   Rate/minute",
+			"app-1   application  100004  60",
+			"",
+			"",
+		}))
+	})
+
+	It("changes the sort column with the 's' key", func() {
+		httpClient.responseBody = []string{
+			`{"meta":{"source-1":{"count":"100"},"source-2":{"count":"100"}}}`,
+			`{"meta":{"source-1":{"count":"700"},"source-2":{"count":"160"}}}`,
+		}
+
+		cliConn.cliCommandResult = [][]string{
+			{
+				capiAppsResponse(map[string]string{
+					"source-1": "zz-app",
+					"source-2": "aa-app",
+				}),
+			},
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Top(
+			context.Background(),
+			cliConn,
+			[]string{"--iterations", "1"},
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithTopSleepDuration(50*time.Millisecond),
+			// two presses cycle rate -> count -> source
+			cf.WithTopInput(strings.NewReader("ss")),
+		)
+
+		lines := strings.Split(tableWriter.String(), "\n")
+		Expect(lines[1]).To(HavePrefix("aa-app"))
+		Expect(lines[2]).To(HavePrefix("zz-app"))
+	})
+
+	It("quits on the 'q' key", func() {
+		httpClient.responseBody = []string{
+			metaResponseInfo("source-1"),
+		}
+		cliConn.cliCommandErr = nil
+
+		cf.Top(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			tableWriter,
+			cf.WithTopSleepDuration(50*time.Millisecond),
+			cf.WithTopInput(strings.NewReader("q")),
+		)
+
+		Expect(tableWriter.String()).To(BeEmpty())
+		Expect(httpClient.requestCount()).To(Equal(1))
+	})
+
+	It("fatally logs when sort-by is invalid", func() {
+		Expect(func() {
+			cf.Top(
+				context.Background(),
+				cliConn,
+				[]string{"--sort-by", "invalid"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Sort by must be 'rate', 'count', or 'source'."))
+	})
+
+	It("fatally logs when the interval is less than a second", func() {
+		Expect(func() {
+			cf.Top(
+				context.Background(),
+				cliConn,
+				[]string{"--interval", "0"},
+				httpClient,
+				logger,
+				tableWriter,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("--interval must be at least 1 second."))
+	})
+})
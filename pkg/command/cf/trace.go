@@ -0,0 +1,92 @@
+package cf
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// traceTimeFormat matches the timestamps the cf CLI writes in CF_TRACE
+// output.
+const traceTimeFormat = "2006-01-02T15:04:05-07:00"
+
+// traceHTTPClient dumps every request and response it carries, with the
+// Authorization header redacted, following the cf CLI's CF_TRACE
+// conventions. It exists so auth and endpoint issues can be debugged without
+// reaching for tcpdump.
+type traceHTTPClient struct {
+	c HTTPClient
+	w io.Writer
+}
+
+// NewTraceClient wraps an HTTPClient so the full URL, headers, and response
+// body of every request are dumped to w.
+func NewTraceClient(c HTTPClient, w io.Writer) HTTPClient {
+	return &traceHTTPClient{c: c, w: w}
+}
+
+// NewTraceClientFromEnv honors CF_TRACE the way the cf CLI does: "true"
+// dumps to stderr, any other non-empty value except "false" names a file to
+// append to. When CF_TRACE is not set the client is returned unchanged.
+func NewTraceClientFromEnv(c HTTPClient) HTTPClient {
+	v := os.Getenv("CF_TRACE")
+	switch {
+	case strings.EqualFold(v, "true"):
+		return NewTraceClient(c, os.Stderr)
+	case v != "" && !strings.EqualFold(v, "false"):
+		f, err := os.OpenFile(v, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return c
+		}
+		return NewTraceClient(c, f)
+	}
+	return c
+}
+
+func (t *traceHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(t.w, "\nREQUEST: [%s]\n", time.Now().Format(traceTimeFormat))
+	fmt.Fprintf(t.w, "%s %s %s\n", req.Method, req.URL, req.Proto)
+	writeTraceHeaders(t.w, req.Header)
+
+	resp, err := t.c.Do(req)
+	if err != nil {
+		fmt.Fprintf(t.w, "\nERROR: [%s]\n%s\n", time.Now().Format(traceTimeFormat), err)
+		return resp, err
+	}
+
+	fmt.Fprintf(t.w, "\nRESPONSE: [%s]\n", time.Now().Format(traceTimeFormat))
+	dump, dumpErr := httputil.DumpResponse(resp, true)
+	if dumpErr != nil {
+		fmt.Fprintf(t.w, "failed to dump response: %s\n", dumpErr)
+		return resp, err
+	}
+	t.w.Write(dump)
+	fmt.Fprintln(t.w)
+
+	return resp, err
+}
+
+// writeTraceHeaders prints headers in a stable order, hiding credentials the
+// way the cf CLI does.
+func writeTraceHeaders(w io.Writer, h http.Header) {
+	var names []string
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := h[name]
+		if name == "Authorization" {
+			values = []string{"[PRIVATE DATA HIDDEN]"}
+		}
+		for _, value := range values {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cf_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewTraceClient", func() {
+	It("dumps the request, redacted headers, and response body", func() {
+		underlying := &sequencedHTTPClient{
+			responses: []*http.Response{
+				{
+					Status:     "200 OK",
+					StatusCode: http.StatusOK,
+					Proto:      "HTTP/1.1",
+					ProtoMajor: 1,
+					ProtoMinor: 1,
+					Body:       ioutil.NopCloser(strings.NewReader(`{"envelopes":{"batch":[]}}`)),
+				},
+			},
+		}
+		out := bytes.NewBuffer(nil)
+		client := cf.NewTraceClient(underlying, out)
+
+		req, err := http.NewRequest("GET", "https://log-cache.some-system.com/v1/read/app-guid", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", "bearer some-token")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		trace := out.String()
+		Expect(trace).To(ContainSubstring("REQUEST: ["))
+		Expect(trace).To(ContainSubstring("GET https://log-cache.some-system.com/v1/read/app-guid HTTP/1.1"))
+		Expect(trace).To(ContainSubstring("Accept: application/json"))
+		Expect(trace).To(ContainSubstring("Authorization: [PRIVATE DATA HIDDEN]"))
+		Expect(trace).ToNot(ContainSubstring("some-token"))
+		Expect(trace).To(ContainSubstring("RESPONSE: ["))
+		Expect(trace).To(ContainSubstring(`{"envelopes":{"batch":[]}}`))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal(`{"envelopes":{"batch":[]}}`))
+	})
+
+	It("leaves the client untouched when CF_TRACE is not set", func() {
+		orig := os.Getenv("CF_TRACE")
+		os.Unsetenv("CF_TRACE")
+		defer os.Setenv("CF_TRACE", orig)
+
+		underlying := &sequencedHTTPClient{}
+		Expect(cf.NewTraceClientFromEnv(underlying)).To(BeIdenticalTo(underlying))
+	})
+})
@@ -0,0 +1,21 @@
+package cf
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Uninstall removes the state the plugin has written under the user's home
+// directory — the GUID name cache, saved presets, profiles, and telemetry
+// settings — so uninstalling does not leave orphaned files behind.
+// Hand-written defaults in ~/.config/log-cache-cli/config.yml are kept,
+// since the plugin did not create them. The cf CLI invokes this via
+// CLI-MESSAGE-UNINSTALL.
+func Uninstall() {
+	path := nameCachePath()
+	if path == "" {
+		return
+	}
+
+	os.RemoveAll(filepath.Dir(path))
+}
@@ -0,0 +1,35 @@
+package cf_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Uninstall", func() {
+	It("removes the plugin's state directory but not the rest of CF_HOME", func() {
+		home, err := ioutil.TempDir("", "uninstall")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(home)
+
+		orig := os.Getenv("CF_HOME")
+		os.Setenv("CF_HOME", home)
+		defer os.Setenv("CF_HOME", orig)
+
+		stateDir := filepath.Join(home, ".cf", "log-cache-cli")
+		Expect(os.MkdirAll(stateDir, 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(stateDir, "name_cache.json"), []byte("{}"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(stateDir, "presets.json"), []byte("{}"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(home, ".cf", "config.json"), []byte("{}"), 0600)).To(Succeed())
+
+		cf.Uninstall()
+
+		Expect(stateDir).ToNot(BeADirectory())
+		Expect(filepath.Join(home, ".cf", "config.json")).To(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,147 @@
+package cf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"github.com/blang/semver"
+)
+
+// latestReleaseURL is the GitHub API endpoint describing the newest plugin
+// release.
+const latestReleaseURL = "https://api.github.com/repos/cloudfoundry-incubator/log-cache-cli/releases/latest"
+
+// githubRelease is the subset of the GitHub release payload the updater
+// needs.
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// Update checks GitHub for a newer plugin release, downloads the artifact
+// for this platform, and re-installs it via `cf install-plugin`, so fleets
+// of operator workstations can be kept current without manual downloads.
+func Update(
+	ctx context.Context,
+	cli plugin.CliConnection,
+	args []string,
+	c HTTPClient,
+	log Logger,
+	w io.Writer,
+) {
+	if len(args) != 0 {
+		fatalf(log, ExitCodeUsage, "Expected 0 arguments, got %d.", len(args))
+	}
+
+	release, err := fetchLatestRelease(c)
+	if err != nil {
+		fatalf(log, ExitCodeNetwork, "Failed to check for updates: %s", err)
+	}
+
+	latest, err := semver.ParseTolerant(release.TagName)
+	if err != nil {
+		log.Fatalf("Failed to parse release version %q: %s", release.TagName, err)
+	}
+
+	if current, err := semver.ParseTolerant(Version); err == nil && !latest.GT(current) {
+		fmt.Fprintf(w, "Already up to date (version %s).\n", Version)
+		return
+	}
+
+	var assetURL string
+	for _, a := range release.Assets {
+		if strings.Contains(strings.ToLower(a.Name), runtime.GOOS) {
+			assetURL = a.BrowserDownloadURL
+			break
+		}
+	}
+	if assetURL == "" {
+		fatalf(log, ExitCodeNotFound, "No release artifact found for %s.", runtime.GOOS)
+	}
+
+	fmt.Fprintf(w, "Downloading version %s...\n", latest)
+
+	path, err := downloadArtifact(c, assetURL)
+	if err != nil {
+		fatalf(log, ExitCodeNetwork, "Failed to download update: %s", err)
+	}
+	defer os.Remove(path)
+
+	if _, err := cli.CliCommandWithoutTerminalOutput("install-plugin", path, "-f"); err != nil {
+		log.Fatalf("Failed to install plugin: %s", err)
+	}
+
+	fmt.Fprintf(w, "Updated to version %s.\n", latest)
+}
+
+func fetchLatestRelease(c HTTPClient) (githubRelease, error) {
+	req, err := http.NewRequest("GET", latestReleaseURL, nil)
+	if err != nil {
+		return githubRelease{}, err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return githubRelease{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return githubRelease{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return githubRelease{}, err
+	}
+
+	return release, nil
+}
+
+// downloadArtifact fetches the release binary into a temp file the cf CLI
+// can install from. The caller removes the file once installed.
+func downloadArtifact(c HTTPClient, url string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	f, err := ioutil.TempFile("", "log-cache-cf-plugin")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,104 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Update", func() {
+	var (
+		logger     *stubLogger
+		httpClient *stubHTTPClient
+		cliConn    *stubCliConnection
+		writer     *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = &stubLogger{}
+		httpClient = newStubHTTPClient()
+		cliConn = newStubCliConnection()
+		writer = bytes.NewBuffer(nil)
+	})
+
+	It("downloads the platform artifact and re-installs the plugin", func() {
+		httpClient.responseBody = []string{
+			fmt.Sprintf(`{
+				"tag_name": "v99.0.0",
+				"assets": [
+					{"name": "log-cache-cf-plugin-other", "browser_download_url": "https://example.com/other"},
+					{"name": "log-cache-cf-plugin-%s", "browser_download_url": "https://example.com/mine"}
+				]
+			}`, runtime.GOOS),
+			"binary bytes",
+		}
+		cliConn.cliCommandResult = [][]string{{"OK"}}
+
+		cf.Update(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			writer,
+		)
+
+		Expect(httpClient.requestURLs).To(HaveLen(2))
+		Expect(httpClient.requestURLs[0]).To(Equal("https://api.github.com/repos/cloudfoundry-incubator/log-cache-cli/releases/latest"))
+		Expect(httpClient.requestURLs[1]).To(Equal("https://example.com/mine"))
+
+		Expect(cliConn.cliCommandArgs).To(HaveLen(1))
+		Expect(cliConn.cliCommandArgs[0]).To(HaveLen(3))
+		Expect(cliConn.cliCommandArgs[0][0]).To(Equal("install-plugin"))
+		Expect(cliConn.cliCommandArgs[0][2]).To(Equal("-f"))
+
+		Expect(writer.String()).To(Equal(
+			"Downloading version 99.0.0...\nUpdated to version 99.0.0.\n",
+		))
+	})
+
+	It("does nothing when already on the latest version", func() {
+		orig := cf.Version
+		cf.Version = "99.0.0"
+		defer func() { cf.Version = orig }()
+
+		httpClient.responseBody = []string{`{"tag_name": "v99.0.0"}`}
+
+		cf.Update(
+			context.Background(),
+			cliConn,
+			nil,
+			httpClient,
+			logger,
+			writer,
+		)
+
+		Expect(writer.String()).To(Equal("Already up to date (version 99.0.0).\n"))
+		Expect(cliConn.cliCommandArgs).To(BeEmpty())
+	})
+
+	It("fatally logs when no artifact matches this platform", func() {
+		httpClient.responseBody = []string{`{"tag_name": "v99.0.0", "assets": []}`}
+
+		Expect(func() {
+			cf.Update(
+				context.Background(),
+				cliConn,
+				nil,
+				httpClient,
+				logger,
+				writer,
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal(
+			fmt.Sprintf("No release artifact found for %s.", runtime.GOOS),
+		))
+	})
+})
@@ -0,0 +1,64 @@
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// vectorTable renders an instant vector result as an aligned table with one
+// column per distinct label plus the sample value, which reads far better in
+// a terminal than the raw JSON.
+func vectorTable(result json.RawMessage) ([]string, error) {
+	var samples []struct {
+		Metric map[string]string `json:"metric"`
+		Value  []json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(result, &samples); err != nil {
+		return nil, err
+	}
+
+	keySet := make(map[string]struct{})
+	for _, s := range samples {
+		for k := range s.Metric {
+			keySet[k] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	// "__name__" sorts ahead of the other labels, putting the metric name in
+	// the first column.
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	tw := tabwriter.NewWriter(buf, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(append(keys, "value"), "\t"))
+
+	for _, s := range samples {
+		cells := make([]string, 0, len(keys)+1)
+		for _, k := range keys {
+			cells = append(cells, s.Metric[k])
+		}
+
+		value := ""
+		if len(s.Value) == 2 {
+			value = strings.Trim(string(s.Value[1]), `"`)
+		}
+		cells = append(cells, value)
+
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	// drop the trailing empty line Fprintln leaves behind
+	return lines[:len(lines)-1], nil
+}
@@ -0,0 +1,66 @@
+package cf
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// verboseLogger writes progress and timing detail through the command Logger
+// once the configured verbosity level is reached. Level 1 (-v) covers
+// progress and timing messages, level 2 (-vv) adds per-request logging.
+type verboseLogger struct {
+	log   Logger
+	level int
+}
+
+func newVerboseLogger(log Logger, level int) verboseLogger {
+	return verboseLogger{log: log, level: level}
+}
+
+func (v verboseLogger) V(level int, format string, args ...interface{}) {
+	if v.level >= level {
+		v.log.Printf(format, args...)
+	}
+}
+
+// quietLogger routes non-fatal diagnostics to a file so piped stdout and
+// stderr stay clean for automation. Fatal errors still go through the
+// original logger.
+type quietLogger struct {
+	Logger
+	f io.Writer
+}
+
+func (l quietLogger) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(l.f, format+"\n", args...)
+}
+
+// verboseHTTPClient logs the method, URL, status and duration of every
+// request at verbosity level 2.
+type verboseHTTPClient struct {
+	c HTTPClient
+	v verboseLogger
+}
+
+func newVerboseHTTPClient(c HTTPClient, v verboseLogger) HTTPClient {
+	if v.level < 2 {
+		return c
+	}
+	return &verboseHTTPClient{c: c, v: v}
+}
+
+func (c *verboseHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.c.Do(req)
+	duration := time.Since(start).Truncate(time.Millisecond)
+
+	if err != nil {
+		c.v.V(2, "%s %s failed after %s: %s", req.Method, req.URL, duration, err)
+		return resp, err
+	}
+
+	c.v.V(2, "%s %s %d (%s)", req.Method, req.URL, resp.StatusCode, duration)
+	return resp, err
+}
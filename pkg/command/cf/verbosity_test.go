@@ -0,0 +1,69 @@
+package cf_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verbosity", func() {
+	It("prints timing detail with -v", func() {
+		json := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"2.5"]}}`
+		tc := setup(json, 200)
+
+		tc.query(`egress{source_id="doppler"}`, "-v")
+
+		Expect(tc.logger.printfMessages).To(ConsistOf(
+			MatchRegexp(`^Query completed in .+`),
+		))
+	})
+
+	It("additionally logs each request with -vv", func() {
+		json := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"2.5"]}}`
+		tc := setup(json, 200)
+
+		tc.query(`egress{source_id="doppler"}`, "-vv")
+
+		Expect(tc.logger.printfMessages).To(ConsistOf(
+			MatchRegexp(`^GET https://log-cache\.some-system\.com/api/v1/query\?query=.+ 200 \(.+\)$`),
+			MatchRegexp(`^Query completed in .+`),
+		))
+	})
+
+	It("summarizes envelopes, pages, and range for tail with -v", func() {
+		logger := &stubLogger{}
+		writer := &stubWriter{}
+		httpClient := newStubHTTPClient()
+		httpClient.responseBody = []string{responseBody(time.Now().Truncate(time.Second).Add(-time.Minute))}
+		cliConn := newStubCliConnection()
+		cliConn.cliCommandResult = [][]string{{"app-guid"}}
+		cliConn.usernameResp = "a-user"
+
+		cf.Tail(
+			context.Background(),
+			cliConn,
+			[]string{"-v", "app-name"},
+			httpClient,
+			logger,
+			writer,
+			cf.WithTailNoHeaders(),
+		)
+
+		Expect(logger.printfMessages).To(ConsistOf(
+			MatchRegexp(`^Received 3 envelopes in .+ \(1 pages, .+ to .+\)$`),
+		))
+	})
+
+	It("prints nothing extra by default", func() {
+		json := `{"status":"success","data":{"resultType":"scalar","result":[1.234,"2.5"]}}`
+		tc := setup(json, 200)
+
+		tc.query(`egress{source_id="doppler"}`)
+
+		Expect(tc.logger.printfMessages).To(BeEmpty())
+	})
+})
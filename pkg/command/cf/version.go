@@ -0,0 +1,32 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// Version is the CLI version stamped into the plugin at build time. It is
+// recorded in export manifests so archived data can be traced back to the
+// build that produced it.
+var Version = "dev"
+
+// GitSHA is the commit the plugin was built from, stamped at build time via
+//
+//	-X code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf.GitSHA=abc1234
+var GitSHA = "unknown"
+
+// ShowVersion prints the plugin version, the git SHA it was built from, and
+// the Log Cache versions it was tested against, so support tickets can
+// include exact build information.
+func ShowVersion(ctx context.Context, cli plugin.CliConnection, args []string, c HTTPClient, log Logger, w io.Writer) {
+	if len(args) != 0 {
+		fatalf(log, ExitCodeUsage, "Expected 0 arguments, got %d.", len(args))
+	}
+
+	fmt.Fprintf(w, "version: %s\n", Version)
+	fmt.Fprintf(w, "git sha: %s\n", GitSHA)
+	fmt.Fprintf(w, "tested against log-cache: >=%s <%s\n", minCompatibleVersion, maxCompatibleVersion)
+}
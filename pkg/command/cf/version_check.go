@@ -0,0 +1,46 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+
+	logcache "code.cloudfoundry.org/log-cache/pkg/client"
+	"github.com/blang/semver"
+)
+
+// minCompatibleVersion and maxCompatibleVersion bracket the Log Cache
+// versions this plugin is known to be compatible with.
+var (
+	minCompatibleVersion = semver.MustParse("2.0.0")
+	maxCompatibleVersion = semver.MustParse("3.0.0")
+)
+
+// checkGatewayCompatibility fetches the version reported by the Log Cache
+// gateway and compares it against the known-compatible range. Mismatches are
+// logged as warnings, or are fatal when strict is set.
+func checkGatewayCompatibility(ctx context.Context, client *logcache.Client, log Logger, strict bool) {
+	version, err := client.LogCacheVersion(ctx)
+	if err != nil {
+		if strict {
+			log.Fatalf("Could not determine Log Cache version: %s", err)
+		}
+		log.Printf("WARNING: Could not determine Log Cache version: %s", err)
+		return
+	}
+
+	if version.GE(minCompatibleVersion) && version.LT(maxCompatibleVersion) {
+		return
+	}
+
+	message := fmt.Sprintf(
+		"Log Cache version %s is outside the tested range [%s, %s). Behavior may differ from what is documented.",
+		version,
+		minCompatibleVersion,
+		maxCompatibleVersion,
+	)
+
+	if strict {
+		log.Fatalf("%s", message)
+	}
+	log.Printf("WARNING: %s", message)
+}
@@ -0,0 +1,41 @@
+package cf_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VersionCheck", func() {
+	It("warns when the Log Cache version is outside the tested range", func() {
+		tc := setup("", 200)
+		tc.httpClient.serverVersion = "1.4.7"
+
+		tc.query(`egress{source_id="doppler"}`, "--version-check")
+
+		Expect(tc.logger.printfMessages).To(ConsistOf(
+			HavePrefix("WARNING: Log Cache version 1.4.7 is outside the tested range"),
+		))
+	})
+
+	It("stays quiet when the Log Cache version is within the tested range", func() {
+		tc := setup("", 200)
+		tc.httpClient.serverVersion = "2.1.0"
+
+		tc.query(`egress{source_id="doppler"}`, "--version-check")
+
+		Expect(tc.logger.printfMessages).To(BeEmpty())
+	})
+
+	It("fails on a mismatch when --strict-compat is given", func() {
+		tc := setup("", 200)
+		tc.httpClient.serverVersion = "1.4.7"
+
+		Expect(func() {
+			tc.query(`egress{source_id="doppler"}`, "--version-check", "--strict-compat")
+		}).To(Panic())
+
+		Expect(tc.logger.fatalfMessage).To(HavePrefix(
+			"Log Cache version 1.4.7 is outside the tested range",
+		))
+	})
+})
@@ -0,0 +1,50 @@
+package cf_test
+
+import (
+	"bytes"
+	"context"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ShowVersion", func() {
+	It("prints the version, git sha, and tested log-cache range", func() {
+		logger := &stubLogger{}
+		writer := bytes.NewBuffer(nil)
+
+		cf.ShowVersion(
+			context.Background(),
+			newStubCliConnection(),
+			nil,
+			newStubHTTPClient(),
+			logger,
+			writer,
+		)
+
+		Expect(writer.String()).To(Equal(
+			"version: dev\n" +
+				"git sha: unknown\n" +
+				"tested against log-cache: >=2.0.0 <3.0.0\n",
+		))
+	})
+
+	It("fatally logs when given arguments", func() {
+		logger := &stubLogger{}
+
+		Expect(func() {
+			cf.ShowVersion(
+				context.Background(),
+				newStubCliConnection(),
+				[]string{"extra"},
+				newStubHTTPClient(),
+				logger,
+				bytes.NewBuffer(nil),
+			)
+		}).To(Panic())
+
+		Expect(logger.fatalfMessage).To(Equal("Expected 0 arguments, got 1."))
+	})
+})
@@ -0,0 +1,37 @@
+package cf
+
+import "strings"
+
+// fitLines fits a formatted line to the terminal width, either truncating it
+// or wrapping it with an indented continuation. Width is counted in runes and
+// does not account for ANSI escape sequences.
+func fitLines(line string, width, indent int, truncate bool) []string {
+	if width <= 0 {
+		return []string{line}
+	}
+
+	runes := []rune(line)
+	if len(runes) <= width {
+		return []string{line}
+	}
+
+	if truncate {
+		return []string{string(runes[:width])}
+	}
+
+	if indent >= width {
+		indent = 0
+	}
+	prefix := strings.Repeat(" ", indent)
+
+	lines := []string{string(runes[:width])}
+	for rest := runes[width:]; len(rest) > 0; {
+		chunk := width - indent
+		if chunk > len(rest) {
+			chunk = len(rest)
+		}
+		lines = append(lines, prefix+string(rest[:chunk]))
+		rest = rest[chunk:]
+	}
+	return lines
+}
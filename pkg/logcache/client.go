@@ -0,0 +1,116 @@
+// Package logcache exposes the Log Cache access logic the cf plugin
+// commands are built on: a configured go-log-cache client, token handling
+// for authenticated requests, and helpers for walking envelopes and
+// working with meta results. It is exported so other tooling can reuse the
+// same client logic instead of copying it out of the commands.
+package logcache
+
+import (
+	"net/http"
+	"time"
+
+	client "code.cloudfoundry.org/log-cache/pkg/client"
+	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
+)
+
+// HTTPClient is the interface a client must implement to issue HTTP
+// requests. *http.Client satisfies it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client reads envelopes and metadata from a Log Cache. It is the
+// go-log-cache client type, re-exported so callers only need this package.
+type Client = client.Client
+
+// Option configures the client returned by NewClient.
+type Option func(*config)
+
+type config struct {
+	httpClient HTTPClient
+	tokenFunc  func() string
+}
+
+// WithHTTPClient sets the HTTPClient used for every request. It defaults
+// to http.DefaultClient.
+func WithHTTPClient(c HTTPClient) Option {
+	return func(cfg *config) {
+		cfg.httpClient = c
+	}
+}
+
+// WithToken has every request carry the token returned by tokenFunc as
+// its Authorization header, e.g. a CF OAuth token. An empty token leaves
+// the request unauthenticated.
+func WithToken(tokenFunc func() string) Option {
+	return func(cfg *config) {
+		cfg.tokenFunc = tokenFunc
+	}
+}
+
+// NewClient builds a Client for the Log Cache at addr.
+func NewClient(addr string, opts ...Option) *Client {
+	cfg := config{httpClient: http.DefaultClient}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	c := cfg.httpClient
+	if cfg.tokenFunc != nil {
+		c = NewTokenClient(c, cfg.tokenFunc)
+	}
+
+	return client.NewClient(addr, client.WithHTTPClient(c))
+}
+
+// ReadOption configures a single Client.Read request.
+type ReadOption = client.ReadOption
+
+// WithEndTime only reads envelopes created before t.
+func WithEndTime(t time.Time) ReadOption {
+	return client.WithEndTime(t)
+}
+
+// WithLimit caps how many envelopes a read returns.
+func WithLimit(limit int) ReadOption {
+	return client.WithLimit(limit)
+}
+
+// WithEnvelopeTypes only reads envelopes of the given types.
+func WithEnvelopeTypes(t ...logcache_v1.EnvelopeType) ReadOption {
+	return client.WithEnvelopeTypes(t...)
+}
+
+// WithDescending reads envelopes newest first.
+func WithDescending() ReadOption {
+	return client.WithDescending()
+}
+
+// WithNameFilter only reads envelopes whose name matches the given
+// regular expression.
+func WithNameFilter(nameFilter string) ReadOption {
+	return client.WithNameFilter(nameFilter)
+}
+
+// PromQLOption configures a single Client.PromQL request.
+type PromQLOption = client.PromQLOption
+
+// WithPromQLTime evaluates an instant query at t.
+func WithPromQLTime(t time.Time) PromQLOption {
+	return client.WithPromQLTime(t)
+}
+
+// WithPromQLStart starts a range query at t.
+func WithPromQLStart(t time.Time) PromQLOption {
+	return client.WithPromQLStart(t)
+}
+
+// WithPromQLEnd ends a range query at t.
+func WithPromQLEnd(t time.Time) PromQLOption {
+	return client.WithPromQLEnd(t)
+}
+
+// WithPromQLStep sets the resolution of a range query, e.g. "1m".
+func WithPromQLStep(step string) PromQLOption {
+	return client.WithPromQLStep(step)
+}
@@ -0,0 +1,95 @@
+package logcache_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/log-cache-cli/v4/pkg/logcache"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type stubHTTPClient struct {
+	requests []*http.Request
+}
+
+func (s *stubHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/api/v1/info" {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"version": "1.4.7"}`)),
+		}, nil
+	}
+
+	s.requests = append(s.requests, req)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"envelopes":{"batch":[]}}`)),
+	}, nil
+}
+
+var _ = Describe("NewClient", func() {
+	It("issues reads through the given HTTPClient", func() {
+		stub := &stubHTTPClient{}
+		client := logcache.NewClient(
+			"https://log-cache.some-system.com",
+			logcache.WithHTTPClient(stub),
+		)
+
+		_, err := client.Read(context.Background(), "app-guid", time.Unix(0, 0), logcache.WithLimit(10))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(stub.requests).To(HaveLen(1))
+		Expect(stub.requests[0].URL.Host).To(Equal("log-cache.some-system.com"))
+		Expect(stub.requests[0].URL.Path).To(Equal("/v1/read/app-guid"))
+		Expect(stub.requests[0].URL.Query().Get("limit")).To(Equal("10"))
+	})
+
+	It("sets the Authorization header with WithToken", func() {
+		stub := &stubHTTPClient{}
+		client := logcache.NewClient(
+			"https://log-cache.some-system.com",
+			logcache.WithHTTPClient(stub),
+			logcache.WithToken(func() string { return "bearer some-token" }),
+		)
+
+		_, err := client.Read(context.Background(), "app-guid", time.Unix(0, 0))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(stub.requests).To(HaveLen(1))
+		Expect(stub.requests[0].Header.Get("Authorization")).To(Equal("bearer some-token"))
+	})
+})
+
+var _ = Describe("NewTokenClient", func() {
+	It("leaves the request unauthenticated for an empty token", func() {
+		stub := &stubHTTPClient{}
+		c := logcache.NewTokenClient(stub, func() string { return "" })
+
+		req, err := http.NewRequest("GET", "https://log-cache.some-system.com/v1/meta", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = c.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(stub.requests).To(HaveLen(1))
+		Expect(stub.requests[0].Header).ToNot(HaveKey("Authorization"))
+	})
+})
+
+var _ = Describe("SourceIDs", func() {
+	It("returns the meta source IDs in a stable order", func() {
+		ids := logcache.SourceIDs(map[string]*logcache.MetaInfo{
+			"source-b": {},
+			"source-a": {},
+			"source-c": {},
+		})
+
+		Expect(ids).To(Equal([]string{"source-a", "source-b", "source-c"}))
+	})
+})
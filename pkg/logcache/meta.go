@@ -0,0 +1,23 @@
+package logcache
+
+import (
+	"sort"
+
+	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
+)
+
+// MetaInfo describes what a Log Cache holds for one source: envelope
+// counts and the timestamps of the cached window.
+type MetaInfo = logcache_v1.MetaInfo
+
+// SourceIDs returns the source IDs of a Client.Meta result, sorted so
+// callers get a stable order.
+func SourceIDs(meta map[string]*MetaInfo) []string {
+	ids := make([]string, 0, len(meta))
+	for id := range meta {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
@@ -0,0 +1,26 @@
+package logcache
+
+import "net/http"
+
+// tokenHTTPClient sets the Authorization header on every request.
+type tokenHTTPClient struct {
+	c         HTTPClient
+	tokenFunc func() string
+}
+
+// NewTokenClient wraps c so every request carries the token returned by
+// tokenFunc as its Authorization header. A tokenFunc that returns an
+// empty string leaves the request untouched, which keeps the client
+// usable against deployments that skip auth.
+func NewTokenClient(c HTTPClient, tokenFunc func() string) HTTPClient {
+	return &tokenHTTPClient{c: c, tokenFunc: tokenFunc}
+}
+
+func (c *tokenHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	accessToken := c.tokenFunc()
+	if len(accessToken) > 0 {
+		req.Header.Set("Authorization", accessToken)
+	}
+
+	return c.c.Do(req)
+}
@@ -0,0 +1,71 @@
+package logcache
+
+import (
+	"context"
+	"time"
+
+	client "code.cloudfoundry.org/log-cache/pkg/client"
+	logcache_v1 "code.cloudfoundry.org/log-cache/pkg/rpc/logcache_v1"
+)
+
+// Reader reads a page of envelopes from a Log Cache. Client.Read
+// satisfies it.
+type Reader = client.Reader
+
+// Visitor is invoked with each batch of envelopes a walk reads. It
+// returns false to stop the walk.
+type Visitor = client.Visitor
+
+// WalkOption configures a Walk.
+type WalkOption = client.WalkOption
+
+// Backoff decides whether a walk keeps going after an error or an empty
+// batch.
+type Backoff = client.Backoff
+
+// AlwaysDoneBackoff stops a walk on the first error or empty batch.
+type AlwaysDoneBackoff = client.AlwaysDoneBackoff
+
+// Walk reads envelopes from sourceID in timestamp order, calling v with
+// each batch until v returns false or the backoff gives up.
+func Walk(ctx context.Context, sourceID string, v Visitor, r Reader, opts ...WalkOption) {
+	client.Walk(ctx, sourceID, v, r, opts...)
+}
+
+// WithWalkStartTime starts the walk at t.
+func WithWalkStartTime(t time.Time) WalkOption {
+	return client.WithWalkStartTime(t)
+}
+
+// WithWalkEndTime stops the walk at t.
+func WithWalkEndTime(t time.Time) WalkOption {
+	return client.WithWalkEndTime(t)
+}
+
+// WithWalkLimit caps how many envelopes each read requests.
+func WithWalkLimit(limit int) WalkOption {
+	return client.WithWalkLimit(limit)
+}
+
+// WithWalkEnvelopeTypes only walks envelopes of the given types.
+func WithWalkEnvelopeTypes(t ...logcache_v1.EnvelopeType) WalkOption {
+	return client.WithWalkEnvelopeTypes(t...)
+}
+
+// WithWalkNameFilter only walks envelopes whose name matches the given
+// regular expression.
+func WithWalkNameFilter(nameFilter string) WalkOption {
+	return client.WithWalkNameFilter(nameFilter)
+}
+
+// WithWalkBackoff sets the Backoff consulted after errors and empty
+// batches. It defaults to AlwaysDoneBackoff.
+func WithWalkBackoff(b Backoff) WalkOption {
+	return client.WithWalkBackoff(b)
+}
+
+// WithWalkDelay keeps the walk the given duration behind the cache's
+// newest envelope so slightly delayed envelopes are not skipped over.
+func WithWalkDelay(delay time.Duration) WalkOption {
+	return client.WithWalkDelay(delay)
+}
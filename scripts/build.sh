@@ -2,14 +2,20 @@
 set -e
 
 version="{\"Major\":0,\"Minor\":0,\"Build\":\"0+dev.0\"}"
+cli_version="${CLI_VERSION:-dev}"
 
 SCRIPTS_PATH="$( cd "$(dirname "$0")" ; pwd -P )"
 WORKSPACE="$SCRIPTS_PATH/.."
 
+git_sha="$(git -C $WORKSPACE rev-parse --short HEAD 2>/dev/null || echo unknown)"
+
 pushd $WORKSPACE
   go get ./...
 popd
 
 pushd "$WORKSPACE/cmd/cf-lc-plugin"
-  go build -ldflags "-X main.version=$version" -o $WORKSPACE/build_artifacts/log-cache-cf-plugin-dev
+  go build -ldflags "-X main.version=$version \
+    -X code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf.Version=$cli_version \
+    -X code.cloudfoundry.org/log-cache-cli/v4/pkg/command/cf.GitSHA=$git_sha" \
+    -o $WORKSPACE/build_artifacts/log-cache-cf-plugin-dev
 popd